@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -83,17 +84,39 @@ func startServer() {
 		logging.Logger().Fatalf("Unrecognized storage backend")
 	}
 
-	var userProvider authentication.UserProvider
+	var (
+		userProvider     authentication.UserProvider
+		ldapUserProvider *authentication.LDAPUserProvider
+	)
 
 	switch {
 	case config.AuthenticationBackend.File != nil:
 		userProvider = authentication.NewFileUserProvider(config.AuthenticationBackend.File)
 	case config.AuthenticationBackend.Ldap != nil:
-		userProvider = authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap, autheliaCertPool)
+		ldapUserProvider = authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap, autheliaCertPool)
+
+		if config.Server.EnableMetrics {
+			recorder, err := authentication.NewPrometheusMetricsRecorder(prometheus.DefaultRegisterer)
+			if err != nil {
+				logging.Logger().Fatalf("Error registering the LDAP metrics: %s", err)
+			}
+
+			ldapUserProvider.SetMetricsRecorder(recorder)
+		}
+
+		userProvider = ldapUserProvider
+
+		if config.AuthenticationBackend.Ldap.EmergencyFallback.Enabled {
+			userProvider = authentication.NewEmergencyFallbackUserProvider(userProvider, config.AuthenticationBackend.Ldap.EmergencyFallback)
+		}
 	default:
 		logging.Logger().Fatalf("Unrecognized authentication backend")
 	}
 
+	if err := userProvider.StartupCheck(); err != nil {
+		logging.Logger().Fatalf("Error during authentication backend startup check: %s", err)
+	}
+
 	var notifier notification.Notifier
 
 	switch {
@@ -126,6 +149,13 @@ func startServer() {
 		SessionProvider: sessionProvider,
 	}
 	server.StartServer(*config, providers)
+
+	// The connections to the LDAP servers are unbound rather than dropped once the requests in progress are served.
+	if ldapUserProvider != nil {
+		if err := ldapUserProvider.Close(); err != nil {
+			logging.Logger().Warnf("Error closing the connections to the LDAP servers: %s", err)
+		}
+	}
 }
 
 func main() {
@@ -147,7 +177,7 @@ func main() {
 	}
 
 	rootCmd.AddCommand(versionCmd, commands.HashPasswordCmd,
-		commands.ValidateConfigCmd, commands.CertificatesCmd)
+		commands.ValidateConfigCmd, commands.CertificatesCmd, commands.LDAPTestUserCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		logging.Logger().Fatal(err)
@@ -2,6 +2,8 @@ package authentication
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 // Level is the type representing a level of authentication.
@@ -51,9 +53,208 @@ const (
 // HashingPossibleSaltCharacters represents valid hashing runes.
 var HashingPossibleSaltCharacters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/")
 
+const (
+	// TransportLDAPS the connection to the LDAP server is encrypted with TLS from the start.
+	TransportLDAPS = "ldaps"
+	// TransportStartTLS the connection to the LDAP server is upgraded to TLS with StartTLS before binding.
+	TransportStartTLS = "starttls"
+	// TransportLDAPI the connection to the LDAP server is a Unix domain socket which never leaves the host.
+	TransportLDAPI = "ldapi"
+	// TransportPlaintext the connection to the LDAP server is not encrypted.
+	TransportPlaintext = "plaintext"
+)
+
 // ErrUserNotFound indicates the user wasn't found in the authentication backend.
 var ErrUserNotFound = errors.New("user not found")
 
+// ErrMultipleUsersFound indicates several users of the authentication backend match the input.
+var ErrMultipleUsersFound = errors.New("multiple users found")
+
+// ErrBindFailed indicates the credentials of the user were rejected by the authentication backend.
+var ErrBindFailed = errors.New("bind failed")
+
+// ErrConnectionFailed indicates the authentication backend could not be reached.
+var ErrConnectionFailed = errors.New("unable to connect to the authentication backend")
+
+// ErrBindTimeout indicates the bind of the user did not complete within the user bind timeout. The LDAP server is
+// reachable so it is regulated like a rejected bind rather than reported as the backend being unavailable.
+var ErrBindTimeout = errors.New("the bind timed out against the authentication backend")
+
+// ErrProviderClosed indicates the authentication backend is closed because Authelia is shutting down.
+var ErrProviderClosed = errors.New("the authentication backend is closed")
+
+// ErrPasswordUpdateFailed indicates the password of the user could not be updated.
+var ErrPasswordUpdateFailed = errors.New("unable to update password")
+
+// ErrPasswordExpired indicates the password of the user has expired according to the password policy of the backend.
+var ErrPasswordExpired = errors.New("password expired")
+
+// ErrPasswordMustChange indicates the password of the user has been reset by an administrator and must be changed
+// before the backend accepts it. The password of the user is valid.
+var ErrPasswordMustChange = fmt.Errorf("%w: the password must be changed", ErrPasswordExpired)
+
+// ErrInvalidCredentials indicates the authentication backend rejected the password of the user.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrAccountDisabled indicates the account of the user is disabled or has expired.
+var ErrAccountDisabled = errors.New("account disabled")
+
+// ErrAccountLocked indicates the account of the user has been locked by the password policy of the backend.
+var ErrAccountLocked = errors.New("account locked")
+
+// ErrTooManyGroups indicates the groups filter matches more groups than the maximum number of groups of a user.
+var ErrTooManyGroups = errors.New("too many groups")
+
+// ErrTooManyUsers indicates the list users filter matches more users than the maximum number of users.
+var ErrTooManyUsers = errors.New("too many users")
+
+// ErrSearchResultTooLarge indicates a search of the authentication backend returned entries larger than the maximum
+// size of the search results.
+var ErrSearchResultTooLarge = errors.New("search result too large")
+
+// ErrPasswordPolicyViolation indicates the new password of the user does not satisfy the password policy.
+var ErrPasswordPolicyViolation = errors.New("the password does not satisfy the password policy")
+
+// The violations of the password policy the LDAP servers report in the diagnostic message, they wrap
+// ErrPasswordPolicyViolation.
+var (
+	// ErrPasswordTooShort indicates the new password of the user is shorter than the minimum length of the policy.
+	ErrPasswordTooShort = fmt.Errorf("%w: the password is too short", ErrPasswordPolicyViolation)
+
+	// ErrPasswordInHistory indicates the new password of the user is one of their previous passwords.
+	ErrPasswordInHistory = fmt.Errorf("%w: the password has already been used", ErrPasswordPolicyViolation)
+
+	// ErrPasswordTooYoung indicates the password of the user has been changed too recently to be changed again.
+	ErrPasswordTooYoung = fmt.Errorf("%w: the password has been changed too recently", ErrPasswordPolicyViolation)
+)
+
+// ErrResetTokenNotConfigured indicates the authentication backend is not configured with an attribute holding the reset
+// tokens of the users.
+var ErrResetTokenNotConfigured = errors.New("the reset token attribute is not configured")
+
+// ErrResetTokenMismatch indicates the reset token doesn't match the one held by the authentication backend for the user.
+var ErrResetTokenMismatch = errors.New("the reset token does not match")
+
+// ErrResetTokenExpired indicates the reset token held by the authentication backend for the user has expired.
+var ErrResetTokenExpired = errors.New("the reset token has expired")
+
+// ErrPasswordChangeNotPermitted indicates the authentication backend doesn't permit the user to change their password.
+var ErrPasswordChangeNotPermitted = errors.New("the password change is not permitted")
+
+// ErrPasswordAssertionFailed indicates the password of the user was not updated because the user no longer matches the
+// state asserted by the password update, for instance after a concurrent change by an administrator.
+var ErrPasswordAssertionFailed = errors.New("the user no longer matches the asserted state")
+
+const ldapSchemeSRV = "srv"
+
+// ldapSchemeSRVS is the scheme of the URLs of the domains whose LDAP servers are discovered with the _ldaps._tcp SRV
+// records and connected to with the ldaps scheme.
+const ldapSchemeSRVS = "srvs"
+
+// ldapSchemeLDAPI is the scheme of the URLs of the LDAP servers listening on a Unix domain socket.
+const ldapSchemeLDAPI = "ldapi"
+
+// The Active Directory attributes used to resolve the primary group of the users.
+const (
+	adAttributeObjectSID      = "objectSid"
+	adAttributePrimaryGroupID = "primaryGroupID"
+)
+
+// adUsersFilterConstraints are the filters restricting the users filters of Active Directory to the user accounts,
+// which excludes the computer accounts sharing the user object class as well as the contacts and the groups.
+var adUsersFilterConstraints = []string{"(objectCategory=person)", "(objectClass=user)"}
+
+// adAttributeObjectGUID is the Active Directory attribute holding the immutable binary GUID of the objects.
+const adAttributeObjectGUID = "objectGUID"
+
+// The Active Directory attribute holding the addresses of the mailbox prefixed by their type, the primary SMTP address
+// being prefixed by SMTP: in uppercase and the others by smtp: in lowercase.
+const (
+	adAttributeProxyAddresses = "proxyAddresses"
+	adProxyAddressPrefixSMTP  = "smtp:"
+	adProxyAddressPrimarySMTP = "SMTP:"
+)
+
+// The Active Directory attributes holding the password and account timestamps.
+const (
+	adAttributePwdLastSet                         = "pwdLastSet"
+	adAttributeAccountExpires                     = "accountExpires"
+	adAttributeMSDSUserPasswordExpiryTimeComputed = "msDS-UserPasswordExpiryTimeComputed"
+)
+
+// The sub-error codes embedded by Active Directory in the diagnostic message of the failed binds, see
+// https://ldapwiki.com/wiki/Common%20Active%20Directory%20Bind%20Errors.
+const (
+	adBindErrorInvalidCredentials = "52e"
+	adBindErrorPasswordExpired    = "532"
+	adBindErrorAccountDisabled    = "533"
+	adBindErrorAccountExpired     = "701"
+	adBindErrorPasswordMustChange = "773"
+	adBindErrorAccountLockedOut   = "775"
+)
+
+// The password policy attributes holding the password timestamps and state, see
+// https://tools.ietf.org/html/draft-behera-ldap-password-policy-10.
+const (
+	ppolicyAttributePwdChangedTime    = "pwdChangedTime"
+	ppolicyAttributePwdPolicySubentry = "pwdPolicySubentry"
+	ppolicyAttributePwdMaxAge         = "pwdMaxAge"
+	ppolicyAttributePwdReset          = "pwdReset"
+)
+
+// ldapAllOperationalAttributes is the pseudo attribute requesting all the operational attributes of an entry, see
+// https://tools.ietf.org/html/rfc3673.
+const ldapAllOperationalAttributes = "+"
+
+// ldapRetryBackoff is the delay before the first retry of an operation failing with a transient error.
+const ldapRetryBackoff = 100 * time.Millisecond
+
+// ldapHealthcheckTimeout is the maximum duration of the healthcheck of the LDAP server.
+const ldapHealthcheckTimeout = 5 * time.Second
+
+// The steps of the LDAP operations reported in the logs.
+const (
+	ldapStepDial   = "dial"
+	ldapStepBind   = "bind"
+	ldapStepSearch = "search"
+	ldapStepModify = "modify"
+	ldapStepWhoAmI = "whoami"
+)
+
+// The operations and the results of the metrics of the LDAP operations.
+const (
+	ldapMetricBind           = "bind"
+	ldapMetricSearchUser     = "search_user"
+	ldapMetricSearchGroups   = "search_groups"
+	ldapMetricSearchUsers    = "search_users"
+	ldapMetricModifyPassword = "modify_password"
+	ldapMetricCircuitBreaker = "circuit_breaker"
+
+	ldapMetricResultSuccess  = "success"
+	ldapMetricResultFailure  = "failure"
+	ldapMetricResultEmpty    = "empty"
+	ldapMetricResultOpen     = "open"
+	ldapMetricResultClosed   = "closed"
+	ldapMetricResultRejected = "rejected"
+
+	ldapMetricOperationsTotal   = "ldap_operations_total"
+	ldapMetricOperationDuration = "ldap_operation_duration_seconds"
+	ldapMetricLabelOperation    = "operation"
+	ldapMetricLabelResult       = "result"
+)
+
+// ldapServerDiscoveryRefreshInterval is the interval after which the SRV records of the LDAP servers are resolved
+// again.
+const ldapServerDiscoveryRefreshInterval = 5 * time.Minute
+
+// ldapServerDiscoveryRetryInterval is the interval after which the SRV records of the LDAP servers are resolved again
+// after a failed resolution, doubled with each consecutive failure up to the refresh interval.
+const ldapServerDiscoveryRetryInterval = 10 * time.Second
+
+// ldapTLSSessionCacheSize is the number of TLS sessions to the LDAP servers kept to be resumed when the warm up is
+// enabled.
+const ldapTLSSessionCacheSize = 64
+
 const argon2id = "argon2id"
 const sha512 = "sha512"
 
@@ -61,6 +262,11 @@ const testPassword = "my;secure*password"
 
 const fileAuthenticationMode = 0600
 
-// OWASP recommends to escape some special characters.
+// OWASP recommends to escape some special characters. They are the default of the escaped characters configuration
+// and are escaped in addition to the characters which are always escaped in a filter.
 // https://github.com/OWASP/CheatSheetSeries/blob/master/cheatsheets/LDAP_Injection_Prevention_Cheat_Sheet.md
 const specialLDAPRunes = ",#+<>;\"="
+
+// ldapFilterRunes are the characters which must always be escaped in the values of a filter according to RFC4515,
+// along with the non ASCII characters.
+const ldapFilterRunes = "\\()*\x00"
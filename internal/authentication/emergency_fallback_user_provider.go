@@ -0,0 +1,155 @@
+package authentication
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+)
+
+// EmergencyFallbackUserProvider is a provider delegating to a primary provider, the named emergency users fall back to
+// the fallback provider when the primary provider is unavailable so they are not locked out of Authelia during an
+// outage of the directory. The emergency users do not fall back when the primary provider rejects their credentials
+// and the other users never fall back.
+type EmergencyFallbackUserProvider struct {
+	primary   UserProvider
+	fallback  UserProvider
+	usernames map[string]bool
+}
+
+// NewEmergencyFallbackUserProvider creates a new instance of EmergencyFallbackUserProvider falling back to the users
+// database file of the configuration.
+func NewEmergencyFallbackUserProvider(primary UserProvider, configuration schema.LDAPEmergencyFallbackConfiguration) *EmergencyFallbackUserProvider {
+	fallback := NewFileUserProvider(&schema.FileAuthenticationBackendConfiguration{
+		Path:     configuration.Path,
+		Password: &schema.DefaultPasswordConfiguration,
+	})
+
+	return newEmergencyFallbackUserProvider(primary, fallback, configuration.Usernames)
+}
+
+func newEmergencyFallbackUserProvider(primary UserProvider, fallback UserProvider, usernames []string) *EmergencyFallbackUserProvider {
+	provider := &EmergencyFallbackUserProvider{
+		primary:   primary,
+		fallback:  fallback,
+		usernames: make(map[string]bool, len(usernames)),
+	}
+
+	for _, username := range usernames {
+		provider.usernames[username] = true
+	}
+
+	return provider
+}
+
+// shouldFallback returns true when the user is an emergency user and the primary provider failed because it is
+// unavailable.
+func (p *EmergencyFallbackUserProvider) shouldFallback(username string, err error) bool {
+	return err != nil && p.usernames[username] && IsBackendUnavailableError(err)
+}
+
+// CheckUserPassword checks if provided password matches for the given user.
+func (p *EmergencyFallbackUserProvider) CheckUserPassword(username string, password string) (bool, error) {
+	ok, err := p.primary.CheckUserPassword(username, password)
+	if !p.shouldFallback(username, err) {
+		return ok, err
+	}
+
+	logging.Logger().Warnf("Checking the password of emergency user %s with the fallback users database as the authentication backend is unavailable: %s", username, err)
+
+	return p.fallback.CheckUserPassword(username, password)
+}
+
+// CheckUserPasswordAndGetDetails checks if provided password matches for the given user and retrieves their details,
+// both from the fallback users database for the emergency users when the primary provider is unavailable.
+func (p *EmergencyFallbackUserProvider) CheckUserPasswordAndGetDetails(username string, password string) (bool, *UserDetails, error) {
+	ok, details, err := p.primary.CheckUserPasswordAndGetDetails(username, password)
+	if !p.shouldFallback(username, err) {
+		return ok, details, err
+	}
+
+	logging.Logger().Warnf("Checking the password of emergency user %s with the fallback users database as the authentication backend is unavailable: %s", username, err)
+
+	return p.fallback.CheckUserPasswordAndGetDetails(username, password)
+}
+
+// GetDetails retrieve the groups a user belongs to.
+func (p *EmergencyFallbackUserProvider) GetDetails(username string) (*UserDetails, error) {
+	details, err := p.primary.GetDetails(username)
+	if !p.shouldFallback(username, err) {
+		return details, err
+	}
+
+	logging.Logger().Warnf("Retrieving the details of emergency user %s from the fallback users database as the authentication backend is unavailable: %s", username, err)
+
+	return p.fallback.GetDetails(username)
+}
+
+// GetDetailsByEmail retrieve the details of the user owning the email, the user found in the fallback users database
+// is only returned when it is an emergency user.
+func (p *EmergencyFallbackUserProvider) GetDetailsByEmail(email string) (*UserDetails, error) {
+	details, err := p.primary.GetDetailsByEmail(email)
+	if err == nil || !IsBackendUnavailableError(err) {
+		return details, err
+	}
+
+	fallbackDetails, fallbackErr := p.fallback.GetDetailsByEmail(email)
+	if fallbackErr != nil || !p.usernames[fallbackDetails.Username] {
+		return nil, err
+	}
+
+	logging.Logger().Warnf("Retrieving the details of emergency user %s from the fallback users database as the authentication backend is unavailable: %s", fallbackDetails.Username, err)
+
+	return fallbackDetails, nil
+}
+
+// UserExists returns true when the user exists in the primary provider, or in the fallback users database for the
+// emergency users when the primary provider is unavailable.
+func (p *EmergencyFallbackUserProvider) UserExists(username string) (bool, error) {
+	exists, err := p.primary.UserExists(username)
+	if !p.shouldFallback(username, err) {
+		return exists, err
+	}
+
+	return p.fallback.UserExists(username)
+}
+
+// UpdatePassword update the password of the given user in the primary provider, the passwords of the emergency users
+// in the fallback users database are only updated by the administrators.
+func (p *EmergencyFallbackUserProvider) UpdatePassword(username string, newPassword string) error {
+	return p.primary.UpdatePassword(username, newPassword)
+}
+
+// ListUsers returns the usernames of the users of the primary provider.
+func (p *EmergencyFallbackUserProvider) ListUsers() ([]string, error) {
+	return p.primary.ListUsers()
+}
+
+// StartupCheck checks the emergency users are in the fallback users database and the primary provider is ready.
+// Authelia starts when the primary provider is unavailable so the emergency users can login during the outage.
+func (p *EmergencyFallbackUserProvider) StartupCheck() error {
+	if err := p.fallback.StartupCheck(); err != nil {
+		return err
+	}
+
+	for username := range p.usernames {
+		if _, err := p.fallback.GetDetails(username); err != nil {
+			return fmt.Errorf("Unable to find emergency user %s in the fallback users database. Cause: %s", username, err)
+		}
+	}
+
+	err := p.primary.StartupCheck()
+	if err != nil && IsBackendUnavailableError(err) {
+		logging.Logger().Errorf("The authentication backend is unavailable at startup, only the emergency users can login until it is available: %s", err)
+
+		return nil
+	}
+
+	return err
+}
+
+// Healthcheck checks the primary provider is healthy, the fallback keeps the emergency users able to login but does
+// not make an unavailable primary provider healthy.
+func (p *EmergencyFallbackUserProvider) Healthcheck() error {
+	return p.primary.Healthcheck()
+}
@@ -0,0 +1,191 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fallbackTestUserProvider is a users database of users sharing the same password.
+type fallbackTestUserProvider struct {
+	UserProvider
+
+	users    []UserDetails
+	password string
+}
+
+func (p *fallbackTestUserProvider) CheckUserPassword(username string, password string) (bool, error) {
+	if _, err := p.GetDetails(username); err != nil {
+		return false, err
+	}
+
+	return password == p.password, nil
+}
+
+func (p *fallbackTestUserProvider) CheckUserPasswordAndGetDetails(username string, password string) (bool, *UserDetails, error) {
+	details, err := p.GetDetails(username)
+	if err != nil || password != p.password {
+		return false, nil, err
+	}
+
+	return true, details, nil
+}
+
+func (p *fallbackTestUserProvider) GetDetails(username string) (*UserDetails, error) {
+	for i := range p.users {
+		if p.users[i].Username == username {
+			return &p.users[i], nil
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
+func (p *fallbackTestUserProvider) GetDetailsByEmail(email string) (*UserDetails, error) {
+	for i := range p.users {
+		if p.users[i].Emails[0] == email {
+			return &p.users[i], nil
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
+func (p *fallbackTestUserProvider) UserExists(username string) (bool, error) {
+	_, err := p.GetDetails(username)
+
+	return err == nil, nil
+}
+
+func (p *fallbackTestUserProvider) StartupCheck() error {
+	return nil
+}
+
+func newEmergencyFallbackTestProvider(ctrl *gomock.Controller, usernames ...string) (*EmergencyFallbackUserProvider, *MockLDAPConnectionFactory, *MockLDAPConnection) {
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	fallback := &fallbackTestUserProvider{
+		users: []UserDetails{
+			{Username: "john", DisplayName: "John Doe", Emails: []string{"john.doe@authelia.com"}, Groups: []string{"admins", "dev"}},
+			{Username: "harry", DisplayName: "Harry Potter", Emails: []string{"harry.potter@authelia.com"}},
+		},
+		password: "password",
+	}
+
+	return newEmergencyFallbackUserProvider(ldapClient, fallback, usernames), mockFactory, mockConn
+}
+
+func TestShouldFallBackForEmergencyUserWhenLDAPIsUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider, mockFactory, _ := newEmergencyFallbackTestProvider(ctrl, "john")
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(5)
+
+	ok, err := provider.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, details, err := provider.CheckUserPasswordAndGetDetails("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "John Doe", details.DisplayName)
+
+	details, err = provider.GetDetails("john")
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", details.DisplayName)
+	assert.Equal(t, []string{"admins", "dev"}, details.Groups)
+
+	details, err = provider.GetDetailsByEmail("john.doe@authelia.com")
+	require.NoError(t, err)
+	assert.Equal(t, "john", details.Username)
+
+	exists, err := provider.UserExists("john")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestShouldNotFallBackForOtherUsersWhenLDAPIsUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider, mockFactory, _ := newEmergencyFallbackTestProvider(ctrl, "john")
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	ok, err := provider.CheckUserPassword("harry", "password")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+	assert.False(t, ok)
+
+	_, err = provider.GetDetailsByEmail("harry.potter@authelia.com")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+}
+
+func TestShouldNotFallBackForEmergencyUserWhenLDAPRejectsCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider, mockFactory, mockConn := newEmergencyFallbackTestProvider(ctrl, "john")
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil).AnyTimes()
+	mockConn.EXPECT().Close().AnyTimes()
+
+	// The password of the fallback users database is not checked although it matches.
+	ok, err := provider.CheckUserPassword("john", "password")
+	assert.Error(t, err)
+	assert.False(t, IsBackendUnavailableError(err))
+	assert.False(t, ok)
+}
+
+func TestShouldStartWhenLDAPIsUnavailableWithEmergencyFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider, mockFactory, _ := newEmergencyFallbackTestProvider(ctrl, "john")
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	assert.NoError(t, provider.StartupCheck())
+	assert.Error(t, provider.Healthcheck())
+}
+
+func TestShouldNotStartWhenEmergencyUserIsNotInFallbackDatabase(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider, _, _ := newEmergencyFallbackTestProvider(ctrl, "john", "breakglass")
+
+	assert.EqualError(t, provider.StartupCheck(), "Unable to find emergency user breakglass in the fallback users database. Cause: user not found")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -158,6 +159,19 @@ func (p *FileUserProvider) CheckUserPassword(username string, password string) (
 	return false, ErrUserNotFound
 }
 
+// CheckUserPasswordAndGetDetails checks if provided password matches for the given user and retrieves their details,
+// which are nil when the password doesn't match.
+func (p *FileUserProvider) CheckUserPasswordAndGetDetails(username string, password string) (bool, *UserDetails, error) {
+	ok, err := p.CheckUserPassword(username, password)
+	if err != nil || !ok {
+		return ok, nil, err
+	}
+
+	details, err := p.GetDetails(username)
+
+	return true, details, err
+}
+
 // GetDetails retrieve the groups a user belongs to.
 func (p *FileUserProvider) GetDetails(username string) (*UserDetails, error) {
 	if details, ok := p.database.Users[username]; ok {
@@ -172,6 +186,42 @@ func (p *FileUserProvider) GetDetails(username string) (*UserDetails, error) {
 	return nil, fmt.Errorf("User '%s' does not exist in database", username)
 }
 
+// GetDetailsByEmail retrieve the details of the single user with the given email.
+func (p *FileUserProvider) GetDetailsByEmail(email string) (*UserDetails, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var username string
+
+	for name, details := range p.database.Users {
+		if !strings.EqualFold(details.Email, email) {
+			continue
+		}
+
+		if username != "" {
+			return nil, fmt.Errorf("%w with email %s", ErrMultipleUsersFound, email)
+		}
+
+		username = name
+	}
+
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+
+	return p.GetDetails(username)
+}
+
+// UserExists returns true when the user is in the database.
+func (p *FileUserProvider) UserExists(username string) (bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_, ok := p.database.Users[username]
+
+	return ok, nil
+}
+
 // UpdatePassword update the password of the given user.
 func (p *FileUserProvider) UpdatePassword(username string, newPassword string) error {
 	details, ok := p.database.Users[username]
@@ -209,3 +259,29 @@ func (p *FileUserProvider) UpdatePassword(username string, newPassword string) e
 
 	return err
 }
+
+// ListUsers returns the usernames of all the users of the database sorted alphabetically.
+func (p *FileUserProvider) ListUsers() ([]string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	usernames := make([]string, 0, len(p.database.Users))
+
+	for username := range p.database.Users {
+		usernames = append(usernames, username)
+	}
+
+	sort.Strings(usernames)
+
+	return usernames, nil
+}
+
+// StartupCheck always succeeds as the database is checked when the provider is created.
+func (p *FileUserProvider) StartupCheck() error {
+	return nil
+}
+
+// Healthcheck always succeeds as the database is loaded in memory.
+func (p *FileUserProvider) Healthcheck() error {
+	return nil
+}
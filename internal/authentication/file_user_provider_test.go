@@ -142,6 +142,69 @@ func TestShouldRetrieveUserDetails(t *testing.T) {
 	})
 }
 
+func TestShouldCheckUserPasswordAndRetrieveUserDetails(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		ok, details, err := provider.CheckUserPasswordAndGetDetails("john", "password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, details.Username, "john")
+		assert.Equal(t, details.Groups, []string{"admins", "dev"})
+
+		ok, details, err = provider.CheckUserPasswordAndGetDetails("john", "wrong_password")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, details)
+	})
+}
+
+func TestShouldCheckUserExists(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		exists, err := provider.UserExists("john")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = provider.UserExists("nobody")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestShouldRetrieveUserDetailsByEmail(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		details, err := provider.GetDetailsByEmail("John.Doe@authelia.com")
+		assert.NoError(t, err)
+		assert.Equal(t, details.Username, "john")
+		assert.Equal(t, details.Groups, []string{"admins", "dev"})
+
+		_, err = provider.GetDetailsByEmail("james.dean@authelia.com")
+		assert.EqualError(t, err, "multiple users found with email james.dean@authelia.com")
+
+		_, err = provider.GetDetailsByEmail("nobody@authelia.com")
+		assert.EqualError(t, err, "user not found")
+	})
+}
+
+func TestShouldListUsers(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		usernames, err := provider.ListUsers()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bob", "enumeration", "harry", "james", "john"}, usernames)
+	})
+}
+
 func TestShouldUpdatePassword(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
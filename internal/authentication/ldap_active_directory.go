@@ -0,0 +1,125 @@
+package authentication
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// adPrimaryGroupSID computes the binary SID of the primary group of an Active Directory user. The primary group
+// belongs to the domain of the user, its SID is therefore the SID of the user with the RID replaced by the
+// primaryGroupID.
+func adPrimaryGroupSID(userSID []byte, primaryGroupID string) ([]byte, error) {
+	rid, err := strconv.ParseUint(primaryGroupID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid primaryGroupID %s. Cause: %s", primaryGroupID, err)
+	}
+
+	// The SID is composed of the revision, the number of sub authorities, the 6 bytes of the identifier authority
+	// and the 4 bytes little-endian sub authorities, the last one being the RID.
+	if len(userSID) < 12 || len(userSID) != 8+4*int(userSID[1]) {
+		return nil, fmt.Errorf("Invalid objectSid of %d bytes", len(userSID))
+	}
+
+	groupSID := make([]byte, len(userSID))
+	copy(groupSID, userSID)
+
+	binary.LittleEndian.PutUint32(groupSID[len(groupSID)-4:], uint32(rid))
+
+	return groupSID, nil
+}
+
+// adConstrainUsersFilter combines the users filter with the constraints restricting it to the user accounts which the
+// filter lacks, so a too broad filter such as (sAMAccountName={input}) doesn't match a computer account. The filter is
+// returned as is when it already contains every constraint.
+func adConstrainUsersFilter(filter string) string {
+	var missing []string
+
+	for _, constraint := range adUsersFilterConstraints {
+		if !strings.Contains(filter, constraint) || strings.Contains(filter, "(!"+constraint+")") {
+			missing = append(missing, constraint)
+		}
+	}
+
+	if len(missing) == 0 {
+		return filter
+	}
+
+	return "(&" + filter + strings.Join(missing, "") + ")"
+}
+
+// parseStableID parses the value of the stable identifier attribute of the entry into the profile. The binary
+// objectGUID and objectSid of Active Directory are formatted as their canonical string, the other attributes such as
+// the entryUUID of OpenLDAP are used as is.
+func (p *LDAPUserProvider) parseStableID(ctx context.Context, entry *ldap.Entry, profile *ldapUserProfile) {
+	attribute := p.configuration.StableIDAttribute
+
+	var err error
+
+	switch {
+	case strings.EqualFold(attribute, adAttributeObjectGUID):
+		profile.StableID, err = adGUIDString(entry.GetEqualFoldRawAttributeValue(attribute))
+	case strings.EqualFold(attribute, adAttributeObjectSID):
+		profile.StableID, err = adSIDString(entry.GetEqualFoldRawAttributeValue(attribute))
+	default:
+		profile.StableID = entry.GetEqualFoldAttributeValue(attribute)
+	}
+
+	if err != nil {
+		operationLogger(ctx).Warnf("Unable to parse the %s of user %s. Cause: %s", attribute, profile.DN, err)
+	}
+
+	if profile.StableID == "" {
+		operationLogger(ctx).Debugf("No stable identifier found for user %s in the attribute %s", profile.DN, attribute)
+	}
+}
+
+// adGUIDString formats a binary GUID such as the objectGUID of Active Directory as its canonical string. The first
+// three groups are stored little-endian.
+func adGUIDString(guid []byte) (string, error) {
+	if len(guid) == 0 {
+		return "", nil
+	}
+
+	if len(guid) != 16 {
+		return "", fmt.Errorf("Invalid GUID of %d bytes", len(guid))
+	}
+
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(guid[0:4]), binary.LittleEndian.Uint16(guid[4:6]), binary.LittleEndian.Uint16(guid[6:8]),
+		guid[8:10], guid[10:16]), nil
+}
+
+// adSIDString formats a binary SID such as the objectSid of Active Directory as its string representation, for
+// instance S-1-5-21-1-2-3-1105.
+func adSIDString(sid []byte) (string, error) {
+	if len(sid) == 0 {
+		return "", nil
+	}
+
+	// The SID is composed of the revision, the number of sub authorities, the 6 bytes big-endian identifier authority
+	// and the 4 bytes little-endian sub authorities.
+	if len(sid) < 8 || len(sid) != 8+4*int(sid[1]) {
+		return "", fmt.Errorf("Invalid objectSid of %d bytes", len(sid))
+	}
+
+	var authority uint64
+
+	for _, b := range sid[2:8] {
+		authority = authority<<8 | uint64(b)
+	}
+
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "S-%d-%d", sid[0], authority)
+
+	for i := 8; i < len(sid); i += 4 {
+		fmt.Fprintf(&builder, "-%d", binary.LittleEndian.Uint32(sid[i:i+4]))
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,318 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// testUserSID is the binary representation of S-1-5-21-1-2-3-1105.
+var testUserSID = []byte{
+	0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05,
+	0x15, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x00, 0x00,
+	0x02, 0x00, 0x00, 0x00,
+	0x03, 0x00, 0x00, 0x00,
+	0x51, 0x04, 0x00, 0x00,
+}
+
+func TestShouldComputePrimaryGroupSID(t *testing.T) {
+	groupSID, err := adPrimaryGroupSID(testUserSID, "513")
+	require.NoError(t, err)
+
+	// S-1-5-21-1-2-3-513.
+	expected := append(append([]byte{}, testUserSID[:24]...), 0x01, 0x02, 0x00, 0x00)
+
+	assert.Equal(t, expected, groupSID)
+	assert.Equal(t, byte(0x51), testUserSID[24], "the SID of the user must not be modified")
+}
+
+func TestShouldFailToComputePrimaryGroupSIDWithInvalidValues(t *testing.T) {
+	_, err := adPrimaryGroupSID(testUserSID, "abc")
+	assert.EqualError(t, err, "Invalid primaryGroupID abc. Cause: strconv.ParseUint: parsing \"abc\": invalid syntax")
+
+	_, err = adPrimaryGroupSID(testUserSID[:20], "513")
+	assert.EqualError(t, err, "Invalid objectSid of 20 bytes")
+}
+
+// activeDirectoryTestConfiguration is the configuration of the provider of the Active Directory tests.
+var activeDirectoryTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	Implementation: schema.LDAPImplementationActiveDirectory,
+	URL:            "ldap://127.0.0.1:389",
+	User:           "cn=admin,dc=example,dc=com",
+	Password:       "password",
+	UsersFilter:    schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+	GroupsFilter:   schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.GroupsFilter,
+	BaseDN:         "dc=corp,dc=example",
+}
+
+func TestShouldMatchUserPrincipalNameWithDefaultActiveDirectoryUsersFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, _ := newTestLDAPUserProvider(ctrl, activeDirectoryTestConfiguration)
+
+	// The @ and the dots of the UPN are not special in the filters and are kept as is.
+	assert.Equal(t, "(&(|(sAMAccountName=jane.doe@corp.example)(mail=jane.doe@corp.example)(userPrincipalName=jane.doe@corp.example))"+
+		"(objectCategory=person)(objectClass=user)(!userAccountControl:1.2.840.113556.1.4.803:=2))",
+		ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, "jane.doe@corp.example"))
+
+	assert.Equal(t, "(&(|(sAMAccountName=jane\\2a\\29\\28cn\\3dx@corp.example)(mail=jane\\2a\\29\\28cn\\3dx@corp.example)"+
+		"(userPrincipalName=jane\\2a\\29\\28cn\\3dx@corp.example))"+
+		"(objectCategory=person)(objectClass=user)(!userAccountControl:1.2.840.113556.1.4.803:=2))",
+		ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, "jane*)(cn=x@corp.example"))
+}
+
+func TestShouldFailWhenUserPrincipalNameAndSAMAccountNameMatchDifferentUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, activeDirectoryTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{DN: "CN=Jane Doe,CN=Users,DC=corp,DC=example"},
+					{DN: "CN=Jane Roe,CN=Users,DC=corp,DC=example"},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("jane@corp.example")
+
+	assert.True(t, errors.Is(err, ErrMultipleUsersFound))
+}
+
+func TestShouldReturnDetailsOfUserWhosePasswordMustChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			Implementation:       schema.LDAPImplementationActiveDirectory,
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "sAMAccountName",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayName",
+			GroupNameAttribute:   "cn",
+			UsersFilter:          schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+			GroupsFilter:         schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.GroupsFilter,
+			BaseDN:               "dc=corp,dc=example",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			DoAndReturn(func(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+				// The default users filter doesn't exclude the users whose password must change.
+				assert.NotContains(t, searchRequest.Filter, "pwdLastSet")
+
+				return &ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: "CN=John,CN=Users,DC=corp,DC=example",
+							Attributes: []*ldap.EntryAttribute{
+								{Name: "sAMAccountName", Values: []string{"john"}},
+								{Name: "mail", Values: []string{"john@corp.example"}},
+								{Name: "pwdLastSet", Values: []string{"0"}},
+							},
+						},
+					},
+				}, nil
+			}),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockUserConn, nil),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("CN=John,CN=Users,DC=corp,DC=example"), gomock.Eq("password")).
+			Return(newADBindError("773")),
+		mockUserConn.EXPECT().
+			Close(),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	valid, details, err := ldapClient.CheckUserPasswordAndGetDetailsWithContext(context.Background(), "john", "password")
+
+	assert.False(t, valid)
+	assert.True(t, errors.Is(err, ErrPasswordMustChange))
+	assert.True(t, errors.Is(err, ErrPasswordExpired))
+
+	require.NotNil(t, details)
+	assert.Equal(t, "john", details.Username)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+	assert.True(t, details.MustChangePassword)
+}
+
+func TestShouldConstrainActiveDirectoryUsersFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{
+			"Default",
+			schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+			schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+		},
+		{
+			"MissingConstraints",
+			"(sAMAccountName={input})",
+			"(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))",
+		},
+		{
+			"MissingObjectCategory",
+			"(&(sAMAccountName={input})(objectClass=user))",
+			"(&(&(sAMAccountName={input})(objectClass=user))(objectCategory=person))",
+		},
+		{
+			"NegatedConstraint",
+			"(&(sAMAccountName={input})(objectClass=user)(!(objectCategory=person)))",
+			"(&(&(sAMAccountName={input})(objectClass=user)(!(objectCategory=person)))(objectCategory=person))",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, adConstrainUsersFilter(tc.filter))
+		})
+	}
+}
+
+func TestShouldConstrainActiveDirectoryUsersFiltersUnlessDisabled(t *testing.T) {
+	configuration := schema.LDAPAuthenticationBackendConfiguration{
+		Implementation:    schema.LDAPImplementationActiveDirectory,
+		UsernameAttribute: "sAMAccountName",
+		UsersFilter:       "({username_attribute}={input})",
+		ListUsersFilter:   "(sAMAccountName=*)",
+		BaseDN:            "dc=corp,dc=example",
+	}
+
+	ldapClient := NewLDAPUserProvider(configuration, nil)
+
+	assert.Equal(t, "(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))", ldapClient.configuration.UsersFilter)
+	assert.Equal(t, "(&(sAMAccountName=*)(objectCategory=person)(objectClass=user))", ldapClient.listUsersFilter)
+
+	configuration.DisableUsersFilterConstraint = true
+
+	ldapClient = NewLDAPUserProvider(configuration, nil)
+
+	assert.Equal(t, "(sAMAccountName={input})", ldapClient.configuration.UsersFilter)
+	assert.Equal(t, "(sAMAccountName=*)", ldapClient.listUsersFilter)
+}
+
+// testUserGUID is the binary representation of the objectGUID 12345678-9abc-def0-1122-334455667788.
+var testUserGUID = []byte{
+	0x78, 0x56, 0x34, 0x12, 0xbc, 0x9a, 0xf0, 0xde,
+	0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88,
+}
+
+func TestShouldFormatActiveDirectoryStableIdentifiers(t *testing.T) {
+	guid, err := adGUIDString(testUserGUID)
+	require.NoError(t, err)
+	assert.Equal(t, "12345678-9abc-def0-1122-334455667788", guid)
+
+	sid, err := adSIDString(testUserSID)
+	require.NoError(t, err)
+	assert.Equal(t, "S-1-5-21-1-2-3-1105", sid)
+
+	_, err = adGUIDString(testUserGUID[:10])
+	assert.EqualError(t, err, "Invalid GUID of 10 bytes")
+
+	_, err = adSIDString(testUserSID[:26])
+	assert.EqualError(t, err, "Invalid objectSid of 26 bytes")
+}
+
+func TestShouldReturnStableIdentifierOfUser(t *testing.T) {
+	testCases := []struct {
+		name      string
+		attribute string
+		value     []byte
+		expected  string
+	}{
+		{"ObjectGUID", "", testUserGUID, "12345678-9abc-def0-1122-334455667788"},
+		{"ObjectSid", "objectSid", testUserSID, "S-1-5-21-1-2-3-1105"},
+		{"EntryUUID", "entryUUID", []byte("0d4d2b3e-5e8f-103b-8f0e-b1c9a0e53c2a"), "0d4d2b3e-5e8f-103b-8f0e-b1c9a0e53c2a"},
+		{"Missing", "", nil, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, activeDirectoryTestConfiguration)
+
+			if tc.attribute != "" {
+				ldapClient.configuration.StableIDAttribute = tc.attribute
+			}
+
+			entry := &ldap.Entry{
+				DN: "CN=John,CN=Users,DC=corp,DC=example",
+				Attributes: []*ldap.EntryAttribute{
+					{Name: "sAMAccountName", Values: []string{"john"}},
+				},
+			}
+
+			if tc.value != nil {
+				entry.Attributes = append(entry.Attributes, &ldap.EntryAttribute{
+					Name:       ldapClient.configuration.StableIDAttribute,
+					Values:     []string{string(tc.value)},
+					ByteValues: [][]byte{tc.value},
+				})
+			}
+
+			mockConn.EXPECT().
+				Search(gomock.Any()).
+				DoAndReturn(func(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+					assert.Contains(t, searchRequest.Attributes, ldapClient.configuration.StableIDAttribute)
+					return &ldap.SearchResult{Entries: []*ldap.Entry{entry}}, nil
+				})
+
+			profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, profile.StableID)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package authentication
+
+import (
+	"context"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapJoinDNs joins each of the DNs relative to the base DN with the base DN, after the DN searched first.
+func ldapJoinDNs(name string, first string, relativeDNs []string, baseDN string) []string {
+	dns := make([]string, 0, len(relativeDNs)+1)
+	dns = append(dns, first)
+
+	for _, relativeDN := range relativeDNs {
+		dns = append(dns, ldapJoinDN(name, relativeDN, baseDN))
+	}
+
+	return dns
+}
+
+// searchBaseDNs performs the search request in each of the base DNs in turn and merges the entries, so the users or
+// the groups living under disjoint DNs are searched without searching from the root of the directory. The size limit
+// applies to each base DN, the callers check the merged entries against their limits. An entry found in several base
+// DNs, one being the descendant of another, is only returned once. The entries received before an error are returned
+// along with the error like searchWithPaging.
+func (p *LDAPUserProvider) searchBaseDNs(ctx context.Context, conn LDAPConnection, searchRequest *ldap.SearchRequest, baseDNs []string, pagingSize uint32) (*ldap.SearchResult, error) {
+	if len(baseDNs) == 1 {
+		searchRequest.BaseDN = baseDNs[0]
+
+		return p.searchWithPaging(ctx, conn, searchRequest, pagingSize)
+	}
+
+	merged := &ldap.SearchResult{}
+	seen := make(map[string]bool)
+
+	for _, baseDN := range baseDNs {
+		request := *searchRequest
+		request.BaseDN = baseDN
+
+		sr, err := p.searchWithPaging(ctx, conn, &request, pagingSize)
+
+		if sr != nil {
+			for _, entry := range sr.Entries {
+				if seen[entry.DN] {
+					continue
+				}
+
+				seen[entry.DN] = true
+				merged.Entries = append(merged.Entries, entry)
+			}
+
+			merged.Referrals = append(merged.Referrals, sr.Referrals...)
+			merged.Controls = append(merged.Controls, sr.Controls...)
+		}
+
+		if err != nil {
+			return merged, err
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,144 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldJoinExtraDNsWithBaseDN(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			BaseDN:             "dc=example,dc=com",
+			AdditionalUsersDN:  "ou=users",
+			ExtraUsersDNs:      []string{"ou=people,o=acquired"},
+			AdditionalGroupsDN: "ou=groups",
+			ExtraGroupsDNs:     []string{"ou=groups,o=acquired", "ou=roles"},
+		},
+		nil)
+
+	assert.Equal(t, []string{"ou=users,dc=example,dc=com", "ou=people,o=acquired,dc=example,dc=com"}, ldapClient.usersDNs)
+	assert.Equal(t, []string{"ou=groups,dc=example,dc=com", "ou=groups,o=acquired,dc=example,dc=com", "ou=roles,dc=example,dc=com"}, ldapClient.groupsDNs)
+}
+
+func TestShouldSearchUserInEachUsersDN(t *testing.T) {
+	acquiredUser := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			ldap.NewEntry("uid=jane,ou=people,o=acquired,dc=example,dc=com", map[string][]string{"uid": {"jane"}}),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		usersResult   *ldap.SearchResult
+		expectedDN    string
+		expectedError error
+	}{
+		{"FoundInExtraUsersDN", &ldap.SearchResult{}, "uid=jane,ou=people,o=acquired,dc=example,dc=com", nil},
+		{"FoundInBothUsersDNs", userCheckTestSearchResult(), "", ErrMultipleUsersFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+			ldapClient.usersDNs = []string{"dc=example,dc=com", "ou=people,o=acquired,dc=example,dc=com"}
+
+			gomock.InOrder(
+				mockConn.EXPECT().
+					Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+					Return(tc.usersResult, nil),
+				mockConn.EXPECT().
+					Search(NewSearchRequestBaseDNMatcher("ou=people,o=acquired,dc=example,dc=com")).
+					Return(acquiredUser, nil),
+			)
+
+			profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "jane")
+
+			if tc.expectedError != nil {
+				assert.True(t, errors.Is(err, tc.expectedError))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDN, profile.DN)
+		})
+	}
+}
+
+func TestShouldMergeGroupsOfEachGroupsDN(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.groupsDNs = []string{"dc=example,dc=com", "ou=groups,o=acquired,dc=example,dc=com"}
+
+	// The groups DNs overlap, the group found in both is only returned once.
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("cn=admins,dc=example,dc=com", map[string][]string{"cn": {"admins"}}),
+					ldap.NewEntry("cn=dev,ou=groups,o=acquired,dc=example,dc=com", map[string][]string{"cn": {"dev"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("ou=groups,o=acquired,dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("cn=dev,ou=groups,o=acquired,dc=example,dc=com", map[string][]string{"cn": {"dev"}}),
+					ldap.NewEntry("cn=ops,ou=groups,o=acquired,dc=example,dc=com", map[string][]string{"cn": {"ops"}}),
+				},
+			}, nil),
+	)
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", &ldapUserProfile{
+		DN:       "uid=john,dc=example,dc=com",
+		Username: "john",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "dev", "ops"}, details.Groups)
+}
+
+func TestShouldFailStartupCheckWhenExtraUsersDNIsMissing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.usersDNs = []string{"dc=example,dc=com", "ou=people,o=acquired,dc=example,dc=com"}
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+			Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: "dc=example,dc=com"}}}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("ou=people,o=acquired,dc=example,dc=com")).
+			Return(nil, ldap.NewError(ldap.LDAPResultNoSuchObject, errors.New("no such object"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	err := ldapClient.StartupCheck()
+
+	assert.EqualError(t, err, "Unable to find the base DN ou=people,o=acquired,dc=example,dc=com. Cause: LDAP Result Code 32 \"No Such Object\": no such object")
+}
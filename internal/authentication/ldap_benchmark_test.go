@@ -0,0 +1,103 @@
+package authentication
+
+import (
+	"strings"
+	"testing"
+)
+
+// The benchmarks of the hot paths of the logins, run with the allocations reported by:
+//
+//   go test -run '^$' -bench . -benchmem ./internal/authentication/
+//
+// The operations run end to end against the test LDAP server on the loopback interface, so they include the encoding
+// of the requests and the allocations of the server, which answers in the same process.
+
+func BenchmarkCheckUserPassword(b *testing.B) {
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(b))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if ok, err := ldapClient.CheckUserPassword("john", "password"); err != nil || !ok {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetDetails(b *testing.B) {
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(b))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ldapClient.GetDetails("john"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResolveUsersFilter(b *testing.B) {
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(b))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, "john")
+	}
+}
+
+func BenchmarkResolveGroupsFilter(b *testing.B) {
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(b))
+	profile := &ldapUserProfile{DN: "uid=john,ou=users,dc=example,dc=com", Username: "john"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ldapClient.resolveGroupsFilter("john", profile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkEscapeInputs are the inputs of the escape benchmarks, from a common username to the pathological inputs of
+// a few kilobytes made of the escaped characters only, which every escape expands to several bytes.
+var benchmarkEscapeInputs = []struct {
+	name  string
+	input string
+}{
+	{"Username", "john.doe"},
+	{"Long", strings.Repeat("a", 4096)},
+	{"FilterCharacters", strings.Repeat("*()\\\x00", 820)},
+	{"SpecialRunes", strings.Repeat(specialLDAPRunes, 512)},
+	{"Multibyte", strings.Repeat("é日本", 512)},
+}
+
+func BenchmarkLDAPEscape(b *testing.B) {
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(b))
+
+	for _, input := range benchmarkEscapeInputs {
+		b.Run(input.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(input.input)))
+
+			for i := 0; i < b.N; i++ {
+				ldapClient.ldapEscape(input.input)
+			}
+		})
+	}
+}
+
+func BenchmarkLDAPEscapeDNValue(b *testing.B) {
+	for _, input := range benchmarkEscapeInputs {
+		b.Run(input.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(input.input)))
+
+			for i := 0; i < b.N; i++ {
+				ldapEscapeDNValue(input.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package authentication
+
+import (
+	"context"
+	"strings"
+)
+
+// bindDNFromTemplate builds the DN of the user from the bind DN template, the {input} placeholder being replaced by the
+// normalized input of the user escaped as a DN attribute value.
+func (p *LDAPUserProvider) bindDNFromTemplate(inputUsername string) string {
+	return strings.ReplaceAll(p.configuration.BindDNTemplate, "{input}", ldapEscapeDNValue(p.normalizeUsername(inputUsername)))
+}
+
+// ldapEscapeDNValue escapes an attribute value of a DN according to RFC4514: the special characters are escaped with a
+// backslash, as are the leading space or number sign and the trailing space, and the NUL character is hex encoded.
+func ldapEscapeDNValue(value string) string {
+	var builder strings.Builder
+
+	for i, c := range value {
+		switch {
+		case c == 0:
+			builder.WriteString(`\00`)
+			continue
+		case strings.ContainsRune(`"+,;<>\=`, c),
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(value)-1 && c == ' ':
+			builder.WriteByte('\\')
+		}
+
+		builder.WriteRune(c)
+	}
+
+	return builder.String()
+}
+
+// checkTemplatePassword binds with the DN built from the bind DN template to verify the password of the user, without
+// searching for the user beforehand.
+func (p *LDAPUserProvider) checkTemplatePassword(ctx context.Context, inputUsername string, password string) (*PasswordPolicyWarnings, *TransportSecurity, error) {
+	dn := p.bindDNFromTemplate(inputUsername)
+
+	operationLogger(ctx).Tracef("Computed bind DN of user %s is %s", inputUsername, dn)
+
+	return p.checkProfilePassword(ctx, inputUsername, &ldapUserProfile{DN: dn}, password)
+}
@@ -0,0 +1,68 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldEscapeDNValues(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"john", "john"},
+		{"Brien, O", `Brien\, O`},
+		{`a+b"c\d<e>f;g=h`, `a\+b\"c\\d\<e\>f\;g\=h`},
+		{" #john ", `\ #john\ `},
+		{"#john", `\#john`},
+		{"jo\x00hn", `jo\00hn`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ldapEscapeDNValue(tc.value))
+		})
+	}
+}
+
+func TestShouldCheckPasswordWithBindDNTemplate(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.BindDNTemplate = "uid={input},ou=users,dc=example,dc=com"
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = ldapClient.CheckUserPassword("john", "wrong")
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	// The user is never searched before the bind.
+	assert.Empty(t, server.Searches)
+
+	// The details are still searched.
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+	assert.NotEmpty(t, server.Searches)
+}
+
+func TestShouldEscapeInputOfBindDNTemplate(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.BindDNTemplate = "cn={input},ou=users,dc=example,dc=com"
+
+	ok, err := ldapClient.CheckUserPassword("Brien, O", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// The comma of the input can't add an RDN to the DN.
+	ok, err = ldapClient.CheckUserPassword("Brien,ou=users", "password")
+	assert.False(t, ok)
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package authentication
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// adBindErrorDataRegexp matches the sub-error code embedded by Active Directory in the diagnostic message of a failed
+// bind like 80090308: LdapErr: DSID-0C09042F, comment: AcceptSecurityContext error, data 52e, v2580.
+var adBindErrorDataRegexp = regexp.MustCompile(`\bdata ([0-9a-fA-F]+),`)
+
+// bindError returns the error describing why the LDAP server rejected the bind of a user, from the password policy
+// response control when there is one or from the sub-error code with Active Directory. It is ErrInvalidCredentials
+// when the LDAP server otherwise rejected the credentials and defaults to ErrBindFailed.
+func (p *LDAPUserProvider) bindError(err error, policy *ldap.ControlBeheraPasswordPolicy) error {
+	switch {
+	case policy != nil && policy.Error == ldap.BeheraPasswordExpired:
+		return ErrPasswordExpired
+	case policy != nil && policy.Error == ldap.BeheraAccountLocked:
+		return ErrAccountLocked
+	case p.configuration.Implementation == schema.LDAPImplementationActiveDirectory:
+		if adErr := adBindError(err); adErr != nil {
+			return adErr
+		}
+	}
+
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return ErrInvalidCredentials
+	}
+
+	return ErrBindFailed
+}
+
+// adBindError maps the sub-error code of an Active Directory bind error to the matching error, nil when the error has
+// no known sub-error code.
+func adBindError(err error) error {
+	var ldapErr *ldap.Error
+
+	if !errors.As(err, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultInvalidCredentials {
+		return nil
+	}
+
+	match := adBindErrorDataRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return nil
+	}
+
+	switch strings.ToLower(match[1]) {
+	case adBindErrorInvalidCredentials:
+		return ErrInvalidCredentials
+	case adBindErrorAccountDisabled, adBindErrorAccountExpired:
+		return ErrAccountDisabled
+	case adBindErrorAccountLockedOut:
+		return ErrAccountLocked
+	case adBindErrorPasswordExpired:
+		return ErrPasswordExpired
+	case adBindErrorPasswordMustChange:
+		return ErrPasswordMustChange
+	default:
+		return nil
+	}
+}
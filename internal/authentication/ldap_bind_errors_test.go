@@ -0,0 +1,146 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newADBindError(data string) error {
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials,
+		fmt.Errorf("80090308: LdapErr: DSID-0C09042F, comment: AcceptSecurityContext error, data %s, v2580", data))
+}
+
+func TestShouldMapActiveDirectoryBindErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"InvalidCredentials", newADBindError("52e"), ErrInvalidCredentials},
+		{"PasswordExpired", newADBindError("532"), ErrPasswordExpired},
+		{"AccountDisabled", newADBindError("533"), ErrAccountDisabled},
+		{"AccountExpired", newADBindError("701"), ErrAccountDisabled},
+		{"PasswordMustChange", newADBindError("773"), ErrPasswordMustChange},
+		{"AccountLockedOut", newADBindError("775"), ErrAccountLocked},
+		{"UppercaseCode", newADBindError("52E"), ErrInvalidCredentials},
+		{"UnknownCode", newADBindError("525"), nil},
+		{"NoCode", ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials")), nil},
+		{"OtherResultCode", ldap.NewError(ldap.LDAPResultUnwillingToPerform, errors.New("data 533, v2580")), nil},
+		{"NotLDAPError", errors.New("data 533, v2580"), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, adBindError(tc.err))
+		})
+	}
+}
+
+func TestShouldReturnBindErrorOfImplementation(t *testing.T) {
+	ad := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{Implementation: schema.LDAPImplementationActiveDirectory}, nil)
+	custom := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{Implementation: schema.LDAPImplementationCustom}, nil)
+
+	assert.Equal(t, ErrAccountDisabled, ad.bindError(newADBindError("533"), nil))
+	assert.Equal(t, ErrInvalidCredentials, ad.bindError(newADBindError("525"), nil))
+	assert.Equal(t, ErrInvalidCredentials, custom.bindError(newADBindError("533"), nil))
+	assert.Equal(t, ErrInvalidCredentials, custom.bindError(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials")), nil))
+	assert.Equal(t, ErrBindFailed, custom.bindError(ldap.NewError(ldap.LDAPResultInappropriateAuthentication, errors.New("inappropriate")), nil))
+	assert.Equal(t, ErrBindFailed, custom.bindError(errors.New("invalid credentials"), nil))
+
+	policy := ldap.NewControlBeheraPasswordPolicy()
+	policy.Error = ldap.BeheraAccountLocked
+
+	assert.Equal(t, ErrAccountLocked, custom.bindError(errors.New("invalid credentials"), policy))
+
+	policy.Error = ldap.BeheraPasswordExpired
+
+	assert.Equal(t, ErrPasswordExpired, custom.bindError(errors.New("invalid credentials"), policy))
+}
+
+func TestShouldTellBackendUnavailableErrorsFromCredentialsErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"ConnectionFailed", fmt.Errorf("%w ldap://127.0.0.1:389. Cause: connection refused", ErrConnectionFailed), true},
+		{"CircuitOpen", &ldapCircuitOpenError{address: "ldap://127.0.0.1:389", failures: 5}, true},
+		{"Unavailable", fmt.Errorf("Cannot find user DN of user john. Cause: %w", ldap.NewError(ldap.LDAPResultUnavailable, errors.New("unavailable"))), true},
+		{"DeadlineExceeded", context.DeadlineExceeded, true},
+		{"InvalidCredentials", fmt.Errorf("%w for user john. Cause: invalid", ErrInvalidCredentials), false},
+		{"AccountLocked", ErrAccountLocked, false},
+		{"UserNotFound", ErrUserNotFound, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.unavailable, IsBackendUnavailableError(tc.err))
+		})
+	}
+}
+
+func TestShouldNotReturnCredentialsErrorWhenConnectionFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	testCases := []struct {
+		name   string
+		expect func()
+	}{
+		{
+			name: "AdminConnection",
+			expect: func() {
+				mockFactory.EXPECT().
+					DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+					Return(nil, errors.New("dial tcp 127.0.0.1:389: connect: connection refused"))
+			},
+		},
+		{
+			name: "UserConnection",
+			expect: func() {
+				gomock.InOrder(
+					mockFactory.EXPECT().
+						DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+						Return(mockConn, nil),
+					mockConn.EXPECT().
+						Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+						Return(nil),
+					mockConn.EXPECT().
+						Search(NewSearchRequestMatcher("(uid=john)")).
+						Return(userCheckTestSearchResult(), nil),
+					mockFactory.EXPECT().
+						DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+						Return(nil, errors.New("dial tcp 127.0.0.1:389: connect: connection refused")),
+					mockConn.EXPECT().
+						Unbind().
+						Return(nil),
+					mockConn.EXPECT().
+						Close(),
+				)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect()
+
+			ok, err := ldapClient.CheckUserPassword("john", "password")
+
+			assert.False(t, ok)
+			assert.True(t, errors.Is(err, ErrConnectionFailed))
+			assert.False(t, errors.Is(err, ErrInvalidCredentials))
+			assert.True(t, IsBackendUnavailableError(err))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ldapCircuitBreaker stops the connections to an LDAP server after a number of consecutive connection failures for a
+// cool-down, so a recovering LDAP server is not overwhelmed by every request reconnecting at once. Once the cool-down
+// elapses a single connection is let through to probe the LDAP server, the breaker closes when it succeeds and opens
+// again for another cool-down when it fails. Each LDAP server has a breaker of its own.
+type ldapCircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	clock     utils.Clock
+	servers   map[string]*ldapCircuitBreakerState
+	lock      sync.Mutex
+}
+
+type ldapCircuitBreakerState struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newLDAPCircuitBreaker(threshold int, cooldown time.Duration, clock utils.Clock) *ldapCircuitBreaker {
+	return &ldapCircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     clock,
+		servers:   make(map[string]*ldapCircuitBreakerState),
+	}
+}
+
+// Allow returns nil when a connection to the LDAP server may be attempted, the error when the breaker is open.
+func (b *ldapCircuitBreaker) Allow(address string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state, ok := b.servers[address]
+	if !ok || state.failures < b.threshold {
+		return nil
+	}
+
+	if state.probing || b.clock.Now().Before(state.openUntil) {
+		return &ldapCircuitOpenError{address: address, failures: state.failures}
+	}
+
+	state.probing = true
+
+	return nil
+}
+
+// Success resets the failures of the LDAP server and returns true when it closes the breaker.
+func (b *ldapCircuitBreaker) Success(address string) (closed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state, ok := b.servers[address]
+	if !ok {
+		return false
+	}
+
+	delete(b.servers, address)
+
+	return state.failures >= b.threshold
+}
+
+// Failure counts a connection failure of the LDAP server and returns true when it opens the breaker. The cool-down is
+// jittered so the instances sharing the LDAP server do not probe it all at once.
+func (b *ldapCircuitBreaker) Failure(address string) (opened bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	state, ok := b.servers[address]
+	if !ok {
+		state = &ldapCircuitBreakerState{}
+		b.servers[address] = state
+	}
+
+	state.failures++
+	state.probing = false
+
+	if state.failures < b.threshold {
+		return false
+	}
+
+	state.openUntil = b.clock.Now().Add(ldapJitter(b.cooldown))
+
+	return state.failures == b.threshold
+}
+
+// Release lets another connection probe the LDAP server when the probing connection was abandoned by its caller
+// without telling whether the LDAP server is reachable.
+func (b *ldapCircuitBreaker) Release(address string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if state, ok := b.servers[address]; ok {
+		state.probing = false
+	}
+}
+
+// ldapCircuitOpenError is the error of the connections not attempted because the breaker of the LDAP server is open.
+type ldapCircuitOpenError struct {
+	address  string
+	failures int
+}
+
+func (e *ldapCircuitOpenError) Error() string {
+	return fmt.Sprintf("%s %s. Cause: the circuit breaker is open after %d consecutive connection failures",
+		ErrConnectionFailed, e.address, e.failures)
+}
+
+func (e *ldapCircuitOpenError) Unwrap() error {
+	return ErrConnectionFailed
+}
+
+// isCircuitOpenError returns true when the connection was not attempted because the breaker of the LDAP server is open.
+func isCircuitOpenError(err error) bool {
+	var circuitErr *ldapCircuitOpenError
+
+	return errors.As(err, &circuitErr)
+}
+
+// ldapJitter returns a random duration between half the duration and the duration.
+func ldapJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+
+	return half + time.Duration(rand.Int63n(int64(d-half)+1)) //nolint:gosec // The jitter does not need a secure random.
+}
+
+// connectURLWithCircuitBreaker connects to the LDAP server of the URL unless its breaker is open and counts the
+// connection failures, the binds rejected by a reachable LDAP server or exceeding the user bind timeout are not failures.
+func (p *LDAPUserProvider) connectURLWithCircuitBreaker(ctx context.Context, address string, connect func() error) error {
+	if p.circuitBreaker == nil {
+		return connect()
+	}
+
+	if err := p.circuitBreaker.Allow(address); err != nil {
+		operationLogger(ctx).Debugf("Not connecting to the LDAP server %s. Cause: %s", address, err)
+		p.recordOperationResult(ldapMetricCircuitBreaker, ldapMetricResultRejected, time.Now())
+
+		return err
+	}
+
+	err := connect()
+
+	switch {
+	case ctx.Err() != nil:
+		p.circuitBreaker.Release(address)
+	case errors.Is(err, ErrConnectionFailed):
+		if p.circuitBreaker.Failure(address) {
+			operationLogger(ctx).Warnf("The circuit breaker of the LDAP server %s is open after %d consecutive connection failures, "+
+				"the connections fail fast for up to %s before the LDAP server is probed again", address, p.circuitBreaker.threshold, p.circuitBreaker.cooldown)
+			p.recordOperationResult(ldapMetricCircuitBreaker, ldapMetricResultOpen, time.Now())
+		}
+	default:
+		if p.circuitBreaker.Success(address) {
+			operationLogger(ctx).Infof("The circuit breaker of the LDAP server %s is closed, the LDAP server is reachable again", address)
+			p.recordOperationResult(ldapMetricCircuitBreaker, ldapMetricResultClosed, time.Now())
+		}
+	}
+
+	return err
+}
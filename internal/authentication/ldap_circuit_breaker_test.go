@@ -0,0 +1,101 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldOpenCircuitBreakerAfterConsecutiveFailures(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	breaker := newLDAPCircuitBreaker(2, time.Minute, clock)
+
+	require.NoError(t, breaker.Allow("ldap://a"))
+	assert.False(t, breaker.Failure("ldap://a"))
+	require.NoError(t, breaker.Allow("ldap://a"))
+	assert.True(t, breaker.Failure("ldap://a"))
+
+	err := breaker.Allow("ldap://a")
+	assert.EqualError(t, err, "unable to connect to the authentication backend ldap://a. Cause: the circuit breaker is open after 2 consecutive connection failures")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+	assert.True(t, isCircuitOpenError(err))
+
+	// The breakers of the other LDAP servers are not affected.
+	assert.NoError(t, breaker.Allow("ldap://b"))
+
+	// A single connection probes the LDAP server once the cool-down elapses, it opens the breaker again on failure.
+	clock.now = clock.now.Add(time.Minute)
+
+	require.NoError(t, breaker.Allow("ldap://a"))
+	assert.Error(t, breaker.Allow("ldap://a"))
+	assert.False(t, breaker.Failure("ldap://a"))
+	assert.Error(t, breaker.Allow("ldap://a"))
+
+	clock.now = clock.now.Add(time.Minute)
+
+	require.NoError(t, breaker.Allow("ldap://a"))
+	assert.True(t, breaker.Success("ldap://a"))
+	assert.NoError(t, breaker.Allow("ldap://a"))
+	assert.False(t, breaker.Success("ldap://a"))
+}
+
+func TestShouldJitterBetweenHalfAndFullDuration(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := ldapJitter(time.Second)
+
+		assert.True(t, d >= 500*time.Millisecond && d <= time.Second, "jitter %s out of bounds", d)
+	}
+
+	assert.Equal(t, time.Duration(0), ldapJitter(0))
+}
+
+func TestShouldFailFastWhileCircuitBreakerIsOpen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	ldapClient.circuitBreaker = newLDAPCircuitBreaker(2, time.Minute, clock)
+
+	recorder := &testMetricsRecorder{}
+	ldapClient.SetMetricsRecorder(recorder)
+
+	// Both attempts fail to connect which opens the breaker, the next call fails without connecting nor retrying.
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	_, err := ldapClient.GetDetails("john")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+
+	_, err = ldapClient.GetDetails("john")
+	assert.True(t, isCircuitOpenError(err))
+
+	assert.Equal(t, []string{"circuit_breaker:open", "circuit_breaker:rejected"}, recorder.operations)
+
+	// The LDAP server rejecting the bind of the probing connection is reachable, which closes the breaker.
+	clock.now = clock.now.Add(time.Minute)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err = ldapClient.GetDetails("john")
+	assert.False(t, errors.Is(err, ErrConnectionFailed))
+
+	assert.Equal(t, []string{"circuit_breaker:open", "circuit_breaker:rejected", "bind:failure", "circuit_breaker:closed"}, recorder.operations)
+}
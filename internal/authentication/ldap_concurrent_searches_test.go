@@ -0,0 +1,246 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/proxy"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+const testPrimaryGroupFilter = "(objectSid=\\01\\05\\00\\00\\00\\00\\00\\05\\15\\00\\00\\00" +
+	"\\01\\00\\00\\00\\02\\00\\00\\00\\03\\00\\00\\00\\01\\02\\00\\00)"
+
+// concurrentSearchesTestConfiguration is the configuration of the provider of the concurrent searches tests.
+var concurrentSearchesTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	Implementation:     schema.LDAPImplementationActiveDirectory,
+	URL:                "ldap://127.0.0.1:389",
+	User:               "cn=admin,dc=example,dc=com",
+	Password:           "password",
+	UsernameAttribute:  "sAMAccountName",
+	GroupNameAttribute: "cn",
+	UsersFilter:        "(sAMAccountName={input})",
+	GroupsFilter:       "(member={dn})",
+	BaseDN:             "dc=example,dc=com",
+	ConcurrentSearches: true,
+}
+
+func concurrentSearchesTestProfile() *ldapUserProfile {
+	return &ldapUserProfile{
+		DN:             "CN=John,CN=Users,DC=example,DC=com",
+		Username:       "john",
+		ObjectSID:      testUserSID,
+		PrimaryGroupID: "513",
+	}
+}
+
+func TestShouldSearchGroupsAndPrimaryGroupConcurrently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, concurrentSearchesTestConfiguration)
+	mockSecondConn := NewMockLDAPConnection(ctrl)
+
+	primaryGroupSearched := make(chan struct{})
+
+	// The groups search only returns once the primary group is searched over the second connection.
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=CN=John,CN=Users,DC=example,DC=com)")).
+		DoAndReturn(func(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+			select {
+			case <-primaryGroupSearched:
+			case <-time.After(time.Second):
+				t.Error("the primary group is not searched while the groups are searched")
+			}
+
+			return createSearchResultWithAttributeValues("admins"), nil
+		})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockSecondConn, nil),
+		mockSecondConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockSecondConn.EXPECT().
+			Search(NewSearchRequestMatcher(testPrimaryGroupFilter)).
+			DoAndReturn(func(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+				close(primaryGroupSearched)
+
+				return createSearchResultWithAttributes(&ldap.EntryAttribute{
+					Name:   "cn",
+					Values: []string{"Domain Users"},
+				}), nil
+			}),
+		mockSecondConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockSecondConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", concurrentSearchesTestProfile())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "Domain Users"}, details.Groups)
+}
+
+func TestShouldSearchPrimaryGroupSequentiallyWhenSecondConnectionFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, concurrentSearchesTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(nil, errors.New("connection refused")),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=CN=John,CN=Users,DC=example,DC=com)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher(testPrimaryGroupFilter)).
+			Return(createSearchResultWithAttributes(&ldap.EntryAttribute{
+				Name:   "cn",
+				Values: []string{"Domain Users"},
+			}), nil),
+	)
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", concurrentSearchesTestProfile())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "Domain Users"}, details.Groups)
+}
+
+func TestShouldCloseSecondConnectionWhenGroupsSearchFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, concurrentSearchesTestConfiguration)
+	mockSecondConn := NewMockLDAPConnection(ctrl)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=CN=John,CN=Users,DC=example,DC=com)")).
+		Return(nil, ldap.NewError(ldap.LDAPResultOperationsError, errors.New("operations error")))
+
+	closed := make(chan struct{})
+
+	// The second connection is closed once the primary group is searched even though the details are not returned.
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockSecondConn, nil),
+		mockSecondConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockSecondConn.EXPECT().
+			Search(NewSearchRequestMatcher(testPrimaryGroupFilter)).
+			Return(&ldap.SearchResult{}, nil),
+		mockSecondConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockSecondConn.EXPECT().
+			Close().
+			Do(func() { close(closed) }),
+	)
+
+	_, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", concurrentSearchesTestProfile())
+	assert.EqualError(t, err, "Unable to retrieve groups of user john. Cause: LDAP Result Code 1 \"Operations Error\": operations error")
+
+	<-closed
+}
+
+func TestShouldNotOpenSecondConnectionWithoutSecondarySearches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, concurrentSearchesTestConfiguration)
+	ldapClient.configuration.Implementation = schema.LDAPImplementationCustom
+
+	// The factory mock fails the test on any dial.
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(member=CN=John,CN=Users,DC=example,DC=com)")).
+		Return(createSearchResultWithAttributeValues("admins"), nil)
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", concurrentSearchesTestProfile())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins"}, details.Groups)
+}
+
+// latencyLDAPConnection is a LDAPConnection answering every search after a latency, like a remote LDAP server.
+type latencyLDAPConnection struct {
+	LDAPConnection
+
+	latency time.Duration
+}
+
+func (c *latencyLDAPConnection) Bind(_, _ string) error {
+	return nil
+}
+
+func (c *latencyLDAPConnection) Search(*ldap.SearchRequest) (*ldap.SearchResult, error) {
+	time.Sleep(c.latency)
+
+	return createSearchResultWithAttributes(&ldap.EntryAttribute{Name: "cn", Values: []string{"Domain Users"}}), nil
+}
+
+func (c *latencyLDAPConnection) Unbind() error {
+	return nil
+}
+
+func (c *latencyLDAPConnection) Close() {}
+
+// latencyLDAPConnectionFactory dials and binds a latencyLDAPConnection after a latency.
+type latencyLDAPConnectionFactory struct {
+	latency       time.Duration
+	searchLatency time.Duration
+}
+
+func (f *latencyLDAPConnectionFactory) DialURL(string, ldap.DialOpt) (LDAPConnection, error) {
+	time.Sleep(f.latency)
+
+	return &latencyLDAPConnection{latency: f.searchLatency}, nil
+}
+
+func (f *latencyLDAPConnectionFactory) DialURLWithProxy(context.Context, string, proxy.Dialer, *tls.Config) (LDAPConnection, error) {
+	return f.DialURL("", nil)
+}
+
+func benchmarkGetUserDetails(b *testing.B, concurrent bool) {
+	ctrl := gomock.NewController(b)
+	defer ctrl.Finish()
+
+	ldapClient, _, _ := newTestLDAPUserProvider(ctrl, concurrentSearchesTestConfiguration)
+	ldapClient.configuration.ConcurrentSearches = concurrent
+	// The second connection pays off when the searches take longer than connecting, which is resumed with TLS sessions.
+	ldapClient.connectionFactory = &latencyLDAPConnectionFactory{latency: time.Millisecond, searchLatency: 3 * time.Millisecond}
+
+	conn := &latencyLDAPConnection{latency: 3 * time.Millisecond}
+	profile := concurrentSearchesTestProfile()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ldapClient.getUserDetails(context.Background(), conn, "john", profile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetUserDetailsSequentialSearches(b *testing.B) {
+	benchmarkGetUserDetails(b, false)
+}
+
+func BenchmarkGetUserDetailsConcurrentSearches(b *testing.B) {
+	benchmarkGetUserDetails(b, true)
+}
@@ -1,9 +1,16 @@
 package authentication
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/net/proxy"
 )
 
 // ********************* CONNECTION *********************.
@@ -11,11 +18,19 @@ import (
 // LDAPConnection interface representing a connection to the ldap.
 type LDAPConnection interface {
 	Bind(username, password string) error
+	SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error)
+	UnauthenticatedBind(username string) error
+	ExternalBind() error
+	GSSAPIBind(client ldap.GSSAPIClient, servicePrincipal, authzid string) error
+	Unbind() error
 	Close()
 
 	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
 	Modify(modifyRequest *ldap.ModifyRequest) error
+	PasswordModify(passwordModifyRequest *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error)
 	StartTLS(config *tls.Config) error
+	WhoAmI(controls []ldap.Control) (*ldap.WhoAmIResult, error)
 }
 
 // LDAPConnectionImpl the production implementation of an ldap connection.
@@ -33,6 +48,33 @@ func (lc *LDAPConnectionImpl) Bind(username, password string) error {
 	return lc.conn.Bind(username, password)
 }
 
+// SimpleBind binds ldap connection with the simple bind request which may carry controls.
+func (lc *LDAPConnectionImpl) SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+	return lc.conn.SimpleBind(simpleBindRequest)
+}
+
+// UnauthenticatedBind performs an unauthenticated bind, an empty username results in an anonymous bind.
+func (lc *LDAPConnectionImpl) UnauthenticatedBind(username string) error {
+	return lc.conn.UnauthenticatedBind(username)
+}
+
+// ExternalBind performs a SASL EXTERNAL bind, the LDAP server derives the identity from the client certificate of the
+// TLS connection.
+func (lc *LDAPConnectionImpl) ExternalBind() error {
+	return lc.conn.ExternalBind()
+}
+
+// GSSAPIBind performs a SASL GSSAPI bind, the client authenticates with a Kerberos ticket for the service principal of
+// the LDAP server.
+func (lc *LDAPConnectionImpl) GSSAPIBind(client ldap.GSSAPIClient, servicePrincipal, authzid string) error {
+	return lc.conn.GSSAPIBind(client, servicePrincipal, authzid)
+}
+
+// Unbind sends an unbind request which ends the session and closes the ldap connection.
+func (lc *LDAPConnectionImpl) Unbind() error {
+	return lc.conn.Unbind()
+}
+
 // Close closes a ldap connection.
 func (lc *LDAPConnectionImpl) Close() {
 	lc.conn.Close()
@@ -43,21 +85,37 @@ func (lc *LDAPConnectionImpl) Search(searchRequest *ldap.SearchRequest) (*ldap.S
 	return lc.conn.Search(searchRequest)
 }
 
+// SearchWithPaging searches a ldap server using the paged results control, accumulating the entries of every page.
+func (lc *LDAPConnectionImpl) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	return lc.conn.SearchWithPaging(searchRequest, pagingSize)
+}
+
 // Modify modifies an ldap object.
 func (lc *LDAPConnectionImpl) Modify(modifyRequest *ldap.ModifyRequest) error {
 	return lc.conn.Modify(modifyRequest)
 }
 
+// PasswordModify modifies the password of an ldap user with the Password Modify extended operation.
+func (lc *LDAPConnectionImpl) PasswordModify(passwordModifyRequest *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	return lc.conn.PasswordModify(passwordModifyRequest)
+}
+
 // StartTLS requests the LDAP server upgrades to TLS encryption.
 func (lc *LDAPConnectionImpl) StartTLS(config *tls.Config) error {
 	return lc.conn.StartTLS(config)
 }
 
+// WhoAmI returns the authorization identity of the connection with the Who Am I? extended operation.
+func (lc *LDAPConnectionImpl) WhoAmI(controls []ldap.Control) (*ldap.WhoAmIResult, error) {
+	return lc.conn.WhoAmI(controls)
+}
+
 // ********************* FACTORY ***********************.
 
 // LDAPConnectionFactory an interface of factory of ldap connections.
 type LDAPConnectionFactory interface {
 	DialURL(addr string, opts ldap.DialOpt) (LDAPConnection, error)
+	DialURLWithProxy(ctx context.Context, addr string, dialer proxy.Dialer, tlsConfig *tls.Config) (LDAPConnection, error)
 }
 
 // LDAPConnectionFactoryImpl the production implementation of an ldap connection factory.
@@ -70,10 +128,109 @@ func NewLDAPConnectionFactoryImpl() *LDAPConnectionFactoryImpl {
 
 // DialURL creates a connection from an LDAP URL when successful.
 func (lcf *LDAPConnectionFactoryImpl) DialURL(addr string, opts ldap.DialOpt) (LDAPConnection, error) {
-	conn, err := ldap.DialURL(addr, opts)
+	conn, err := ldap.DialURL(ldapDialURL(addr), opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return NewLDAPConnectionImpl(conn), nil
 }
+
+// ldapDialURL adds the default port of the scheme to the URLs of an IPv6 literal address without a port. The LDAP
+// library otherwise joins the bracketed address with the default port as if the brackets were part of a host name.
+func ldapDialURL(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil || u.Port() != "" || !strings.Contains(u.Hostname(), ":") {
+		return addr
+	}
+
+	switch u.Scheme {
+	case "ldap":
+		u.Host = net.JoinHostPort(u.Hostname(), ldap.DefaultLdapPort)
+	case "ldaps":
+		u.Host = net.JoinHostPort(u.Hostname(), ldap.DefaultLdapsPort)
+	}
+
+	return u.String()
+}
+
+// DialURLWithProxy creates a connection from an LDAP URL through the proxy dialer when successful. The TLS of the ldaps
+// URLs is negotiated with the LDAP server over the tunnel of the proxy, as is StartTLS once connected.
+func (lcf *LDAPConnectionFactoryImpl) DialURLWithProxy(ctx context.Context, addr string, dialer proxy.Dialer, tlsConfig *tls.Config) (LDAPConnection, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, ldap.NewError(ldap.ErrorNetwork, err)
+	}
+
+	host, port, isTLS := u.Hostname(), u.Port(), false
+
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = ldap.DefaultLdapPort
+		}
+	case "ldaps":
+		if port == "" {
+			port = ldap.DefaultLdapsPort
+		}
+
+		isTLS = true
+	default:
+		return nil, ldap.NewError(ldap.ErrorNetwork, fmt.Errorf("Unknown scheme '%s'", u.Scheme))
+	}
+
+	var c net.Conn
+
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		c, err = contextDialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	} else {
+		c, err = dialer.Dial("tcp", net.JoinHostPort(host, port))
+	}
+
+	if err != nil {
+		return nil, ldap.NewError(ldap.ErrorNetwork, err)
+	}
+
+	if isTLS {
+		if c, err = tlsClientHandshake(ctx, c, host, tlsConfig); err != nil {
+			return nil, ldap.NewError(ldap.ErrorNetwork, err)
+		}
+	}
+
+	conn := ldap.NewConn(c, isTLS)
+	conn.Start()
+
+	return NewLDAPConnectionImpl(conn), nil
+}
+
+// tlsClientHandshake negotiates TLS over the connection the same way the ldaps URLs are dialed, the server name
+// defaults to the host of the URL. The connection is closed when the handshake fails.
+func tlsClientHandshake(ctx context.Context, c net.Conn, host string, tlsConfig *tls.Config) (net.Conn, error) {
+	config := &tls.Config{}
+	if tlsConfig != nil {
+		config = tlsConfig.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	// The handshake must not outlive the deadline of the context nor the timeout of the dial.
+	deadline := time.Now().Add(ldap.DefaultTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	tlsConn := tls.Client(c, config)
+
+	_ = c.SetDeadline(deadline)
+
+	if err := tlsConn.Handshake(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	_ = c.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
@@ -3,11 +3,13 @@
 package authentication
 
 import (
+	context "context"
 	tls "crypto/tls"
 	reflect "reflect"
 
 	ldap "github.com/go-ldap/ldap/v3"
 	gomock "github.com/golang/mock/gomock"
+	proxy "golang.org/x/net/proxy"
 )
 
 // MockLDAPConnection is a mock of LDAPConnection interface
@@ -47,6 +49,77 @@ func (mr *MockLDAPConnectionMockRecorder) Bind(username, password interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockLDAPConnection)(nil).Bind), username, password)
 }
 
+// SimpleBind mocks base method
+func (m *MockLDAPConnection) SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SimpleBind", simpleBindRequest)
+	ret0, _ := ret[0].(*ldap.SimpleBindResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SimpleBind indicates an expected call of SimpleBind
+func (mr *MockLDAPConnectionMockRecorder) SimpleBind(simpleBindRequest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SimpleBind", reflect.TypeOf((*MockLDAPConnection)(nil).SimpleBind), simpleBindRequest)
+}
+
+// UnauthenticatedBind mocks base method
+func (m *MockLDAPConnection) UnauthenticatedBind(username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnauthenticatedBind", username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnauthenticatedBind indicates an expected call of UnauthenticatedBind
+func (mr *MockLDAPConnectionMockRecorder) UnauthenticatedBind(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnauthenticatedBind", reflect.TypeOf((*MockLDAPConnection)(nil).UnauthenticatedBind), username)
+}
+
+// ExternalBind mocks base method
+func (m *MockLDAPConnection) ExternalBind() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExternalBind")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExternalBind indicates an expected call of ExternalBind
+func (mr *MockLDAPConnectionMockRecorder) ExternalBind() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExternalBind", reflect.TypeOf((*MockLDAPConnection)(nil).ExternalBind))
+}
+
+// GSSAPIBind mocks base method
+func (m *MockLDAPConnection) GSSAPIBind(arg0 ldap.GSSAPIClient, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GSSAPIBind", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GSSAPIBind indicates an expected call of GSSAPIBind
+func (mr *MockLDAPConnectionMockRecorder) GSSAPIBind(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GSSAPIBind", reflect.TypeOf((*MockLDAPConnection)(nil).GSSAPIBind), arg0, arg1, arg2)
+}
+
+// Unbind mocks base method
+func (m *MockLDAPConnection) Unbind() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unbind")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unbind indicates an expected call of Unbind
+func (mr *MockLDAPConnectionMockRecorder) Unbind() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockLDAPConnection)(nil).Unbind))
+}
+
 // Close mocks base method
 func (m *MockLDAPConnection) Close() {
 	m.ctrl.T.Helper()
@@ -74,6 +147,21 @@ func (mr *MockLDAPConnectionMockRecorder) Search(searchRequest interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockLDAPConnection)(nil).Search), searchRequest)
 }
 
+// SearchWithPaging mocks base method
+func (m *MockLDAPConnection) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchWithPaging", searchRequest, pagingSize)
+	ret0, _ := ret[0].(*ldap.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchWithPaging indicates an expected call of SearchWithPaging
+func (mr *MockLDAPConnectionMockRecorder) SearchWithPaging(searchRequest, pagingSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchWithPaging", reflect.TypeOf((*MockLDAPConnection)(nil).SearchWithPaging), searchRequest, pagingSize)
+}
+
 // Modify mocks base method
 func (m *MockLDAPConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
 	m.ctrl.T.Helper()
@@ -88,6 +176,21 @@ func (mr *MockLDAPConnectionMockRecorder) Modify(modifyRequest interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Modify", reflect.TypeOf((*MockLDAPConnection)(nil).Modify), modifyRequest)
 }
 
+// PasswordModify mocks base method
+func (m *MockLDAPConnection) PasswordModify(passwordModifyRequest *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PasswordModify", passwordModifyRequest)
+	ret0, _ := ret[0].(*ldap.PasswordModifyResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PasswordModify indicates an expected call of PasswordModify
+func (mr *MockLDAPConnectionMockRecorder) PasswordModify(passwordModifyRequest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PasswordModify", reflect.TypeOf((*MockLDAPConnection)(nil).PasswordModify), passwordModifyRequest)
+}
+
 // StartTLS mocks base method
 func (m *MockLDAPConnection) StartTLS(config *tls.Config) error {
 	m.ctrl.T.Helper()
@@ -102,6 +205,21 @@ func (mr *MockLDAPConnectionMockRecorder) StartTLS(config interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartTLS", reflect.TypeOf((*MockLDAPConnection)(nil).StartTLS), config)
 }
 
+// WhoAmI mocks base method
+func (m *MockLDAPConnection) WhoAmI(controls []ldap.Control) (*ldap.WhoAmIResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WhoAmI", controls)
+	ret0, _ := ret[0].(*ldap.WhoAmIResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WhoAmI indicates an expected call of WhoAmI
+func (mr *MockLDAPConnectionMockRecorder) WhoAmI(controls interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WhoAmI", reflect.TypeOf((*MockLDAPConnection)(nil).WhoAmI), controls)
+}
+
 // MockLDAPConnectionFactory is a mock of LDAPConnectionFactory interface
 type MockLDAPConnectionFactory struct {
 	ctrl     *gomock.Controller
@@ -139,3 +257,18 @@ func (mr *MockLDAPConnectionFactoryMockRecorder) DialURL(addr, opts interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DialURL", reflect.TypeOf((*MockLDAPConnectionFactory)(nil).DialURL), addr, opts)
 }
+
+// DialURLWithProxy mocks base method
+func (m *MockLDAPConnectionFactory) DialURLWithProxy(ctx context.Context, addr string, dialer proxy.Dialer, tlsConfig *tls.Config) (LDAPConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DialURLWithProxy", ctx, addr, dialer, tlsConfig)
+	ret0, _ := ret[0].(LDAPConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DialURLWithProxy indicates an expected call of DialURLWithProxy
+func (mr *MockLDAPConnectionFactoryMockRecorder) DialURLWithProxy(ctx, addr, dialer, tlsConfig interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DialURLWithProxy", reflect.TypeOf((*MockLDAPConnectionFactory)(nil).DialURLWithProxy), ctx, addr, dialer, tlsConfig)
+}
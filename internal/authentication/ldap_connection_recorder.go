@@ -0,0 +1,205 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/net/proxy"
+)
+
+// ldapModifyOperations are the names of the operations of the changes of the modify requests.
+var ldapModifyOperations = map[uint]string{
+	ldap.AddAttribute:       "add",
+	ldap.DeleteAttribute:    "delete",
+	ldap.ReplaceAttribute:   "replace",
+	ldap.IncrementAttribute: "increment",
+}
+
+// RecordingLDAPConnectionFactory is a connection factory recording in order the operations performed on the
+// connections of another factory, for instance to write golden tests of the requests sent to the LDAP server. The
+// passwords and the values of the modified attributes are never recorded.
+type RecordingLDAPConnectionFactory struct {
+	factory LDAPConnectionFactory
+
+	mutex      sync.Mutex
+	operations []string
+}
+
+// NewRecordingLDAPConnectionFactory creates a factory recording the operations performed on the connections of the
+// given factory.
+func NewRecordingLDAPConnectionFactory(factory LDAPConnectionFactory) *RecordingLDAPConnectionFactory {
+	return &RecordingLDAPConnectionFactory{factory: factory}
+}
+
+// Operations returns the operations recorded so far in the order they were performed.
+func (f *RecordingLDAPConnectionFactory) Operations() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]string(nil), f.operations...)
+}
+
+// Reset discards the operations recorded so far.
+func (f *RecordingLDAPConnectionFactory) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.operations = nil
+}
+
+func (f *RecordingLDAPConnectionFactory) record(operation string, err error) {
+	if err != nil {
+		operation += fmt.Sprintf(" error=%q", err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.operations = append(f.operations, operation)
+}
+
+// DialURL creates a recording connection from an LDAP URL when successful.
+func (f *RecordingLDAPConnectionFactory) DialURL(addr string, opts ldap.DialOpt) (LDAPConnection, error) {
+	conn, err := f.factory.DialURL(addr, opts)
+	f.record(fmt.Sprintf("DialURL url=%s", addr), err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingLDAPConnection{conn: conn, factory: f}, nil
+}
+
+// DialURLWithProxy creates a recording connection from an LDAP URL through the proxy dialer when successful.
+func (f *RecordingLDAPConnectionFactory) DialURLWithProxy(ctx context.Context, addr string, dialer proxy.Dialer, tlsConfig *tls.Config) (LDAPConnection, error) {
+	conn, err := f.factory.DialURLWithProxy(ctx, addr, dialer, tlsConfig)
+	f.record(fmt.Sprintf("DialURLWithProxy url=%s", addr), err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingLDAPConnection{conn: conn, factory: f}, nil
+}
+
+// recordingLDAPConnection records the operations performed on a connection in its factory.
+type recordingLDAPConnection struct {
+	conn    LDAPConnection
+	factory *RecordingLDAPConnectionFactory
+}
+
+func (c *recordingLDAPConnection) Bind(username, password string) error {
+	err := c.conn.Bind(username, password)
+	c.factory.record(fmt.Sprintf("Bind username=%s", username), err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+	result, err := c.conn.SimpleBind(simpleBindRequest)
+	c.factory.record(fmt.Sprintf("SimpleBind username=%s controls=%d", simpleBindRequest.Username, len(simpleBindRequest.Controls)), err)
+
+	return result, err
+}
+
+func (c *recordingLDAPConnection) UnauthenticatedBind(username string) error {
+	err := c.conn.UnauthenticatedBind(username)
+	c.factory.record(fmt.Sprintf("UnauthenticatedBind username=%s", username), err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) ExternalBind() error {
+	err := c.conn.ExternalBind()
+	c.factory.record("ExternalBind", err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) GSSAPIBind(client ldap.GSSAPIClient, servicePrincipal, authzid string) error {
+	err := c.conn.GSSAPIBind(client, servicePrincipal, authzid)
+	c.factory.record(fmt.Sprintf("GSSAPIBind service_principal=%s", servicePrincipal), err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) Unbind() error {
+	err := c.conn.Unbind()
+	c.factory.record("Unbind", err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) Close() {
+	c.conn.Close()
+	c.factory.record("Close", nil)
+}
+
+func (c *recordingLDAPConnection) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	sr, err := c.conn.Search(searchRequest)
+	c.factory.record("Search "+recordedSearchRequest(searchRequest, sr), err)
+
+	return sr, err
+}
+
+func (c *recordingLDAPConnection) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	sr, err := c.conn.SearchWithPaging(searchRequest, pagingSize)
+	c.factory.record(fmt.Sprintf("SearchWithPaging %s paging_size=%d", recordedSearchRequest(searchRequest, sr), pagingSize), err)
+
+	return sr, err
+}
+
+func (c *recordingLDAPConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
+	changes := make([]string, len(modifyRequest.Changes))
+
+	for i, change := range modifyRequest.Changes {
+		changes[i] = ldapModifyOperations[change.Operation] + ":" + change.Modification.Type
+	}
+
+	err := c.conn.Modify(modifyRequest)
+	c.factory.record(fmt.Sprintf("Modify dn=%s changes=%s", modifyRequest.DN, strings.Join(changes, ",")), err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) PasswordModify(passwordModifyRequest *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	result, err := c.conn.PasswordModify(passwordModifyRequest)
+	c.factory.record(fmt.Sprintf("PasswordModify user_identity=%s", passwordModifyRequest.UserIdentity), err)
+
+	return result, err
+}
+
+func (c *recordingLDAPConnection) StartTLS(config *tls.Config) error {
+	serverName := ""
+	if config != nil {
+		serverName = config.ServerName
+	}
+
+	err := c.conn.StartTLS(config)
+	c.factory.record(fmt.Sprintf("StartTLS server_name=%s", serverName), err)
+
+	return err
+}
+
+func (c *recordingLDAPConnection) WhoAmI(controls []ldap.Control) (*ldap.WhoAmIResult, error) {
+	result, err := c.conn.WhoAmI(controls)
+	c.factory.record("WhoAmI", err)
+
+	return result, err
+}
+
+// recordedSearchRequest formats the search request and the number of entries it returned.
+func recordedSearchRequest(searchRequest *ldap.SearchRequest, sr *ldap.SearchResult) string {
+	entries := 0
+	if sr != nil {
+		entries = len(sr.Entries)
+	}
+
+	return fmt.Sprintf("base_dn=%s scope=%s size_limit=%d filter=%s attributes=%s entries=%d",
+		searchRequest.BaseDN, ldap.ScopeMap[searchRequest.Scope], searchRequest.SizeLimit, searchRequest.Filter,
+		strings.Join(searchRequest.Attributes, ","), entries)
+}
@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldRecordLDAPOperationsInOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	recorder := NewRecordingLDAPConnectionFactory(mockFactory)
+	ldapClient.connectionFactory = recorder
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins", "dev"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"DialURL url=ldap://127.0.0.1:389",
+		"Bind username=cn=admin,dc=example,dc=com",
+		"Search base_dn=dc=example,dc=com scope=Whole Subtree size_limit=2 filter=(uid=john) attributes=displayname,mail,uid,entryDN,pwdChangedTime,pwdPolicySubentry,pwdReset entries=1",
+		"Search base_dn=dc=example,dc=com scope=Whole Subtree size_limit=0 filter=(member=uid=john,dc=example,dc=com) attributes=cn entries=1",
+		"Unbind",
+		"Close",
+	}, recorder.Operations())
+
+	recorder.Reset()
+
+	assert.Empty(t, recorder.Operations())
+}
+
+func TestShouldRecordFailedLDAPOperationsWithoutPasswords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	recorder := NewRecordingLDAPConnectionFactory(mockFactory)
+	ldapClient.connectionFactory = recorder
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("john")
+	require.Error(t, err)
+
+	operations := recorder.Operations()
+	require.Len(t, operations, 3)
+
+	assert.Equal(t, "DialURL url=ldap://127.0.0.1:389", operations[0])
+	assert.Contains(t, operations[1], "Bind username=cn=admin,dc=example,dc=com error=")
+	assert.Equal(t, "Close", operations[2])
+
+	for _, operation := range operations {
+		assert.NotContains(t, operation, "password")
+	}
+}
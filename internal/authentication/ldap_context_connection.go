@@ -0,0 +1,200 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapContextConnection is a LDAPConnection closed as soon as its context is done which aborts the pending
+// operations since the LDAP library does not accept a context.
+type ldapContextConnection struct {
+	LDAPConnection
+
+	ctx  context.Context
+	once sync.Once
+	done chan struct{}
+}
+
+// newLDAPContextConnection binds the connection to the context. The connection is returned as is when the context
+// can never be cancelled.
+func newLDAPContextConnection(ctx context.Context, conn LDAPConnection) LDAPConnection {
+	if ctx.Done() == nil {
+		return conn
+	}
+
+	c := &ldapContextConnection{
+		LDAPConnection: conn,
+		ctx:            ctx,
+		done:           make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-c.done:
+		}
+	}()
+
+	return c
+}
+
+// err returns the error of the context when it is the reason of the failure of an operation.
+func (c *ldapContextConnection) err(err error) error {
+	if err != nil && c.ctx.Err() != nil {
+		return c.ctx.Err()
+	}
+
+	return err
+}
+
+// Bind binds the connection unless the context is done.
+func (c *ldapContextConnection) Bind(username, password string) error {
+	return c.err(c.LDAPConnection.Bind(username, password))
+}
+
+// SimpleBind binds the connection with the simple bind request unless the context is done.
+func (c *ldapContextConnection) SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+	result, err := c.LDAPConnection.SimpleBind(simpleBindRequest)
+	return result, c.err(err)
+}
+
+// UnauthenticatedBind binds the connection anonymously unless the context is done.
+func (c *ldapContextConnection) UnauthenticatedBind(username string) error {
+	return c.err(c.LDAPConnection.UnauthenticatedBind(username))
+}
+
+// ExternalBind binds the connection with the SASL EXTERNAL mechanism unless the context is done.
+func (c *ldapContextConnection) ExternalBind() error {
+	return c.err(c.LDAPConnection.ExternalBind())
+}
+
+// GSSAPIBind binds the connection with the SASL GSSAPI mechanism unless the context is done.
+func (c *ldapContextConnection) GSSAPIBind(client ldap.GSSAPIClient, servicePrincipal, authzid string) error {
+	return c.err(c.LDAPConnection.GSSAPIBind(client, servicePrincipal, authzid))
+}
+
+// Unbind ends the session unless the context is done, the connection is already closed then.
+func (c *ldapContextConnection) Unbind() error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.err(c.LDAPConnection.Unbind())
+}
+
+// Close closes the connection and stops watching the context.
+func (c *ldapContextConnection) Close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.LDAPConnection.Close()
+	})
+}
+
+// Search searches the LDAP server unless the context is done.
+func (c *ldapContextConnection) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	sr, err := c.LDAPConnection.Search(searchRequest)
+	return sr, c.err(err)
+}
+
+// SearchWithPaging searches the LDAP server using the paging control unless the context is done.
+func (c *ldapContextConnection) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	sr, err := c.LDAPConnection.SearchWithPaging(searchRequest, pagingSize)
+	return sr, c.err(err)
+}
+
+// Modify modifies an LDAP object unless the context is done.
+func (c *ldapContextConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
+	return c.err(c.LDAPConnection.Modify(modifyRequest))
+}
+
+// PasswordModify modifies the password of an LDAP user unless the context is done.
+func (c *ldapContextConnection) PasswordModify(passwordModifyRequest *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	result, err := c.LDAPConnection.PasswordModify(passwordModifyRequest)
+	return result, c.err(err)
+}
+
+// StartTLS requests the LDAP server upgrades to TLS unless the context is done.
+func (c *ldapContextConnection) StartTLS(config *tls.Config) error {
+	return c.err(c.LDAPConnection.StartTLS(config))
+}
+
+// WhoAmI returns the authorization identity of the connection unless the context is done.
+func (c *ldapContextConnection) WhoAmI(controls []ldap.Control) (*ldap.WhoAmIResult, error) {
+	result, err := c.LDAPConnection.WhoAmI(controls)
+	return result, c.err(err)
+}
+
+// connectTimeoutKey is the key of the context value holding the connect timeout of a call.
+type connectTimeoutKey struct{}
+
+// withConnectTimeout returns a context overriding the timeout of the dial and the bind of the connections made with
+// it, the operations on the connection afterwards are not bounded by it.
+func withConnectTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, connectTimeoutKey{}, timeout)
+}
+
+// withConnectDeadline returns the context bounding the dial and the bind of a connection, which is the context with
+// the deadline of the connect timeout of the call when there is one.
+func withConnectDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := ctx.Value(connectTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return ctx, func() {}
+}
+
+// closeOnDone closes the connection when the context is done before the returned function is called, which aborts
+// the pending operation. The connection is no longer closed once the returned function returns.
+func closeOnDone(ctx context.Context, conn LDAPConnection) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// connectError returns the error of the context when it is the reason of the failure of the connection.
+func connectError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// dialOptsWithDeadline adds a dialer honoring the deadline to the dial options.
+func dialOptsWithDeadline(dialOpts ldap.DialOpt, deadline time.Time) ldap.DialOpt {
+	dialer := ldap.DialWithDialer(&net.Dialer{Timeout: ldap.DefaultTimeout, Deadline: deadline})
+
+	if dialOpts == nil {
+		return dialer
+	}
+
+	return func(dc *ldap.DialContext) {
+		dialOpts(dc)
+		dialer(dc)
+	}
+}
@@ -0,0 +1,108 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldExcludeDisabledUsersFromTheUsersFilters(t *testing.T) {
+	configuration := schema.LDAPAuthenticationBackendConfiguration{
+		Implementation:    schema.LDAPImplementationActiveDirectory,
+		UsernameAttribute: "sAMAccountName",
+		UsersFilter:       "({username_attribute}={input})",
+		BaseDN:            "dc=corp,dc=example",
+	}
+
+	ldapClient := NewLDAPUserProvider(configuration, nil)
+
+	assert.Equal(t, "(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))", ldapClient.configuration.UsersFilter)
+
+	configuration.ExcludeDisabledUsers = true
+
+	ldapClient = NewLDAPUserProvider(configuration, nil)
+
+	assert.Equal(t, "(&(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))(!(userAccountControl:1.2.840.113556.1.4.803:=2)))", ldapClient.configuration.UsersFilter)
+	assert.Equal(t, "(&(&(sAMAccountName=*)(objectCategory=person)(objectClass=user))(!(userAccountControl:1.2.840.113556.1.4.803:=2)))", ldapClient.listUsersFilter)
+}
+
+func TestShouldNotFindUsersLockedByThePasswordPolicy(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["pwdAccountLockedTime"] = []string{"000001010000Z"}
+
+	ldapClient := newIntegrationTestProvider(server)
+
+	_, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	ldapClient = newIntegrationTestProvider(server)
+	ldapClient.configuration.ExcludeDisabledUsers = true
+	ldapClient.parseDynamicConfiguration()
+
+	_, err = ldapClient.GetDetails("john")
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+
+	details, err := ldapClient.GetDetails("o'brien(*)")
+	require.NoError(t, err)
+	assert.Equal(t, "o'brien(*)", details.Username)
+	assert.Contains(t, server.Searches, "(&(&(uid=john)(objectClass=person))(!(pwdAccountLockedTime=*)))")
+}
+
+func TestShouldNotFindDisabledUsersNorContactsByEmail(t *testing.T) {
+	server := newTestLDAPServer(t,
+		&testLDAPServerEntry{DN: "dc=corp,dc=example", Attributes: map[string][]string{"objectClass": {"domain"}}},
+		&testLDAPServerEntry{DN: "cn=admin,dc=corp,dc=example", Attributes: map[string][]string{
+			"objectClass":  {"organizationalRole"},
+			"userPassword": {"password"},
+		}},
+		&testLDAPServerEntry{DN: "CN=John,CN=Users,DC=corp,DC=example", Attributes: map[string][]string{
+			"objectClass":        {"user"},
+			"objectCategory":     {"person"},
+			"sAMAccountName":     {"john"},
+			"mail":               {"john@corp.example"},
+			"userAccountControl": {"512"},
+		}},
+		&testLDAPServerEntry{DN: "CN=John Old,CN=Users,DC=corp,DC=example", Attributes: map[string][]string{
+			"objectClass":        {"user"},
+			"objectCategory":     {"person"},
+			"sAMAccountName":     {"john.old"},
+			"mail":               {"john@corp.example"},
+			"userAccountControl": {"514"},
+		}},
+		&testLDAPServerEntry{DN: "CN=John Contact,CN=Users,DC=corp,DC=example", Attributes: map[string][]string{
+			"objectClass":    {"contact"},
+			"objectCategory": {"person"},
+			"mail":           {"john@corp.example"},
+		}},
+	)
+
+	configuration := schema.LDAPAuthenticationBackendConfiguration{
+		Implementation:               schema.LDAPImplementationActiveDirectory,
+		URL:                          server.URL(),
+		User:                         "cn=admin,dc=corp,dc=example",
+		Password:                     "password",
+		UsernameAttribute:            "sAMAccountName",
+		MailAttribute:                "mail",
+		UsersFilter:                  "({username_attribute}={input})",
+		GroupsFilter:                 "(member={dn})",
+		GroupNameAttribute:           "cn",
+		BaseDN:                       "dc=corp,dc=example",
+		DisableUsersFilterConstraint: true,
+	}
+
+	_, err := NewLDAPUserProvider(configuration, nil).GetDetailsByEmail("john@corp.example")
+	assert.True(t, errors.Is(err, ErrMultipleUsersFound))
+
+	configuration.DisableUsersFilterConstraint = false
+	configuration.ExcludeDisabledUsers = true
+
+	details, err := NewLDAPUserProvider(configuration, nil).GetDetailsByEmail("john@corp.example")
+	require.NoError(t, err)
+	assert.Equal(t, "john", details.Username)
+	assert.Contains(t, server.Searches,
+		"(&(&(mail=john@corp.example)(objectCategory=person)(objectClass=user))(!(userAccountControl:1.2.840.113556.1.4.803:=2)))")
+}
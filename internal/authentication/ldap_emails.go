@@ -0,0 +1,58 @@
+package authentication
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// mailAttributeValues returns the mail addresses held by the attribute of the entry. The values of the proxyAddresses
+// attribute of Active Directory are parsed so only the SMTP addresses are returned, the primary one first.
+func mailAttributeValues(entry *ldap.Entry, attribute string) []string {
+	values := entry.GetAttributeValues(attribute)
+
+	if !strings.EqualFold(attribute, adAttributeProxyAddresses) {
+		return values
+	}
+
+	var primary, others []string
+
+	for _, value := range values {
+		switch {
+		case strings.HasPrefix(value, adProxyAddressPrimarySMTP):
+			primary = append(primary, value[len(adProxyAddressPrimarySMTP):])
+		case strings.HasPrefix(strings.ToLower(value), adProxyAddressPrefixSMTP):
+			others = append(others, value[len(adProxyAddressPrefixSMTP):])
+		}
+	}
+
+	return append(primary, others...)
+}
+
+// primaryEmailsFirst orders the emails with the primary ones first, the emails being used by Authelia in order so the
+// notifications such as the password reset emails are sent to the first one. The duplicates are dropped, the mail
+// addresses being compared case insensitively.
+func primaryEmailsFirst(primary []string, emails []string) []string {
+	if len(primary) == 0 {
+		return emails
+	}
+
+	ordered := make([]string, 0, len(primary)+len(emails))
+
+	for _, email := range append(append([]string{}, primary...), emails...) {
+		duplicate := false
+
+		for _, existing := range ordered {
+			if strings.EqualFold(existing, email) {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			ordered = append(ordered, email)
+		}
+	}
+
+	return ordered
+}
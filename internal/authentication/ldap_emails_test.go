@@ -0,0 +1,62 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldParseProxyAddresses(t *testing.T) {
+	entry := ldap.NewEntry("cn=john,dc=example,dc=com", map[string][]string{
+		"proxyAddresses": {"smtp:john.doe@example.com", "X500:/o=Example/cn=john", "SMTP:john@example.com", "sip:john@example.com"},
+		"mail":           {"smtp:john@example.com"},
+	})
+
+	assert.Equal(t, []string{"john@example.com", "john.doe@example.com"}, mailAttributeValues(entry, "proxyAddresses"))
+	assert.Equal(t, []string{"smtp:john@example.com"}, mailAttributeValues(entry, "mail"))
+	assert.Empty(t, mailAttributeValues(entry, "otherMailbox"))
+}
+
+func TestShouldOrderEmailsWithPrimaryFirst(t *testing.T) {
+	testCases := []struct {
+		name     string
+		primary  []string
+		emails   []string
+		expected []string
+	}{
+		{"NoPrimary", nil, []string{"a@example.com", "b@example.com"}, []string{"a@example.com", "b@example.com"}},
+		{"PrimaryAmongEmails", []string{"b@example.com"}, []string{"a@example.com", "B@example.com"}, []string{"b@example.com", "a@example.com"}},
+		{"PrimaryOnly", []string{"b@example.com"}, nil, []string{"b@example.com"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, primaryEmailsFirst(tc.primary, tc.emails))
+		})
+	}
+}
+
+func TestShouldReturnPrimaryEmailFirst(t *testing.T) {
+	server := newTestLDAPServer(t,
+		&testLDAPServerEntry{DN: "dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"domain"}}},
+		&testLDAPServerEntry{DN: "ou=users,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "cn=admin,dc=example,dc=com", Attributes: map[string][]string{"userPassword": {"password"}}},
+		&testLDAPServerEntry{DN: "uid=john,ou=users,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass":    {"person"},
+			"uid":            {"john"},
+			"mail":           {"john.doe@example.com", "john@example.com"},
+			"proxyAddresses": {"smtp:john.doe@example.com", "SMTP:john@example.com"},
+		}},
+	)
+
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.PrimaryMailAttribute = "proxyAddresses"
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"john@example.com", "john.doe@example.com"}, details.Emails)
+}
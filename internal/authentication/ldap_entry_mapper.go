@@ -0,0 +1,68 @@
+package authentication
+
+import (
+	"errors"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPEntryMapper maps the LDAP entry of a user to their details, for instance to derive the display name from the
+// first and last names or the groups from a custom attribute.
+//
+// MapLDAPEntry receives the raw entry of the user along with the details mapped from it by default according to the
+// configuration, which it may return modified or replace. The entry only holds the attributes requested by the
+// provider, the additional_attributes allowing to request others. The username of the returned details must not be
+// empty. The groups returned are merged with the groups searched, and the group display names and the MIME type of
+// the picture are computed afterwards so they are ignored. An error fails the search of the user.
+//
+// The signature of MapLDAPEntry is stable, it only changes in a major release. The fields of UserDetails may be added
+// to in minor releases, in which case the default details hold them already populated.
+type LDAPEntryMapper interface {
+	MapLDAPEntry(entry *ldap.Entry, details *UserDetails) (*UserDetails, error)
+}
+
+// SetEntryMapper sets the mapper of the LDAP entries of the users to their details. The entries are mapped according
+// to the configuration when the mapper is nil.
+func (p *LDAPUserProvider) SetEntryMapper(mapper LDAPEntryMapper) {
+	p.entryMapper = mapper
+}
+
+// mapEntry maps the entry of the user with the entry mapper, passing the details of the profile mapped by default, and
+// updates the profile with the details returned.
+func (p *LDAPUserProvider) mapEntry(entry *ldap.Entry, profile *ldapUserProfile) error {
+	details, err := p.entryMapper.MapLDAPEntry(entry, &UserDetails{
+		Username:              profile.Username,
+		DisplayName:           profile.DisplayName,
+		Emails:                profile.Emails,
+		StableID:              profile.StableID,
+		Extra:                 profile.Extra,
+		OperationalTimestamps: profile.OperationalTimestamps,
+		PasswordLastSet:       profile.PasswordLastSet,
+		PasswordExpires:       profile.PasswordExpires,
+		AccountExpires:        profile.AccountExpires,
+		Photo:                 profile.Photo,
+		MustChangePassword:    profile.MustChangePassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	if details == nil || details.Username == "" {
+		return errors.New("the username of the mapped details is empty")
+	}
+
+	profile.Username = details.Username
+	profile.DisplayName = details.DisplayName
+	profile.Emails = details.Emails
+	profile.Groups = details.Groups
+	profile.StableID = details.StableID
+	profile.Extra = details.Extra
+	profile.OperationalTimestamps = details.OperationalTimestamps
+	profile.PasswordLastSet = details.PasswordLastSet
+	profile.PasswordExpires = details.PasswordExpires
+	profile.AccountExpires = details.AccountExpires
+	profile.Photo = details.Photo
+	profile.MustChangePassword = details.MustChangePassword
+
+	return nil
+}
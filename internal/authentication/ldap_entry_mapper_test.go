@@ -0,0 +1,73 @@
+package authentication
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLDAPEntryMapper func(entry *ldap.Entry, details *UserDetails) (*UserDetails, error)
+
+func (m testLDAPEntryMapper) MapLDAPEntry(entry *ldap.Entry, details *UserDetails) (*UserDetails, error) {
+	return m(entry, details)
+}
+
+func TestShouldMapEntryWithEntryMapper(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["givenName"] = []string{"John"}
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["sn"] = []string{"Smith"}
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["roles"] = []string{"dev;ops"}
+
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.AdditionalAttributes = []string{"givenName", "sn", "roles"}
+	ldapClient.SetEntryMapper(testLDAPEntryMapper(func(entry *ldap.Entry, details *UserDetails) (*UserDetails, error) {
+		assert.Equal(t, "uid=john,ou=users,dc=example,dc=com", entry.DN)
+		assert.Equal(t, "John Doe", details.DisplayName)
+
+		details.DisplayName = entry.GetAttributeValue("givenName") + " " + entry.GetAttributeValue("sn")
+		details.Groups = strings.Split(entry.GetAttributeValue("roles"), ";")
+
+		return details, nil
+	}))
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+	assert.Equal(t, "John Smith", details.DisplayName)
+	assert.Equal(t, []string{"john@example.com"}, details.Emails)
+	assert.ElementsMatch(t, []string{"admins", "contractors", "dev", "ops"}, details.Groups)
+}
+
+func TestShouldFailWhenEntryMapperFails(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+
+	ldapClient.SetEntryMapper(testLDAPEntryMapper(func(entry *ldap.Entry, details *UserDetails) (*UserDetails, error) {
+		return nil, errors.New("unexpected entry")
+	}))
+
+	_, err := ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "Unable to map the entry of user john. Cause: unexpected entry")
+
+	ldapClient.SetEntryMapper(testLDAPEntryMapper(func(entry *ldap.Entry, details *UserDetails) (*UserDetails, error) {
+		details.Username = ""
+
+		return details, nil
+	}))
+
+	_, err = ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "Unable to map the entry of user john. Cause: the username of the mapped details is empty")
+
+	// The entries are mapped by default once the mapper is unset.
+	ldapClient.SetEntryMapper(nil)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "John Doe", details.DisplayName)
+}
@@ -0,0 +1,73 @@
+package authentication
+
+import (
+	"regexp"
+
+	"github.com/authelia/authelia/internal/logging"
+)
+
+// compileGroupsList compiles the regular expressions of the groups allowlist or denylist, each of them matching the
+// whole name of the groups. The invalid regular expressions are rejected by the validator, they are logged and ignored
+// for the configurations which have not been through it.
+func compileGroupsList(name string, patterns []string) []*regexp.Regexp {
+	list := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			logging.Logger().Errorf("Ignoring the LDAP %s pattern '%s' which is not a valid regular expression: %s", name, pattern, err)
+			continue
+		}
+
+		list = append(list, re)
+	}
+
+	return list
+}
+
+// matchesGroupsList returns true when one of the regular expressions matches the group.
+func matchesGroupsList(list []*regexp.Regexp, group string) bool {
+	for _, re := range list {
+		if re.MatchString(group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterGroups keeps the groups matching the groups allowlist, or all of them when it is empty, unless they match the
+// groups denylist which takes precedence. The display names of the groups dropped are dropped too.
+func (p *LDAPUserProvider) filterGroups(groups []string, groupDisplayNames map[string]string) ([]string, map[string]string) {
+	if len(p.groupsAllowlist) == 0 && len(p.groupsDenylist) == 0 {
+		return groups, groupDisplayNames
+	}
+
+	filtered := make([]string, 0, len(groups))
+
+	for _, group := range groups {
+		if len(p.groupsAllowlist) != 0 && !matchesGroupsList(p.groupsAllowlist, group) {
+			continue
+		}
+
+		if matchesGroupsList(p.groupsDenylist, group) {
+			continue
+		}
+
+		filtered = append(filtered, group)
+	}
+
+	if groupDisplayNames == nil {
+		return filtered, nil
+	}
+
+	filteredDisplayNames := make(map[string]string, len(filtered))
+
+	for _, group := range filtered {
+		if displayName, ok := groupDisplayNames[group]; ok {
+			filteredDisplayNames[group] = displayName
+		}
+	}
+
+	return filtered, filteredDisplayNames
+}
@@ -0,0 +1,78 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldFilterGroupsWithAllowlistAndDenylist(t *testing.T) {
+	groups := []string{"app-admins", "app-users", "app-legacy", "admins", "staff"}
+
+	testCases := []struct {
+		name      string
+		allowlist []string
+		denylist  []string
+		expected  []string
+	}{
+		{"None", nil, nil, groups},
+		{"Allowlist", []string{"app-.*", "admins"}, nil, []string{"app-admins", "app-users", "app-legacy", "admins"}},
+		{"Denylist", nil, []string{"app-legacy", "staff"}, []string{"app-admins", "app-users", "admins"}},
+		{"DenylistTakesPrecedence", []string{"app-.*"}, []string{".*-legacy", "app-admins"}, []string{"app-users"}},
+		{"WholeNameMatched", []string{"admins"}, nil, []string{"admins"}},
+		{"AllDenied", []string{"app-.*"}, []string{"app-.*"}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ldapClient := NewLDAPUserProvider(
+				schema.LDAPAuthenticationBackendConfiguration{
+					URL:             "ldap://127.0.0.1:389",
+					GroupsAllowlist: tc.allowlist,
+					GroupsDenylist:  tc.denylist,
+				},
+				nil)
+
+			filtered, displayNames := ldapClient.filterGroups(groups, nil)
+
+			assert.Equal(t, tc.expected, filtered)
+			assert.Nil(t, displayNames)
+		})
+	}
+}
+
+func TestShouldDropDisplayNamesOfFilteredGroups(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:            "ldap://127.0.0.1:389",
+			GroupsDenylist: []string{"staff"},
+		},
+		nil)
+
+	filtered, displayNames := ldapClient.filterGroups([]string{"admins", "staff"}, map[string]string{"admins": "Administrators", "staff": "All Staff"})
+
+	assert.Equal(t, []string{"admins"}, filtered)
+	assert.Equal(t, map[string]string{"admins": "Administrators"}, displayNames)
+}
+
+func TestShouldIgnoreInvalidGroupsListPatterns(t *testing.T) {
+	list := compileGroupsList("groups_denylist", []string{"admins", "app-(", "staff"})
+
+	require.Len(t, list, 2)
+	assert.True(t, matchesGroupsList(list, "staff"))
+	assert.False(t, matchesGroupsList(list, "app-("))
+}
+
+func TestShouldFilterGroupsOfUserDetails(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.groupsDenylist = compileGroupsList("groups_denylist", []string{"contract.*"})
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins"}, details.Groups)
+}
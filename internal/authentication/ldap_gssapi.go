@@ -0,0 +1,106 @@
+package authentication
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3/gssapi"
+	krbclient "github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// ldapKerberos holds the Kerberos client of the gssapi auth method. It is created from the keytab and the Kerberos
+// configuration at the first bind and kept afterwards so the binds reuse its tickets.
+type ldapKerberos struct {
+	lock   sync.Mutex
+	client *krbclient.Client
+}
+
+// kerberosClient returns the Kerberos client of the principal, creating it at the first call. The files are read again
+// at the next bind when they are unreadable, for instance while the keytab is being provisioned.
+func (p *LDAPUserProvider) kerberosClient() (*krbclient.Client, error) {
+	p.kerberos.lock.Lock()
+	defer p.kerberos.lock.Unlock()
+
+	if p.kerberos.client != nil {
+		return p.kerberos.client, nil
+	}
+
+	krb5conf, err := config.Load(p.configuration.KerberosConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the Kerberos configuration %s. Cause: %w", p.configuration.KerberosConfig, err)
+	}
+
+	kt, err := keytab.Load(p.configuration.KerberosKeytab)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the Kerberos keytab %s. Cause: %w", p.configuration.KerberosKeytab, err)
+	}
+
+	username, realm := kerberosPrincipal(p.configuration.KerberosPrincipal)
+
+	p.kerberos.client = krbclient.NewWithKeytab(username, realm, kt, krb5conf)
+
+	return p.kerberos.client, nil
+}
+
+// kerberosPrincipal splits the principal into its name and its realm. The realm is empty when the principal has none,
+// in which case the default realm of the Kerberos configuration is used.
+func kerberosPrincipal(principal string) (username string, realm string) {
+	if i := strings.LastIndex(principal, "@"); i != -1 {
+		return principal[:i], principal[i+1:]
+	}
+
+	return principal, ""
+}
+
+// kerberosServicePrincipal returns the service principal of the LDAP server at the address, ldap/ followed by the host
+// of the URL unless one is configured, for instance when the LDAP servers are reached through an alias.
+func (p *LDAPUserProvider) kerberosServicePrincipal(address string) string {
+	if p.configuration.KerberosServicePrincipal != "" {
+		return p.configuration.KerberosServicePrincipal
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "ldap/" + address
+	}
+
+	return "ldap/" + u.Hostname()
+}
+
+// gssapiClient is the GSSAPI client of a single bind. The Kerberos client logs in with the keytab before requesting the
+// ticket of the LDAP server when it has no ticket granting ticket yet, the LDAP library expecting a logged in client.
+type gssapiClient struct {
+	*gssapi.Client
+}
+
+// InitSecContext initiates the security context, logging in first when the context is initiated without a token.
+func (c *gssapiClient) InitSecContext(target string, token []byte) ([]byte, bool, error) {
+	if token == nil {
+		if err := c.Client.Client.AffirmLogin(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return c.Client.InitSecContext(target, token)
+}
+
+// gssapiBind binds the connection to the LDAP server at the address with the SASL GSSAPI mechanism, authenticating with
+// the Kerberos principal of the keytab.
+func (p *LDAPUserProvider) gssapiBind(conn LDAPConnection, address string) error {
+	client, err := p.kerberosClient()
+	if err != nil {
+		return err
+	}
+
+	c := &gssapiClient{Client: &gssapi.Client{Client: client}}
+
+	defer func() {
+		_ = c.DeleteSecContext()
+	}()
+
+	return conn.GSSAPIBind(c, p.kerberosServicePrincipal(address), "")
+}
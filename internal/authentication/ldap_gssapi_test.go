@@ -0,0 +1,138 @@
+package authentication
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// writeTestKerberosFiles writes a keytab holding the keys of the principal and a Kerberos configuration of its realm.
+func writeTestKerberosFiles(t *testing.T) (keytabPath string, krb5confPath string) {
+	dir := t.TempDir()
+
+	kt := keytab.New()
+	require.NoError(t, kt.AddEntry("authelia", "EXAMPLE.COM", "password", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96))
+
+	data, err := kt.Marshal()
+	require.NoError(t, err)
+
+	keytabPath = filepath.Join(dir, "authelia.keytab")
+	krb5confPath = filepath.Join(dir, "krb5.conf")
+
+	require.NoError(t, ioutil.WriteFile(keytabPath, data, 0600))
+	require.NoError(t, ioutil.WriteFile(krb5confPath, []byte("[libdefaults]\n  default_realm = EXAMPLE.COM\n\n"+
+		"[realms]\n  EXAMPLE.COM = {\n    kdc = kdc.example.com\n  }\n"), 0600))
+
+	return keytabPath, krb5confPath
+}
+
+func TestShouldBindWithGSSAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	keytabPath, krb5confPath := writeTestKerberosFiles(t)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://ldap.example.com",
+			AuthMethod:        schema.LDAPAuthMethodGSSAPI,
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			KerberosPrincipal: "authelia@EXAMPLE.COM",
+			KerberosKeytab:    keytabPath,
+			KerberosConfig:    krb5confPath,
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://ldap.example.com"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			GSSAPIBind(gomock.Any(), "ldap/ldap.example.com", "").
+			DoAndReturn(func(client ldap.GSSAPIClient, _, _ string) error {
+				c, ok := client.(*gssapiClient)
+				require.True(t, ok)
+
+				assert.Equal(t, "authelia", c.Client.Client.Credentials.UserName())
+				assert.Equal(t, "EXAMPLE.COM", c.Client.Client.Credentials.Domain())
+
+				return nil
+			}),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	conn, err := ldapClient.connectSearch(context.Background())
+	require.NoError(t, err)
+
+	conn.Close()
+}
+
+func TestShouldFailToBindWithGSSAPIWithoutKeytab(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	_, krb5confPath := writeTestKerberosFiles(t)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://ldap.example.com",
+			AuthMethod:        schema.LDAPAuthMethodGSSAPI,
+			KerberosPrincipal: "authelia",
+			KerberosKeytab:    filepath.Join(t.TempDir(), "missing.keytab"),
+			KerberosConfig:    krb5confPath,
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://ldap.example.com"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.connectSearch(context.Background())
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "Unable to load the Kerberos keytab")
+	assert.Nil(t, ldapClient.kerberos.client)
+}
+
+func TestShouldResolveKerberosPrincipals(t *testing.T) {
+	username, realm := kerberosPrincipal("authelia@EXAMPLE.COM")
+	assert.Equal(t, "authelia", username)
+	assert.Equal(t, "EXAMPLE.COM", realm)
+
+	username, realm = kerberosPrincipal("authelia")
+	assert.Equal(t, "authelia", username)
+	assert.Equal(t, "", realm)
+
+	ldapClient := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{URL: "ldaps://dc1.example.com:636"}, nil)
+
+	assert.Equal(t, "ldap/dc1.example.com", ldapClient.kerberosServicePrincipal("ldaps://dc1.example.com:636"))
+
+	ldapClient.configuration.KerberosServicePrincipal = "ldap/ldap.example.com@EXAMPLE.COM"
+
+	assert.Equal(t, "ldap/ldap.example.com@EXAMPLE.COM", ldapClient.kerberosServicePrincipal("ldaps://dc1.example.com:636"))
+}
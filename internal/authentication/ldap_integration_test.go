@@ -0,0 +1,146 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newIntegrationTestLDAPServer(t testing.TB) *testLDAPServer {
+	return newTestLDAPServer(t,
+		&testLDAPServerEntry{DN: "dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"domain"}}},
+		&testLDAPServerEntry{DN: "ou=users,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "cn=admin,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass":  {"organizationalRole"},
+			"userPassword": {"password"},
+		}},
+		&testLDAPServerEntry{DN: "uid=john,ou=users,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass":  {"person"},
+			"uid":          {"john"},
+			"mail":         {"john@example.com"},
+			"displayName":  {"John Doe"},
+			"userPassword": {"password"},
+		}},
+		// The username holds the special characters of the filters and the DN an escaped comma, which are escaped by
+		// the provider and unescaped by the LDAP library.
+		&testLDAPServerEntry{DN: "cn=Brien\\, O,ou=users,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass":  {"person"},
+			"uid":          {"o'brien(*)"},
+			"mail":         {"obrien@example.com"},
+			"displayName":  {"O. Brien"},
+			"userPassword": {"password"},
+		}},
+		&testLDAPServerEntry{DN: "cn=admins,ou=groups,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"cn":          {"admins"},
+			"member":      {"uid=john,ou=users,dc=example,dc=com"},
+		}},
+		&testLDAPServerEntry{DN: "cn=contractors,ou=groups,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"cn":          {"contractors"},
+			"member":      {"cn=Brien\\, O,ou=users,dc=example,dc=com", "uid=john,ou=users,dc=example,dc=com"},
+		}},
+	)
+}
+
+func newIntegrationTestProvider(server *testLDAPServer) *LDAPUserProvider {
+	return NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  server.URL(),
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayName",
+			UsersFilter:          "(&({username_attribute}={input})(objectClass=person))",
+			GroupsFilter:         "(&(member={dn})(objectClass=groupOfNames))",
+			GroupNameAttribute:   "cn",
+			AdditionalUsersDN:    "ou=users",
+			AdditionalGroupsDN:   "ou=groups",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil)
+}
+
+func TestShouldCheckPasswordAgainstTestLDAPServer(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+
+	require.NoError(t, ldapClient.StartupCheck())
+	require.NoError(t, ldapClient.Healthcheck())
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = ldapClient.CheckUserPassword("john", "wrong")
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	ok, err = ldapClient.CheckUserPassword("jane", "password")
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+}
+
+func TestShouldEscapeFiltersOverTheWire(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	ldapClient := newIntegrationTestProvider(server)
+
+	ok, err := ldapClient.CheckUserPassword("o'brien(*)", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	details, err := ldapClient.GetDetails("o'brien(*)")
+	require.NoError(t, err)
+
+	assert.Equal(t, "o'brien(*)", details.Username)
+	assert.Equal(t, "O. Brien", details.DisplayName)
+	assert.Equal(t, []string{"obrien@example.com"}, details.Emails)
+	assert.Equal(t, []string{"contractors"}, details.Groups)
+	assert.Contains(t, server.Searches, "(&(uid=o'brien\\28\\2a\\29)(objectClass=person))")
+	assert.Contains(t, server.Searches, "(&(member=cn=Brien\\5c, O,ou=users,dc=example,dc=com)(objectClass=groupOfNames))")
+
+	// A wildcard input never matches the other users.
+	_, err = ldapClient.GetDetails("*")
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+
+	details, err = ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"admins", "contractors"}, details.Groups)
+}
+
+func TestShouldUpdatePasswordAgainstTestLDAPServer(t *testing.T) {
+	testCases := []struct {
+		name              string
+		extendedOperation bool
+	}{
+		{"Modify", false},
+		{"PasswordModifyExtendedOperation", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newIntegrationTestLDAPServer(t)
+			ldapClient := newIntegrationTestProvider(server)
+			ldapClient.configuration.PasswordModifyExtendedOperation = tc.extendedOperation
+
+			require.NoError(t, ldapClient.UpdatePassword("john", "new-password"))
+
+			assert.Equal(t, []string{"new-password"}, server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["userPassword"])
+
+			ok, err := ldapClient.CheckUserPassword("john", "new-password")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = ldapClient.CheckUserPassword("john", "password")
+			assert.False(t, ok)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldAddDefaultPortToIPv6DialURLs(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		expected string
+	}{
+		{"ldap://[2001:db8::1]", "ldap://[2001:db8::1]:389"},
+		{"ldaps://[2001:db8::1]", "ldaps://[2001:db8::1]:636"},
+		{"ldaps://[2001:db8::1]:1636", "ldaps://[2001:db8::1]:1636"},
+		{"ldap://[fe80::1%25eth0]", "ldap://[fe80::1%25eth0]:389"},
+		{"ldap://127.0.0.1", "ldap://127.0.0.1"},
+		{"ldaps://ldap.example.com", "ldaps://ldap.example.com"},
+		{"ldapi:///var/run/slapd/ldapi", "ldapi:///var/run/slapd/ldapi"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ldapDialURL(tc.addr))
+		})
+	}
+}
+
+func TestShouldDeriveServerNameFromIPv6URL(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL: "ldaps://[2001:db8::1]:636",
+			StartTLSConfig: &schema.TLSConfig{
+				SkipVerify: true,
+			},
+		},
+		nil)
+
+	assert.Equal(t, "2001:db8::1", ldapClient.tlsConfig.ServerName)
+	assert.Equal(t, "2001:db8::1", ldapClient.startTLSConfig.ServerName)
+}
+
+// listenIPv6Loopback listens on the IPv6 loopback address, the test is skipped when IPv6 is unavailable.
+func listenIPv6Loopback(t *testing.T, tlsConfig *tls.Config) net.Listener {
+	var (
+		listener net.Listener
+		err      error
+	)
+
+	if tlsConfig == nil {
+		listener, err = net.Listen("tcp", "[::1]:0")
+	} else {
+		listener, err = tls.Listen("tcp", "[::1]:0", tlsConfig)
+	}
+
+	if err != nil {
+		t.Skipf("IPv6 is unavailable: %s", err)
+	}
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return listener
+}
+
+func TestShouldDialIPv6URLs(t *testing.T) {
+	certificate, err := tls.LoadX509KeyPair("../suites/common/ssl/cert.pem", "../suites/common/ssl/key.pem")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		scheme    string
+		tlsConfig *tls.Config
+	}{
+		{"LDAP", "ldap", nil},
+		{"LDAPS", "ldaps", &tls.Config{Certificates: []tls.Certificate{certificate}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := listenIPv6Loopback(t, tc.tlsConfig)
+			accepted := make(chan error, 1)
+
+			go func() {
+				conn, err := listener.Accept()
+				if err == nil {
+					if tlsConn, ok := conn.(*tls.Conn); ok {
+						err = tlsConn.Handshake()
+					}
+
+					_ = conn.Close()
+				}
+
+				accepted <- err
+			}()
+
+			ldapClient := NewLDAPUserProvider(
+				schema.LDAPAuthenticationBackendConfiguration{
+					URL: tc.scheme + "://" + listener.Addr().String(),
+					TLS: &schema.TLSConfig{
+						SkipVerify: true,
+					},
+				},
+				nil)
+
+			assert.Equal(t, "::1", ldapClient.tlsConfig.ServerName)
+
+			conn, err := ldapClient.dial(context.Background(), ldapClient.configuration.URL, ldapClient.tlsConfig)
+			require.NoError(t, err)
+
+			conn.Close()
+
+			select {
+			case err := <-accepted:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("the LDAP server did not accept the connection")
+			}
+		})
+	}
+}
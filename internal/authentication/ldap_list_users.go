@@ -0,0 +1,86 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ListUsers returns the usernames of all the users matched by the list users filter.
+func (p *LDAPUserProvider) ListUsers() ([]string, error) {
+	return p.ListUsersWithContext(context.Background())
+}
+
+// ListUsersWithContext returns the usernames of all the users matched by the list users filter, which defaults to the
+// users filter matching any input. The listing fails rather than returning a partial list when more users than the
+// maximum number of users are matched.
+func (p *LDAPUserProvider) ListUsersWithContext(ctx context.Context) (usernames []string, err error) {
+	ctx = newOperationContext(ctx, "list_users", "")
+
+	err = p.retry(ctx, func() error {
+		conn, err := p.connectSearch(ctx)
+		if err != nil {
+			return err
+		}
+		defer unbindAndClose(conn)
+
+		usernames, err = p.listUsers(ctx, conn)
+
+		return err
+	})
+
+	return usernames, err
+}
+
+func (p *LDAPUserProvider) listUsers(ctx context.Context, conn LDAPConnection) ([]string, error) {
+	operationLogger(ctx).Tracef("Computed list users filter is %s", p.listUsersFilter)
+
+	// Only the username attributes are requested to keep the results small. The size limit bounds the number of users
+	// returned by the LDAP server.
+	searchRequest := ldap.NewSearchRequest(
+		p.usersDN, p.usersScope, p.derefAliases,
+		p.configuration.MaxUsers, 0, false, p.listUsersFilter, p.usernameAttributes, nil,
+	)
+
+	start := time.Now()
+
+	sr, err := p.searchBaseDNs(ctx, conn, searchRequest, p.usersDNs, uint32(p.configuration.PageSize))
+	p.recordOperation(ldapMetricSearchUsers, start, err)
+
+	var ldapErr *ldap.Error
+
+	switch {
+	case errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultSizeLimitExceeded:
+		return nil, fmt.Errorf("%w matched by the filter %s, the maximum is %d", ErrTooManyUsers, p.listUsersFilter, p.configuration.MaxUsers)
+	case err != nil:
+		return nil, fmt.Errorf("Unable to list the users. Cause: %w", err)
+	case p.configuration.MaxUsers > 0 && len(sr.Entries) > p.configuration.MaxUsers:
+		return nil, fmt.Errorf("%w matched by the filter %s, the maximum is %d", ErrTooManyUsers, p.listUsersFilter, p.configuration.MaxUsers)
+	}
+
+	usernames := make([]string, 0, len(sr.Entries))
+
+	// The first populated attribute supplies the username, the attributes are ordered by preference.
+	for _, entry := range sr.Entries {
+		username := ""
+
+		for _, attribute := range p.usernameAttributes {
+			if username = entry.GetAttributeValue(attribute); username != "" {
+				break
+			}
+		}
+
+		if username == "" {
+			operationLogger(ctx).Debugf("No username found for user %s in the attributes %s, the user is not listed", entry.DN, strings.Join(p.usernameAttributes, ", "))
+			continue
+		}
+
+		usernames = append(usernames, username)
+	}
+
+	return usernames, nil
+}
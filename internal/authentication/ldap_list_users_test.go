@@ -0,0 +1,129 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldListUsersMatchingUsersFilterWithAnyInput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=*)")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{"uid": {"john"}}),
+					ldap.NewEntry("cn=nobody,dc=example,dc=com", map[string][]string{}),
+					ldap.NewEntry("uid=harry,dc=example,dc=com", map[string][]string{"uid": {"harry"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	usernames, err := ldapClient.ListUsers()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"john", "harry"}, usernames)
+}
+
+func TestShouldListUsersMatchingListUsersFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                        "ldap://127.0.0.1:389",
+			User:                       "cn=admin,dc=example,dc=com",
+			Password:                   "password",
+			UsernameAttribute:          "uid",
+			UsernameAttributeFallbacks: []string{"cn"},
+			UsersFilter:                "(uid={input})",
+			ListUsersFilter:            "(objectClass=person)",
+			MaxUsers:                   10,
+			BaseDN:                     "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestAttributesMatcher("uid", "cn")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{"uid": {"john"}, "cn": {"John Doe"}}),
+					ldap.NewEntry("cn=harry,dc=example,dc=com", map[string][]string{"cn": {"harry"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	usernames, err := ldapClient.ListUsers()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"john", "harry"}, usernames)
+}
+
+func TestShouldReturnErrorWhenListingTooManyUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.MaxUsers = 1
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("john"), ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	usernames, err := ldapClient.ListUsers()
+
+	assert.Nil(t, usernames)
+	assert.True(t, errors.Is(err, ErrTooManyUsers))
+	assert.EqualError(t, err, "too many users matched by the filter (uid=*), the maximum is 1")
+}
@@ -0,0 +1,75 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// searchMemberOfGroups reads the groups of the user from the DNs listed by the member of attribute of the user instead
+// of searching the groups DNs with the groups filter. Each group is read with a search of its own entry, which the LDAP
+// server resolves without scanning the groups DNs. The groups outside of the groups DNs, the groups which no longer
+// exist and the groups not matching the group name patterns are skipped.
+func (p *LDAPUserProvider) searchMemberOfGroups(ctx context.Context, conn LDAPConnection, inputUsername string, profile *ldapUserProfile) ([]*ldap.Entry, error) {
+	if p.configuration.MaxGroups > 0 && len(profile.MemberOf) > p.configuration.MaxGroups {
+		return nil, p.tooManyGroupsError(ctx, inputUsername, "")
+	}
+
+	filter := "(objectClass=*)"
+	if p.groupNamesFilter != "" {
+		filter = p.groupNamesFilter
+	}
+
+	start := time.Now()
+
+	entries := make([]*ldap.Entry, 0, len(profile.MemberOf))
+
+	for _, dn := range profile.MemberOf {
+		if !p.isGroupsDNDescendant(dn) {
+			operationLogger(ctx).Tracef("Skipping group %s of user %s which is outside of the groups DNs", dn, inputUsername)
+			continue
+		}
+
+		searchRequest := ldap.NewSearchRequest(
+			dn, ldap.ScopeBaseObject, p.derefAliases,
+			1, 0, false, filter, p.groupAttributes(), nil,
+		)
+
+		sr, err := p.search(ctx, conn, searchRequest)
+
+		var ldapErr *ldap.Error
+
+		switch {
+		case errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultNoSuchObject:
+			operationLogger(ctx).Debugf("Skipping group %s of user %s which doesn't exist", dn, inputUsername)
+		case err != nil:
+			p.recordOperation(ldapMetricSearchGroups, start, err)
+
+			return nil, fmt.Errorf("Unable to retrieve group %s of user %s. Cause: %w", dn, inputUsername, err)
+		default:
+			entries = append(entries, sr.Entries...)
+		}
+	}
+
+	if len(entries) == 0 {
+		p.recordOperationResult(ldapMetricSearchGroups, ldapMetricResultEmpty, start)
+	} else {
+		p.recordOperation(ldapMetricSearchGroups, start, nil)
+	}
+
+	return entries, nil
+}
+
+// isGroupsDNDescendant returns true when the DN is one of the groups DNs or one of their descendants.
+func (p *LDAPUserProvider) isGroupsDNDescendant(dn string) bool {
+	for _, groupsDN := range p.groupsDNs {
+		if isDNDescendantOf(dn, groupsDN) {
+			return true
+		}
+	}
+
+	return false
+}
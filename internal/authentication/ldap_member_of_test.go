@@ -0,0 +1,74 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newMemberOfTestLDAPServer(t *testing.T, memberOf ...string) *testLDAPServer {
+	return newTestLDAPServer(t,
+		&testLDAPServerEntry{DN: "dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"domain"}}},
+		&testLDAPServerEntry{DN: "ou=users,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"organizationalUnit"}}},
+		&testLDAPServerEntry{DN: "cn=admin,dc=example,dc=com", Attributes: map[string][]string{"userPassword": {"password"}}},
+		&testLDAPServerEntry{DN: "uid=john,ou=users,dc=example,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"john"},
+			"mail":        {"john@example.com"},
+			"memberOf":    memberOf,
+		}},
+		&testLDAPServerEntry{DN: "cn=admins,ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"admins"}}},
+		&testLDAPServerEntry{DN: "cn=dev,ou=groups,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"dev"}}},
+		&testLDAPServerEntry{DN: "cn=outside,dc=example,dc=com", Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"outside"}}},
+	)
+}
+
+func TestShouldReadGroupsFromMemberOfAttribute(t *testing.T) {
+	server := newMemberOfTestLDAPServer(t,
+		"cn=admins,ou=groups,dc=example,dc=com",
+		"CN=dev,OU=groups,DC=example,DC=com",
+		"cn=outside,dc=example,dc=com",
+		"cn=deleted,ou=groups,dc=example,dc=com",
+	)
+
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.GroupsSearchMode = schema.LDAPGroupsSearchModeMemberOf
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "dev"}, details.Groups)
+
+	// The groups are read from their own entries, the groups filter is never searched.
+	assert.NotContains(t, server.Searches, "(&(member=uid=john,ou=users,dc=example,dc=com)(objectClass=groupOfNames))")
+	assert.Contains(t, server.Searches, "(objectClass=*)")
+}
+
+func TestShouldReadGroupsFromMemberOfAttributeMatchingGroupNamePatterns(t *testing.T) {
+	server := newMemberOfTestLDAPServer(t, "cn=admins,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com")
+
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.GroupsSearchMode = schema.LDAPGroupsSearchModeMemberOf
+	ldapClient.groupNamesFilter = ldapGroupNamesFilter(ldapClient.groupNameAttributes, []string{"dev"})
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dev"}, details.Groups)
+}
+
+func TestShouldRejectMemberOfAttributeListingTooManyGroups(t *testing.T) {
+	server := newMemberOfTestLDAPServer(t, "cn=admins,ou=groups,dc=example,dc=com", "cn=dev,ou=groups,dc=example,dc=com")
+
+	ldapClient := newIntegrationTestProvider(server)
+	ldapClient.configuration.GroupsSearchMode = schema.LDAPGroupsSearchModeMemberOf
+	ldapClient.configuration.MaxGroups = 1
+
+	_, err := ldapClient.GetDetails("john")
+	assert.True(t, errors.Is(err, ErrTooManyGroups))
+}
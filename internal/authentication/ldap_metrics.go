@@ -0,0 +1,38 @@
+package authentication
+
+import "time"
+
+// MetricsRecorder records the outcome and the duration of the LDAP operations so they can be exposed by a metrics
+// registry, for instance as an ldap_operations_total counter and an ldap_operation_duration_seconds histogram labelled
+// by operation.
+type MetricsRecorder interface {
+	RecordLDAPOperation(operation string, result string, duration time.Duration)
+}
+
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) RecordLDAPOperation(_ string, _ string, _ time.Duration) {}
+
+// SetMetricsRecorder sets the recorder of the metrics of the LDAP operations.
+func (p *LDAPUserProvider) SetMetricsRecorder(recorder MetricsRecorder) {
+	if recorder == nil {
+		recorder = noopMetricsRecorder{}
+	}
+
+	p.metrics = recorder
+}
+
+// recordOperation records the outcome and the duration of an LDAP operation started at the given time.
+func (p *LDAPUserProvider) recordOperation(operation string, start time.Time, err error) {
+	result := ldapMetricResultSuccess
+	if err != nil {
+		result = ldapMetricResultFailure
+	}
+
+	p.recordOperationResult(operation, result, start)
+}
+
+// recordOperationResult records the given result and the duration of an LDAP operation started at the given time.
+func (p *LDAPUserProvider) recordOperationResult(operation string, result string, start time.Time) {
+	p.metrics.RecordLDAPOperation(operation, result, time.Since(start))
+}
@@ -0,0 +1,43 @@
+package authentication
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusMetricsRecorder records the metrics of the LDAP operations as Prometheus collectors.
+type PrometheusMetricsRecorder struct {
+	operations *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+}
+
+// NewPrometheusMetricsRecorder creates a recorder of the ldap_operations_total counter and the
+// ldap_operation_duration_seconds histogram and registers them with the given registerer.
+func NewPrometheusMetricsRecorder(registerer prometheus.Registerer) (*PrometheusMetricsRecorder, error) {
+	recorder := &PrometheusMetricsRecorder{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: ldapMetricOperationsTotal,
+			Help: "The number of LDAP operations by operation and result.",
+		}, []string{ldapMetricLabelOperation, ldapMetricLabelResult}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    ldapMetricOperationDuration,
+			Help:    "The duration of the LDAP operations in seconds by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{ldapMetricLabelOperation}),
+	}
+
+	for _, collector := range []prometheus.Collector{recorder.operations, recorder.durations} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return recorder, nil
+}
+
+// RecordLDAPOperation increments the counter of the operation and result and observes the duration of the operation.
+func (r *PrometheusMetricsRecorder) RecordLDAPOperation(operation string, result string, duration time.Duration) {
+	r.operations.WithLabelValues(operation, result).Inc()
+	r.durations.WithLabelValues(operation).Observe(duration.Seconds())
+}
@@ -0,0 +1,196 @@
+package authentication
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+type testMetricsRecorder struct {
+	operations []string
+}
+
+func (r *testMetricsRecorder) RecordLDAPOperation(operation string, result string, duration time.Duration) {
+	r.operations = append(r.operations, fmt.Sprintf("%s:%s", operation, result))
+}
+
+func TestShouldRecordLDAPOperationMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "uid",
+			UsersFilter:        "(uid={input})",
+			GroupsFilter:       "(member={dn})",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	recorder := &testMetricsRecorder{}
+	ldapClient.SetMetricsRecorder(recorder)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.LDAPResultBusy, fmt.Errorf("busy"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("john")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"bind:success", "search_user:success", "search_groups:failure"}, recorder.operations)
+}
+
+func TestShouldRecordAndWarnWhenUserHasNoGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "uid",
+			UsersFilter:        "(uid={input})",
+			GroupsFilter:       "(memberUid={dn})",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	recorder := &testMetricsRecorder{}
+	ldapClient.SetMetricsRecorder(recorder)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{"uid": {"john"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Empty(t, details.Groups)
+	assert.Equal(t, []string{"bind:success", "search_user:success", "search_groups:empty"}, recorder.operations)
+
+	var warnings []string
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+
+	assert.Contains(t, warnings, "User john doesn't belong to any group, the groups_filter is likely misconfigured")
+}
+
+func TestShouldRecordLDAPOperationMetricsWithPrometheus(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	recorder, err := NewPrometheusMetricsRecorder(registry)
+	require.NoError(t, err)
+
+	recorder.RecordLDAPOperation(ldapMetricBind, ldapMetricResultSuccess, 20*time.Millisecond)
+	recorder.RecordLDAPOperation(ldapMetricBind, ldapMetricResultFailure, 30*time.Millisecond)
+	recorder.RecordLDAPOperation(ldapMetricSearchUser, ldapMetricResultSuccess, 2*time.Second)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.operations.WithLabelValues("bind", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.operations.WithLabelValues("bind", "failure")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(recorder.operations.WithLabelValues("search_user", "success")))
+	assert.Equal(t, 3, testutil.CollectAndCount(recorder.operations))
+	assert.Equal(t, 2, testutil.CollectAndCount(recorder.durations))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "ldap_operation_duration_seconds" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			switch metric.GetLabel()[0].GetValue() {
+			case "bind":
+				assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+				assert.InDelta(t, 0.05, metric.GetHistogram().GetSampleSum(), 0.0001)
+			case "search_user":
+				assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+				assert.InDelta(t, 2, metric.GetHistogram().GetSampleSum(), 0.0001)
+			}
+		}
+	}
+
+	_, err = NewPrometheusMetricsRecorder(registry)
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package authentication
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+type operationIDContextKey struct{}
+
+type operationLoggerContextKey struct{}
+
+// ContextWithOperationID returns a copy of the context carrying the ID correlating the LDAP operations, for instance
+// the ID of the request which triggered them. An ID is generated for every operation otherwise.
+func ContextWithOperationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, operationIDContextKey{}, id)
+}
+
+// OperationIDFromContext returns the ID correlating the LDAP operations carried by the context, if any.
+func OperationIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(operationIDContextKey{}).(string)
+
+	return id, ok && id != ""
+}
+
+// newOperationContext returns a copy of the context carrying the ID and the logger of an operation of the provider,
+// the operations it calls in turn share the ID. The username is the input of the user and never a credential.
+func newOperationContext(ctx context.Context, operation, username string) context.Context {
+	id, ok := OperationIDFromContext(ctx)
+	if !ok {
+		id = utils.RandomString(16, utils.AlphaNumericCharacters)
+		ctx = ContextWithOperationID(ctx, id)
+	}
+
+	fields := logrus.Fields{
+		"operation_id": id,
+		"operation":    operation,
+	}
+
+	if username != "" {
+		fields["username"] = username
+	}
+
+	return context.WithValue(ctx, operationLoggerContextKey{}, logging.Logger().WithFields(fields))
+}
+
+func operationLogger(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(operationLoggerContextKey{}).(*logrus.Entry); ok {
+		return logger
+	}
+
+	return logrus.NewEntry(logging.Logger())
+}
+
+// logOperationStep logs the outcome of a step of an operation such as a bind or a search. Failures of the searches and
+// modifications are logged as warnings whereas failed binds are expected when users mistype their password.
+func logOperationStep(ctx context.Context, step string, start time.Time, fields logrus.Fields, err error) {
+	logger := operationLogger(ctx).WithFields(fields).WithFields(logrus.Fields{
+		"step":        step,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+
+	switch {
+	case err == nil:
+		logger.WithField("result", "success").Debug("LDAP operation step succeeded")
+	case step == ldapStepBind:
+		logger.WithField("result", "failure").WithError(err).Debug("LDAP operation step failed")
+	default:
+		logger.WithField("result", "failure").WithError(err).Warn("LDAP operation step failed")
+	}
+}
@@ -0,0 +1,182 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldLogCorrelatedLDAPOperationSteps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	level := logrus.GetLevel()
+
+	logrus.SetLevel(logrus.DebugLevel)
+
+	defer logrus.SetLevel(level)
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "admin-secret",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(uid={input})",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("admin-secret")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("user-secret")).
+			Return(errors.New("Invalid Credentials")),
+		mockConn.EXPECT().
+			Close().
+			Times(2),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil)
+
+	ctx := ContextWithOperationID(context.Background(), "request-1")
+
+	_, err := ldapClient.CheckUserPasswordWithContext(ctx, "john", "user-secret")
+	require.Error(t, err)
+
+	var steps []string
+
+	for _, entry := range hook.AllEntries() {
+		if _, ok := entry.Data["step"]; !ok {
+			continue
+		}
+
+		steps = append(steps, fmt.Sprintf("%s:%s", entry.Data["step"], entry.Data["result"]))
+
+		assert.Equal(t, logrus.DebugLevel, entry.Level)
+		assert.Equal(t, "request-1", entry.Data["operation_id"])
+		assert.Equal(t, "check_user_password", entry.Data["operation"])
+		assert.Equal(t, "john", entry.Data["username"])
+		assert.Contains(t, entry.Data, "duration_ms")
+
+		for _, value := range entry.Data {
+			assert.NotContains(t, fmt.Sprint(value), "secret")
+		}
+	}
+
+	assert.Equal(t, []string{"dial:success", "bind:success", "search:success", "dial:success", "bind:failure"}, steps)
+}
+
+func TestShouldLogFailedSearchAsWarning(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ctx := newOperationContext(context.Background(), "get_details", "john")
+	searchRequest := ldap.NewSearchRequest("dc=example,dc=com", ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, 0, false, "(uid=john)", nil, nil)
+
+	logOperationStep(ctx, ldapStepSearch, time.Now(), searchFields(searchRequest, nil), errors.New("Busy"))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "failure", entry.Data["result"])
+	assert.Equal(t, "dc=example,dc=com", entry.Data["base_dn"])
+	assert.Equal(t, "(uid=john)", entry.Data["filter"])
+	assert.NotEmpty(t, entry.Data["operation_id"])
+	assert.NotContains(t, entry.Data, "entry_count")
+}
+
+func TestShouldLogCircuitBreakerTransitionsWithOperationID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ldapClient, mockFactory, _ := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+	ldapClient.configuration.MaxAttempts = 1
+	ldapClient.circuitBreaker = newLDAPCircuitBreaker(1, time.Minute, &testClock{now: time.Unix(1600000000, 0)})
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused"))
+
+	_, err := ldapClient.GetDetailsWithContext(ContextWithOperationID(context.Background(), "request-1"), "john")
+	require.True(t, errors.Is(err, ErrConnectionFailed))
+
+	var opened *logrus.Entry
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			opened = entry
+		}
+	}
+
+	require.NotNil(t, opened)
+	assert.Contains(t, opened.Message, "The circuit breaker of the LDAP server ldap://127.0.0.1:389 is open")
+	assert.Equal(t, "request-1", opened.Data["operation_id"])
+	assert.Equal(t, "get_details", opened.Data["operation"])
+}
+
+func TestShouldShareOperationIDWithNestedOperations(t *testing.T) {
+	_, ok := OperationIDFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := newOperationContext(context.Background(), "check_user_password_and_get_details", "john")
+
+	id, ok := OperationIDFromContext(ctx)
+	require.True(t, ok)
+	assert.Len(t, id, 16)
+
+	// The operations called by an operation log with its generated ID.
+	nested := newOperationContext(ctx, "get_details", "john")
+
+	assert.Equal(t, id, operationLogger(nested).Data["operation_id"])
+
+	ctx = newOperationContext(ContextWithOperationID(context.Background(), "request-1"), "get_details", "john")
+
+	assert.Equal(t, "request-1", operationLogger(ctx).Data["operation_id"])
+}
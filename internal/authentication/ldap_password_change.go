@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ChangePassword changes the password of the given user after verifying their current password, as opposed to
+// UpdatePassword which resets the password without knowing the current one.
+func (p *LDAPUserProvider) ChangePassword(inputUsername string, oldPassword string, newPassword string) error {
+	return p.ChangePasswordWithContext(context.Background(), inputUsername, oldPassword, newPassword)
+}
+
+// ChangePasswordWithContext changes the password of the given user after verifying their current password by binding
+// as the user, the LDAP operations are aborted when the context is cancelled or its deadline is exceeded. The new
+// password is set with the connection bound as the user when password_change_as_user is enabled, otherwise with the
+// user updating the passwords. The error wraps the error matching the reason the bind failed when the current
+// password is rejected, ErrPasswordPolicyViolation when the new password is rejected by the password policy and
+// ErrPasswordChangeNotPermitted when the user is not permitted to change the password.
+func (p *LDAPUserProvider) ChangePasswordWithContext(ctx context.Context, inputUsername string, oldPassword string, newPassword string) error {
+	ctx = newOperationContext(ctx, "change_password", inputUsername)
+
+	if err := checkPasswordPolicy(p.configuration.PasswordPolicy, inputUsername, newPassword); err != nil {
+		return err
+	}
+
+	if p.cache != nil {
+		defer p.cache.Delete(p.cacheKey(inputUsername))
+	}
+
+	user, password := p.passwordModifyCredentials()
+
+	conn, err := p.connectAdmin(ctx, user, password)
+	if err != nil {
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+	defer unbindAndClose(conn)
+
+	profile, err := p.getUserProfile(ctx, conn, inputUsername)
+	if err != nil {
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+
+	userConn, _, err := p.connectUser(ctx, inputUsername, profile, oldPassword)
+	if err != nil {
+		return err
+	}
+	defer unbindAndClose(userConn)
+
+	start := time.Now()
+
+	if p.configuration.PasswordChangeAsUser {
+		err = p.changeOwnPassword(userConn, profile.DN, oldPassword, newPassword)
+	} else {
+		err = p.modifyPassword(conn, profile.DN, newPassword)
+	}
+
+	logOperationStep(ctx, ldapStepModify, start, logrus.Fields{"dn": profile.DN}, err)
+	p.recordOperation(ldapMetricModifyPassword, start, err)
+
+	return passwordModifyError(inputUsername, err)
+}
+
+// changeOwnPassword changes the password of the user with the connection bound as the user. The old password is sent
+// along the new one whenever the LDAP server needs it to let the users change their own password.
+func (p *LDAPUserProvider) changeOwnPassword(conn LDAPConnection, userDN string, oldPassword string, newPassword string) error {
+	switch {
+	case p.configuration.Implementation == schema.LDAPImplementationActiveDirectory:
+		// Active Directory only lets the users change their own password by deleting the old value and adding the new
+		// one in a single modify request, replacing the value requires the reset password right.
+		attribute, oldValues := p.passwordEncoder.Encode(oldPassword)
+		_, newValues := p.passwordEncoder.Encode(newPassword)
+
+		modifyRequest := ldap.NewModifyRequest(userDN, nil)
+		modifyRequest.Delete(attribute, oldValues)
+		modifyRequest.Add(attribute, newValues)
+
+		return conn.Modify(modifyRequest)
+	case p.configuration.PasswordModifyExtendedOperation:
+		_, err := conn.PasswordModify(ldap.NewPasswordModifyRequest(userDN, oldPassword, newPassword))
+
+		return err
+	default:
+		return p.modifyPassword(conn, userDN, newPassword)
+	}
+}
@@ -0,0 +1,239 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// expectPasswordChangeBinds expects the admin connection searching the user followed by the connection verifying the
+// old password of the user.
+func expectPasswordChangeBinds(mockFactory *MockLDAPConnectionFactory, mockAdminConn, mockUserConn *MockLDAPConnection, userBindErr error) []*gomock.Call {
+	return []*gomock.Call{
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockAdminConn, nil),
+		mockAdminConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Return(userCheckTestSearchResult(), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockUserConn, nil),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("old-password")).
+			Return(userBindErr),
+	}
+}
+
+func TestShouldChangePasswordWithAdminConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	modifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", nil)
+	modifyRequest.Replace("userPassword", []string{"new-password"})
+
+	calls := expectPasswordChangeBinds(mockFactory, mockAdminConn, mockUserConn, nil)
+	calls = append(calls,
+		mockAdminConn.EXPECT().
+			Modify(modifyRequest).
+			Return(nil),
+		mockUserConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockUserConn.EXPECT().
+			Close(),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Close(),
+	)
+
+	gomock.InOrder(calls...)
+
+	require.NoError(t, ldapClient.ChangePassword("john", "old-password", "new-password"))
+}
+
+func TestShouldChangePasswordWithUserConnection(t *testing.T) {
+	activeDirectoryModifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", nil)
+	activeDirectoryModifyRequest.Delete("unicodePwd", []string{adPasswordValue("old-password")})
+	activeDirectoryModifyRequest.Add("unicodePwd", []string{adPasswordValue("new-password")})
+
+	testCases := []struct {
+		name              string
+		implementation    string
+		extendedOperation bool
+		expect            func(mockUserConn *MockLDAPConnection) *gomock.Call
+	}{
+		{
+			name:           "ActiveDirectory",
+			implementation: schema.LDAPImplementationActiveDirectory,
+			expect: func(mockUserConn *MockLDAPConnection) *gomock.Call {
+				return mockUserConn.EXPECT().
+					Modify(activeDirectoryModifyRequest).
+					Return(nil)
+			},
+		},
+		{
+			name:              "PasswordModifyExtendedOperation",
+			implementation:    schema.LDAPImplementationCustom,
+			extendedOperation: true,
+			expect: func(mockUserConn *MockLDAPConnection) *gomock.Call {
+				return mockUserConn.EXPECT().
+					PasswordModify(ldap.NewPasswordModifyRequest("uid=john,dc=example,dc=com", "old-password", "new-password")).
+					Return(&ldap.PasswordModifyResult{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+			mockUserConn := NewMockLDAPConnection(ctrl)
+
+			ldapClient.configuration.Implementation = tc.implementation
+			ldapClient.passwordEncoder = newLDAPPasswordEncoder(tc.implementation)
+			ldapClient.configuration.PasswordModifyExtendedOperation = tc.extendedOperation
+			ldapClient.configuration.PasswordChangeAsUser = true
+
+			calls := expectPasswordChangeBinds(mockFactory, mockAdminConn, mockUserConn, nil)
+			calls = append(calls,
+				tc.expect(mockUserConn),
+				mockUserConn.EXPECT().
+					Unbind().
+					Return(nil),
+				mockUserConn.EXPECT().
+					Close(),
+				mockAdminConn.EXPECT().
+					Unbind().
+					Return(nil),
+				mockAdminConn.EXPECT().
+					Close(),
+			)
+
+			gomock.InOrder(calls...)
+
+			require.NoError(t, ldapClient.ChangePassword("john", "old-password", "new-password"))
+		})
+	}
+}
+
+func TestShouldNotChangePasswordWithInvalidOldPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient.configuration.Implementation = schema.LDAPImplementationActiveDirectory
+
+	bindErr := ldap.NewError(ldap.LDAPResultInvalidCredentials,
+		errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 52e, v4563"))
+
+	calls := expectPasswordChangeBinds(mockFactory, mockAdminConn, mockUserConn, bindErr)
+	calls = append(calls,
+		mockUserConn.EXPECT().
+			Close(),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Close(),
+	)
+
+	gomock.InOrder(calls...)
+
+	err := ldapClient.ChangePassword("john", "old-password", "new-password")
+
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestShouldMapPasswordChangeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"ConstraintViolation", ldap.NewError(ldap.LDAPResultConstraintViolation, errors.New("password in history")), ErrPasswordInHistory},
+		{"InsufficientAccessRights", ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("no write access")), ErrPasswordChangeNotPermitted},
+		{"Other", ldap.NewError(ldap.LDAPResultUnwillingToPerform, errors.New("unwilling to perform")), ErrPasswordUpdateFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+			mockUserConn := NewMockLDAPConnection(ctrl)
+
+			ldapClient.configuration.PasswordChangeAsUser = true
+
+			calls := expectPasswordChangeBinds(mockFactory, mockAdminConn, mockUserConn, nil)
+			calls = append(calls,
+				mockUserConn.EXPECT().
+					Modify(gomock.Any()).
+					Return(tc.err),
+				mockUserConn.EXPECT().
+					Unbind().
+					Return(nil),
+				mockUserConn.EXPECT().
+					Close(),
+				mockAdminConn.EXPECT().
+					Unbind().
+					Return(nil),
+				mockAdminConn.EXPECT().
+					Close(),
+			)
+
+			gomock.InOrder(calls...)
+
+			err := ldapClient.ChangePassword("john", "old-password", "new-password")
+
+			assert.True(t, errors.Is(err, tc.expected), "expected %v, got %v", tc.expected, err)
+		})
+	}
+}
+
+func TestShouldReportPasswordPolicyViolationsFromDiagnosticMessage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected error
+	}{
+		{"OpenLDAPQuality", "Password fails quality checking policy", ErrPasswordPolicyViolation},
+		{"OpenLDAPTooShort", "Password is too short for policy", ErrPasswordTooShort},
+		{"OpenLDAPHistory", "Password is in history of old passwords", ErrPasswordInHistory},
+		{"OpenLDAPTooYoung", "Password is too young to change", ErrPasswordTooYoung},
+		{"SambaTooShort", "0000052D: Constraint violation - check_password_restrictions: the password is too short. It should be equal or longer than 7 characters!", ErrPasswordTooShort},
+		{"SambaHistory", "0000052D: Constraint violation - check_password_restrictions: the password was already used (in history)!", ErrPasswordInHistory},
+		{"389DSMinimumAge", "password within minimum age", ErrPasswordTooYoung},
+		{"ActiveDirectory", "0000052D: AtrErr: DSID-03191083, #1:\n\t0: 0000052D: DSID-03191083, problem 1005 (CONSTRAINT_ATT_TYPE), data 0, Att 9005a (unicodePwd)", ErrPasswordPolicyViolation},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := passwordModifyError("john", ldap.NewError(ldap.LDAPResultConstraintViolation, errors.New(tc.message)))
+
+			assert.True(t, errors.Is(err, tc.expected), "expected %v, got %v", tc.expected, err)
+			assert.True(t, errors.Is(err, ErrPasswordPolicyViolation))
+			assert.EqualError(t, err, fmt.Sprintf("%s of user john. Cause: LDAP Result Code 19 \"Constraint Violation\": %s", tc.expected, tc.message))
+		})
+	}
+}
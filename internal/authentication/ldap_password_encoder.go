@@ -0,0 +1,56 @@
+package authentication
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ldapPasswordEncoder encodes the passwords set with the modify requests in the attribute and the encoding expected by
+// the implementation of the LDAP server.
+type ldapPasswordEncoder interface {
+	// Encode returns the attribute holding the password and the values encoding it.
+	Encode(password string) (attribute string, values []string)
+}
+
+// ldapPasswordEncoders are the password encoders of the implementations, the other implementations use the generic one.
+var ldapPasswordEncoders = map[string]ldapPasswordEncoder{
+	schema.LDAPImplementationActiveDirectory: ldapActiveDirectoryPasswordEncoder{},
+}
+
+// newLDAPPasswordEncoder returns the password encoder of the implementation.
+func newLDAPPasswordEncoder(implementation string) ldapPasswordEncoder {
+	if encoder, ok := ldapPasswordEncoders[implementation]; ok {
+		return encoder
+	}
+
+	return ldapGenericPasswordEncoder{}
+}
+
+// ldapGenericPasswordEncoder sets the password in the userPassword attribute as is, the LDAP server hashing it.
+type ldapGenericPasswordEncoder struct{}
+
+// Encode returns the userPassword attribute and the password.
+func (ldapGenericPasswordEncoder) Encode(password string) (attribute string, values []string) {
+	return "userPassword", []string{password}
+}
+
+// ldapActiveDirectoryPasswordEncoder sets the password in the unicodePwd attribute of Active Directory.
+type ldapActiveDirectoryPasswordEncoder struct{}
+
+// Encode returns the unicodePwd attribute and the password encoded for it.
+func (ldapActiveDirectoryPasswordEncoder) Encode(password string) (attribute string, values []string) {
+	return "unicodePwd", []string{adPasswordValue(password)}
+}
+
+// adPasswordValue encodes the password as a value of the unicodePwd attribute of Active Directory, the password
+// enclosed in quotes and encoded in UTF-16.
+// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-adts/6e803168-f140-4d23-b2d3-c3a8ab5917d2
+func adPasswordValue(password string) string {
+	utf16 := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	pwdEncoded, _ := utf16.NewEncoder().String(fmt.Sprintf("\"%s\"", password))
+
+	return pwdEncoded
+}
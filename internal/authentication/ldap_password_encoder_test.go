@@ -0,0 +1,55 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldEncodePasswordForImplementation(t *testing.T) {
+	testCases := []struct {
+		implementation string
+		attribute      string
+		values         []string
+	}{
+		{schema.LDAPImplementationCustom, "userPassword", []string{"pass"}},
+		{"", "userPassword", []string{"pass"}},
+		{schema.LDAPImplementationActiveDirectory, "unicodePwd", []string{"\"\x00p\x00a\x00s\x00s\x00\"\x00"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.implementation, func(t *testing.T) {
+			attribute, values := newLDAPPasswordEncoder(tc.implementation).Encode("pass")
+
+			assert.Equal(t, tc.attribute, attribute)
+			assert.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestShouldEncodeActiveDirectoryPasswordAsQuotedUTF16LittleEndian(t *testing.T) {
+	// The password is enclosed in quotes and encoded in UTF-16 little endian without byte order mark, the characters
+	// outside of the basic multilingual plane being encoded as surrogate pairs.
+	testCases := []struct {
+		name     string
+		password string
+		expected []byte
+	}{
+		{"Empty", "", []byte{0x22, 0x00, 0x22, 0x00}},
+		{"ASCII", "newPassword", []byte{
+			0x22, 0x00, 0x6e, 0x00, 0x65, 0x00, 0x77, 0x00, 0x50, 0x00, 0x61, 0x00, 0x73, 0x00, 0x73, 0x00, 0x77, 0x00,
+			0x6f, 0x00, 0x72, 0x00, 0x64, 0x00, 0x22, 0x00,
+		}},
+		{"Quote", "a\"b", []byte{0x22, 0x00, 0x61, 0x00, 0x22, 0x00, 0x62, 0x00, 0x22, 0x00}},
+		{"Accent", "é€", []byte{0x22, 0x00, 0xe9, 0x00, 0xac, 0x20, 0x22, 0x00}},
+		{"SurrogatePair", "😀", []byte{0x22, 0x00, 0x3d, 0xd8, 0x00, 0xde, 0x22, 0x00}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, []byte(adPasswordValue(tc.password)))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapControlTypeAssertion is the OID of the Assertion control, see https://tools.ietf.org/html/rfc4528.
+const ldapControlTypeAssertion = "1.3.6.1.1.12"
+
+// newLDAPAssertionControl returns the critical Assertion control making the LDAP server perform the operation only
+// when the entry matches the filter, the operation fails with the assertionFailed result otherwise. The value of the
+// control is the BER encoding of the filter.
+func newLDAPAssertionControl(filter string) (ldap.Control, error) {
+	packet, err := ldap.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return ldap.NewControlString(ldapControlTypeAssertion, true, string(packet.Bytes())), nil
+}
+
+// passwordModifyControls returns the controls of the modify requests updating the passwords.
+func (p *LDAPUserProvider) passwordModifyControls() []ldap.Control {
+	if p.passwordModifyAssertion == nil {
+		return nil
+	}
+
+	return []ldap.Control{p.passwordModifyAssertion}
+}
+
+// preReadPasswordModify logs the values of the pre-read attributes of the user right before their password is updated
+// to keep the prior state of the user for auditing. The LDAP library drops the response controls of the modify requests
+// so the Pre-Read control of RFC 4527 can't be used, the attributes are read on the same connection instead. The
+// assertion of the modify request guarantees the user still matches the asserted filter when the values were read.
+func (p *LDAPUserProvider) preReadPasswordModify(ctx context.Context, conn LDAPConnection, userDN string) error {
+	if len(p.configuration.PasswordModifyPreReadAttributes) == 0 {
+		return nil
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		userDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		1, 0, false, "(objectClass=*)", p.configuration.PasswordModifyPreReadAttributes, nil,
+	)
+
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return fmt.Errorf("Unable to read the prior state of user %s. Cause: %s", userDN, err)
+	}
+
+	if len(sr.Entries) != 1 {
+		return fmt.Errorf("Unable to read the prior state of user %s. Cause: %d entries found", userDN, len(sr.Entries))
+	}
+
+	values := make([]string, 0, len(p.configuration.PasswordModifyPreReadAttributes))
+
+	for _, attribute := range p.configuration.PasswordModifyPreReadAttributes {
+		values = append(values, fmt.Sprintf("%s=%v", attribute, sr.Entries[0].GetEqualFoldAttributeValues(attribute)))
+	}
+
+	operationLogger(ctx).Infof("Updating the password of user %s whose prior state is %s", userDN, strings.Join(values, " "))
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+const testPasswordModifyAssertionFilter = "(!(pwdAccountLockedTime=*))"
+
+func TestShouldEncodeAssertionControl(t *testing.T) {
+	control, err := newLDAPAssertionControl(testPasswordModifyAssertionFilter)
+	require.NoError(t, err)
+
+	packet, err := ldap.CompileFilter(testPasswordModifyAssertionFilter)
+	require.NoError(t, err)
+
+	assert.Equal(t, ldap.NewControlString("1.3.6.1.1.12", true, string(packet.Bytes())), control)
+
+	_, err = newLDAPAssertionControl("(uid=john")
+	assert.Error(t, err)
+
+	ldapClient := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{
+		BaseDN:                        "dc=example,dc=com",
+		PasswordModifyAssertionFilter: testPasswordModifyAssertionFilter,
+	}, nil)
+
+	assert.Equal(t, control, ldapClient.passwordModifyAssertion)
+	assert.Equal(t, []ldap.Control{control}, ldapClient.passwordModifyControls())
+}
+
+func TestShouldUpdatePasswordWithAssertionAndPreRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.PasswordModifyPreReadAttributes = []string{"pwdChangedTime", "memberOf"}
+
+	control, err := newLDAPAssertionControl(testPasswordModifyAssertionFilter)
+	require.NoError(t, err)
+
+	ldapClient.passwordModifyAssertion = control
+
+	modifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", []ldap.Control{control})
+	modifyRequest.Replace("userPassword", []string{"new-password"})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("uid=john,dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{
+						"pwdChangedTime": {"20201010101010Z"},
+						"memberOf":       {"cn=admins,dc=example,dc=com", "cn=users,dc=example,dc=com"},
+					}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Modify(modifyRequest).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	require.NoError(t, ldapClient.UpdatePassword("john", "new-password"))
+
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+
+	assert.Contains(t, messages, "Updating the password of user uid=john,dc=example,dc=com whose prior state is "+
+		"pwdChangedTime=[20201010101010Z] memberOf=[cn=admins,dc=example,dc=com cn=users,dc=example,dc=com]")
+}
+
+func TestShouldReturnErrorWhenPasswordModifyAssertionFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.passwordModifyAssertion, _ = newLDAPAssertionControl(testPasswordModifyAssertionFilter)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Modify(gomock.Any()).
+			Return(ldap.NewError(ldap.LDAPResultAssertionFailed, errors.New("assertion failed"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	err := ldapClient.UpdatePassword("john", "new-password")
+
+	assert.True(t, errors.Is(err, ErrPasswordAssertionFailed))
+}
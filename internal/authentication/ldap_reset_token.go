@@ -0,0 +1,126 @@
+package authentication
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// VerifyResetToken verifies the password reset token of the user against the one held by the reset token attribute,
+// see VerifyResetTokenWithContext.
+func (p *LDAPUserProvider) VerifyResetToken(inputUsername string, token string) error {
+	return p.VerifyResetTokenWithContext(context.Background(), inputUsername, token)
+}
+
+// VerifyResetTokenWithContext verifies the password reset token of the user against the one held by the reset token
+// attribute, for instance set by an external identity management system. The error wraps ErrResetTokenNotConfigured
+// when no reset token attribute is configured, ErrResetTokenMismatch when the token doesn't match and
+// ErrResetTokenExpired when the time held by the reset token expiry attribute has passed.
+func (p *LDAPUserProvider) VerifyResetTokenWithContext(ctx context.Context, inputUsername string, token string) error {
+	ctx = newOperationContext(ctx, "verify_reset_token", inputUsername)
+
+	if p.configuration.ResetTokenAttribute == "" {
+		return ErrResetTokenNotConfigured
+	}
+
+	// An empty token would match a user without a reset token on a directory returning the attribute without values.
+	if token == "" {
+		return fmt.Errorf("%w for user %s. Cause: the token is empty", ErrResetTokenMismatch, inputUsername)
+	}
+
+	var entry *ldap.Entry
+
+	err := p.retry(ctx, func() error {
+		conn, err := p.connectSearch(ctx)
+		if err != nil {
+			return err
+		}
+		defer unbindAndClose(conn)
+
+		profile, err := p.getUserProfile(ctx, conn, inputUsername)
+		if err != nil {
+			return err
+		}
+
+		entry, err = p.searchResetToken(ctx, conn, profile.DN)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.verifyResetToken(entry, inputUsername, token, time.Now())
+}
+
+// searchResetToken reads the reset token attributes of the entry of the user. They are only requested here so the
+// reset tokens are never part of the profile of the user.
+func (p *LDAPUserProvider) searchResetToken(ctx context.Context, conn LDAPConnection, dn string) (*ldap.Entry, error) {
+	attributes := []string{p.configuration.ResetTokenAttribute}
+
+	if p.configuration.ResetTokenExpiryAttribute != "" {
+		attributes = append(attributes, p.configuration.ResetTokenExpiryAttribute)
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		dn, ldap.ScopeBaseObject, p.derefAliases,
+		1, 0, false, "(objectClass=*)", attributes, nil,
+	)
+
+	start := time.Now()
+
+	sr, err := p.search(ctx, conn, searchRequest)
+	p.recordOperation(ldapMetricSearchUser, start, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the reset token of user %s. Cause: %w", dn, err)
+	}
+
+	if len(sr.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return sr.Entries[0], nil
+}
+
+// verifyResetToken compares the token with the values of the reset token attribute of the entry in constant time and
+// checks the expiry of the token. The expiry is either of the generalized time syntax or an Active Directory FILETIME,
+// the token being considered expired when the expiry is missing or can't be parsed.
+func (p *LDAPUserProvider) verifyResetToken(entry *ldap.Entry, inputUsername string, token string, now time.Time) error {
+	matched := 0
+
+	for _, value := range entry.GetEqualFoldRawAttributeValues(p.configuration.ResetTokenAttribute) {
+		matched |= subtle.ConstantTimeCompare(value, []byte(token))
+	}
+
+	if matched != 1 {
+		return fmt.Errorf("%w for user %s", ErrResetTokenMismatch, inputUsername)
+	}
+
+	if p.configuration.ResetTokenExpiryAttribute == "" {
+		return nil
+	}
+
+	// The token is considered expired when its expiry is missing, the expiry being set along with the token.
+	value := entry.GetEqualFoldAttributeValue(p.configuration.ResetTokenExpiryAttribute)
+	if value == "" {
+		return fmt.Errorf("%w for user %s. Cause: the expiry is missing", ErrResetTokenExpired, inputUsername)
+	}
+
+	expiry, err := ldapGeneralizedTime(value)
+	if err != nil {
+		if expiry, err = adFileTime(value); err != nil {
+			return fmt.Errorf("%w for user %s. Cause: the expiry %s can't be parsed", ErrResetTokenExpired, inputUsername, value)
+		}
+	}
+
+	// The expiry is zero for the FILETIME values meaning never.
+	if !expiry.IsZero() && !now.Before(expiry) {
+		return fmt.Errorf("%w for user %s since %s", ErrResetTokenExpired, inputUsername, expiry.Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldVerifyResetTokenAgainstTestLDAPServer(t *testing.T) {
+	server := newIntegrationTestLDAPServer(t)
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["resetToken"] = []string{"s3cr3t-t0ken"}
+	server.Entry("uid=john,ou=users,dc=example,dc=com").Attributes["resetTokenExpiry"] = []string{
+		time.Now().Add(time.Hour).UTC().Format("20060102150405Z"),
+	}
+
+	ldapClient := newIntegrationTestProvider(server)
+
+	assert.True(t, errors.Is(ldapClient.VerifyResetToken("john", "s3cr3t-t0ken"), ErrResetTokenNotConfigured))
+
+	ldapClient.configuration.ResetTokenAttribute = "resetToken"
+	ldapClient.configuration.ResetTokenExpiryAttribute = "resetTokenExpiry"
+
+	require.NoError(t, ldapClient.VerifyResetToken("john", "s3cr3t-t0ken"))
+
+	assert.True(t, errors.Is(ldapClient.VerifyResetToken("john", "wrong"), ErrResetTokenMismatch))
+	assert.True(t, errors.Is(ldapClient.VerifyResetToken("john", ""), ErrResetTokenMismatch))
+	assert.True(t, errors.Is(ldapClient.VerifyResetToken("jane", "s3cr3t-t0ken"), ErrUserNotFound))
+
+	// The user without a reset token never matches.
+	assert.True(t, errors.Is(ldapClient.VerifyResetToken("o'brien(*)", "s3cr3t-t0ken"), ErrResetTokenMismatch))
+}
+
+func TestShouldCheckResetTokenExpiry(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		expiry   []string
+		expected error
+	}{
+		{"GeneralizedTimeNotExpired", []string{"20210101130000Z"}, nil},
+		{"GeneralizedTimeExpired", []string{"20210101120000Z"}, ErrResetTokenExpired},
+		{"FileTimeNotExpired", []string{"132539796000000000"}, nil},
+		{"FileTimeExpired", []string{"132539724000000000"}, ErrResetTokenExpired},
+		{"FileTimeNever", []string{"9223372036854775807"}, nil},
+		{"Missing", nil, ErrResetTokenExpired},
+		{"Invalid", []string{"tomorrow"}, ErrResetTokenExpired},
+	}
+
+	ldapClient := newIntegrationTestProvider(newIntegrationTestLDAPServer(t))
+	ldapClient.configuration.ResetTokenAttribute = "resetToken"
+	ldapClient.configuration.ResetTokenExpiryAttribute = "resetTokenExpiry"
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := ldap.NewEntry("uid=john,ou=users,dc=example,dc=com", map[string][]string{
+				"resetToken":       {"s3cr3t-t0ken"},
+				"resetTokenExpiry": tc.expiry,
+			})
+
+			err := ldapClient.verifyResetToken(entry, "john", "s3cr3t-t0ken", now)
+
+			if tc.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tc.expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapErrConnectionClosed is the message of the network error returned by the LDAP library for the operations sent
+// over a connection closed by the LDAP server.
+const ldapErrConnectionClosed = "ldap: connection closed"
+
+// retry runs an operation which only reads the directory until it succeeds, fails with an error which is not
+// transient or the maximum number of attempts is reached. The delay between two attempts doubles after each attempt
+// and is jittered so the requests failing together do not reconnect all at once. The connections not attempted because
+// the circuit breaker of the LDAP server is open are not retried.
+// The operations binding with the credentials of the users must never be retried to avoid locking their account.
+// The operation is run again at once the first time the LDAP server closes the connection, which directories do with
+// the connections they consider idle, without counting as an attempt.
+func (p *LDAPUserProvider) retry(ctx context.Context, operation func() error) (err error) {
+	backoff := p.retryBackoff
+	reconnected := false
+
+	for attempt := 1; ; attempt++ {
+		err = operation()
+		if !reconnected && isClosedConnectionLDAPError(err) {
+			operationLogger(ctx).Debugf("Reconnecting to the LDAP server which closed the connection. Cause: %s", err)
+
+			reconnected = true
+			attempt--
+
+			continue
+		}
+
+		if err == nil || attempt >= p.configuration.MaxAttempts || !isTransientLDAPError(err) {
+			return err
+		}
+
+		delay := ldapJitter(backoff)
+
+		operationLogger(ctx).Debugf("Retrying the LDAP operation in %s after a transient error (attempt %d of %d). Cause: %s",
+			delay, attempt, p.configuration.MaxAttempts, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		backoff *= 2
+	}
+}
+
+// isTransientLDAPError returns true when the error is the result of the LDAP server being unreachable, busy or
+// unavailable, which a new attempt may succeed past.
+func isTransientLDAPError(err error) bool {
+	if isCircuitOpenError(err) {
+		return false
+	}
+
+	if errors.Is(err, ErrConnectionFailed) {
+		return true
+	}
+
+	var ldapErr *ldap.Error
+
+	if !errors.As(err, &ldapErr) {
+		return false
+	}
+
+	switch ldapErr.ResultCode {
+	case ldap.LDAPResultBusy, ldap.LDAPResultUnavailable, ldap.ErrorNetwork:
+		return true
+	default:
+		return false
+	}
+}
+
+// isClosedConnectionLDAPError returns true when the error is the result of the LDAP server closing the connection.
+func isClosedConnectionLDAPError(err error) bool {
+	var ldapErr *ldap.Error
+
+	if !errors.As(err, &ldapErr) {
+		return false
+	}
+
+	return ldapErr.ResultCode == ldap.ErrorNetwork && ldapErr.Err != nil && ldapErr.Err.Error() == ldapErrConnectionClosed
+}
@@ -0,0 +1,222 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// retryTestConfiguration is the configuration of the provider of the retry tests.
+var retryTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	URL:                "ldap://127.0.0.1:389",
+	User:               "cn=admin,dc=example,dc=com",
+	Password:           "password",
+	UsernameAttribute:  "uid",
+	UsersFilter:        "(uid={input})",
+	GroupsFilter:       "(member={dn})",
+	GroupNameAttribute: "cn",
+	BaseDN:             "dc=example,dc=com",
+	MaxAttempts:        2,
+}
+
+func TestShouldRetryGetDetailsOnTransientError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.LDAPResultBusy, errors.New("busy"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins"}, details.Groups)
+}
+
+func TestShouldStopRetryingAfterMaxAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, _ := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	_, err := ldapClient.GetDetails("john")
+
+	assert.EqualError(t, err, "unable to connect to the authentication backend ldap://127.0.0.1:389. Cause: connection refused")
+}
+
+func TestShouldNotRetryOnInvalidCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("john")
+
+	assert.Error(t, err)
+}
+
+func TestShouldReconnectOnceWhenConnectionIsClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	// A single attempt is allowed so only the reconnection on the closed connection runs the operation again.
+	ldapClient.configuration.MaxAttempts = 1
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection closed"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+}
+
+func TestShouldReconnectOnlyOnceWhenConnectionIsClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, retryTestConfiguration)
+
+	ldapClient.configuration.MaxAttempts = 1
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil).
+		Times(2)
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil).
+		Times(2)
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection closed"))).
+		Times(2)
+	mockConn.EXPECT().
+		Close().
+		Times(2)
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+
+	_, err := ldapClient.GetDetails("john")
+
+	assert.EqualError(t, err, "Cannot find user DN of user john. Cause: LDAP Result Code 200 \"Network Error\": ldap: connection closed")
+}
+
+func TestShouldDetectTransientLDAPErrors(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{fmt.Errorf("%w ldap://127.0.0.1:389. Cause: timeout", ErrConnectionFailed), true},
+		{ldap.NewError(ldap.LDAPResultBusy, errors.New("busy")), true},
+		{ldap.NewError(ldap.LDAPResultUnavailable, errors.New("unavailable")), true},
+		{fmt.Errorf("Cannot find user DN of user john. Cause: %w", ldap.NewError(ldap.ErrorNetwork, errors.New("reset"))), true},
+		{ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials")), false},
+		{ErrUserNotFound, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, isTransientLDAPError(tc.err))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// probeUsersSearch runs a search of the users filter matching any input, which should return at least a user, and
+// tells apart the reasons it may return no entry. A bind user lacking the permission to read the users gets no entry
+// rather than an error from most LDAP servers, which makes every login fail with user not found as if the users filter
+// was wrong. The error describes the likely cause, nil when the users filter matches a user.
+func (p *LDAPUserProvider) probeUsersSearch(conn LDAPConnection, usersDNEntries int) error {
+	// The users DN itself is hidden from the bind user lacking the permission to read it.
+	if usersDNEntries == 0 {
+		return fmt.Errorf("The users DN %s is not readable by user %s, the user likely lacks the permission to read it",
+			p.usersDN, p.configuration.User)
+	}
+
+	found, err := p.probeSearch(conn, p.usersScope, p.listUsersFilter)
+	if err != nil || found {
+		return err
+	}
+
+	// The children of the users DN are searched regardless of the users filter to know whether the users filter
+	// matches none of the entries or none of them is readable.
+	found, err = p.probeSearch(conn, ldap.ScopeSingleLevel, "(objectClass=*)")
+	if err != nil {
+		return err
+	}
+
+	if found {
+		return fmt.Errorf("The users filter %s matches none of the entries readable under the users DN %s, the users filter is likely wrong",
+			p.listUsersFilter, p.usersDN)
+	}
+
+	return fmt.Errorf("No entry under the users DN %s is readable by user %s, either the users DN is empty or the user lacks the permission to read its entries",
+		p.usersDN, p.configuration.User)
+}
+
+// probeSearch returns true when the filter matches at least an entry under the users DN within the scope.
+func (p *LDAPUserProvider) probeSearch(conn LDAPConnection, scope int, filter string) (bool, error) {
+	// The 1.1 attribute requests no attributes at all, only the presence of an entry matters.
+	searchRequest := ldap.NewSearchRequest(
+		p.usersDN, scope, p.derefAliases,
+		1, 0, false, filter, []string{"1.1"}, nil,
+	)
+
+	sr, err := conn.Search(searchRequest)
+
+	var ldapErr *ldap.Error
+
+	switch {
+	case errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultSizeLimitExceeded:
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("Unable to search the users DN %s with the filter %s. Cause: %s", p.usersDN, filter, err)
+	}
+
+	return len(sr.Entries) != 0, nil
+}
@@ -0,0 +1,128 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldProbeUsersSearch(t *testing.T) {
+	userEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: "uid=john,dc=example,dc=com"}}}
+
+	testCases := []struct {
+		name           string
+		usersDNEntries int
+		usersResult    *ldap.SearchResult
+		usersErr       error
+		childrenResult *ldap.SearchResult
+		expected       string
+	}{
+		{
+			name:     "UsersDNNotReadable",
+			expected: "The users DN dc=example,dc=com is not readable by user cn=admin,dc=example,dc=com, the user likely lacks the permission to read it",
+		},
+		{
+			name:           "UserFound",
+			usersDNEntries: 1,
+			usersResult:    userEntry,
+		},
+		{
+			name:           "SizeLimitExceeded",
+			usersDNEntries: 1,
+			usersErr:       ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")),
+		},
+		{
+			name:           "UsersFilterMismatch",
+			usersDNEntries: 1,
+			usersResult:    &ldap.SearchResult{},
+			childrenResult: userEntry,
+			expected:       "The users filter (uid=*) matches none of the entries readable under the users DN dc=example,dc=com, the users filter is likely wrong",
+		},
+		{
+			name:           "EntriesNotReadable",
+			usersDNEntries: 1,
+			usersResult:    &ldap.SearchResult{},
+			childrenResult: &ldap.SearchResult{},
+			expected:       "No entry under the users DN dc=example,dc=com is readable by user cn=admin,dc=example,dc=com, either the users DN is empty or the user lacks the permission to read its entries",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+			if tc.usersResult != nil || tc.usersErr != nil {
+				mockConn.EXPECT().
+					Search(NewSearchRequestMatcher("(uid=*)")).
+					Return(tc.usersResult, tc.usersErr)
+			}
+
+			if tc.childrenResult != nil {
+				mockConn.EXPECT().
+					Search(NewSearchRequestMatcher("(objectClass=*)")).
+					Return(tc.childrenResult, nil)
+			}
+
+			err := ldapClient.probeUsersSearch(mockConn, tc.usersDNEntries)
+
+			if tc.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestShouldWarnOnStartupWhenUsersAreNotReadable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	baseEntry := &ldap.SearchResult{Entries: []*ldap.Entry{{DN: "dc=example,dc=com"}}}
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+			Return(baseEntry, nil).
+			Times(2),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=*)")).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(objectClass=*)")).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.NoError(t, ldapClient.StartupCheck())
+
+	entry := hook.LastEntry()
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "No entry under the users DN dc=example,dc=com is readable by user cn=admin,dc=example,dc=com, "+
+			"either the users DN is empty or the user lacks the permission to read its entries, every login will fail with user not found", entry.Message)
+	}
+}
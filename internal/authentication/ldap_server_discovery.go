@@ -0,0 +1,157 @@
+package authentication
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ldapServerDiscovery discovers the LDAP servers of a domain with the _ldap._tcp or _ldaps._tcp SRV records and
+// resolves them again once the refresh interval has elapsed so servers coming and going are picked up.
+type ldapServerDiscovery struct {
+	scheme    string
+	domain    string
+	refresh   time.Duration
+	clock     utils.Clock
+	lookupSRV func(service, proto, name string) (cname string, addrs []*net.SRV, err error)
+
+	urls      []string
+	resolved  time.Time
+	err       error
+	failures  int
+	retryAt   time.Time
+	resolving chan struct{}
+	lock      sync.Mutex
+}
+
+// newLDAPServerDiscovery creates the discovery of the LDAP servers of the domain, the scheme being either ldap or ldaps
+// which is both the service of the SRV records and the scheme of the URLs of the discovered servers.
+func newLDAPServerDiscovery(scheme, domain string, refresh time.Duration, clock utils.Clock) *ldapServerDiscovery {
+	return &ldapServerDiscovery{
+		scheme:    scheme,
+		domain:    domain,
+		refresh:   refresh,
+		clock:     clock,
+		lookupSRV: net.LookupSRV,
+	}
+}
+
+// URLs returns the URLs of the discovered servers ordered by priority and randomized by weight. The previously
+// discovered servers are kept when the resolution fails, in which case it is only attempted again after a backoff.
+// The lookup is performed without holding the lock and only once at a time, the callers arriving meanwhile are served
+// the previously discovered servers or wait for the lookup when there are none.
+func (d *ldapServerDiscovery) URLs() ([]string, error) {
+	d.lock.Lock()
+
+	now := d.clock.Now()
+
+	switch {
+	case d.urls != nil && now.Before(d.resolved.Add(d.refresh)):
+		defer d.lock.Unlock()
+
+		return d.urls, nil
+	case now.Before(d.retryAt), d.resolving != nil && d.urls != nil:
+		defer d.lock.Unlock()
+
+		return d.result()
+	case d.resolving != nil:
+		resolving := d.resolving
+		d.lock.Unlock()
+
+		<-resolving
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		return d.result()
+	}
+
+	resolving := make(chan struct{})
+	d.resolving = resolving
+	d.lock.Unlock()
+
+	urls, err := d.resolve()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.resolving = nil
+	close(resolving)
+
+	if err != nil {
+		d.err = err
+		d.failures++
+		d.retryAt = d.clock.Now().Add(d.backoff())
+
+		if d.urls != nil {
+			logging.Logger().Warnf("Unable to refresh the LDAP servers of %s, using the previously discovered servers until %s. Cause: %s",
+				d.domain, d.retryAt.Format(time.RFC3339), err)
+		}
+
+		return d.result()
+	}
+
+	d.urls, d.err = urls, nil
+	d.failures, d.retryAt = 0, time.Time{}
+	d.resolved = now
+
+	return d.urls, nil
+}
+
+// result returns the last discovered servers or the error of the last resolution when no server was ever discovered.
+func (d *ldapServerDiscovery) result() ([]string, error) {
+	if d.urls == nil {
+		return nil, d.err
+	}
+
+	return d.urls, nil
+}
+
+// backoff returns the delay before the next resolution after consecutive failures, doubling with each failure up to
+// the refresh interval.
+func (d *ldapServerDiscovery) backoff() time.Duration {
+	backoff := ldapServerDiscoveryRetryInterval
+
+	for i := 1; i < d.failures && backoff < d.refresh; i++ {
+		backoff *= 2
+	}
+
+	if backoff > d.refresh {
+		return d.refresh
+	}
+
+	return backoff
+}
+
+func (d *ldapServerDiscovery) resolve() ([]string, error) {
+	// The records returned by the lookup are already sorted by priority and randomized by weight.
+	_, records, err := d.lookupSRV(d.scheme, "tcp", d.domain)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to lookup the SRV records of %s. Cause: %s", d.domain, err)
+	}
+
+	urls := make([]string, 0, len(records))
+
+	for _, record := range records {
+		// A single record with the target . indicates the service is not available in the domain.
+		if record.Target == "." {
+			continue
+		}
+
+		urls = append(urls, fmt.Sprintf("%s://%s", d.scheme, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))))
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("No LDAP server found in the SRV records of %s", d.domain)
+	}
+
+	logging.Logger().Debugf("Discovered LDAP servers of %s: %s", d.domain, strings.Join(urls, ", "))
+
+	return urls, nil
+}
@@ -0,0 +1,238 @@
+package authentication
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLDAPServerDiscovery(clock *testClock, lookups *int, records *[]*net.SRV, err *error) *ldapServerDiscovery {
+	discovery := newLDAPServerDiscovery("ldap", "example.com", time.Minute, clock)
+	discovery.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		*lookups++
+
+		if *err != nil {
+			return "", nil, *err
+		}
+
+		return "_" + service + "._" + proto + "." + name + ".", *records, nil
+	}
+
+	return discovery
+}
+
+func TestShouldDiscoverLDAPServersInRecordsOrder(t *testing.T) {
+	var (
+		lookups int
+		err     error
+	)
+
+	records := []*net.SRV{
+		{Target: "dc1.example.com.", Port: 389, Priority: 0, Weight: 100},
+		{Target: "dc2.example.com.", Port: 3268, Priority: 10, Weight: 100},
+	}
+
+	discovery := newTestLDAPServerDiscovery(&testClock{now: time.Unix(1600000000, 0)}, &lookups, &records, &err)
+
+	urls, err := discovery.URLs()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ldap://dc1.example.com:389", "ldap://dc2.example.com:3268"}, urls)
+	assert.Equal(t, 1, lookups)
+}
+
+func TestShouldResolveLDAPServersAgainAfterRefreshInterval(t *testing.T) {
+	var (
+		lookups int
+		err     error
+	)
+
+	records := []*net.SRV{{Target: "dc1.example.com.", Port: 389}}
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	discovery := newTestLDAPServerDiscovery(clock, &lookups, &records, &err)
+
+	_, err = discovery.URLs()
+	require.NoError(t, err)
+
+	records = []*net.SRV{{Target: "dc2.example.com.", Port: 389}}
+	clock.now = clock.now.Add(59 * time.Second)
+
+	urls, err := discovery.URLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ldap://dc1.example.com:389"}, urls)
+	assert.Equal(t, 1, lookups)
+
+	clock.now = clock.now.Add(time.Second)
+
+	urls, err = discovery.URLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ldap://dc2.example.com:389"}, urls)
+	assert.Equal(t, 2, lookups)
+}
+
+func TestShouldKeepDiscoveredLDAPServersWhenRefreshFails(t *testing.T) {
+	var (
+		lookups int
+		err     error
+	)
+
+	records := []*net.SRV{{Target: "dc1.example.com.", Port: 389}}
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	discovery := newTestLDAPServerDiscovery(clock, &lookups, &records, &err)
+
+	_, err = discovery.URLs()
+	require.NoError(t, err)
+
+	err = errors.New("no such host")
+	clock.now = clock.now.Add(time.Minute)
+
+	urls, urlsErr := discovery.URLs()
+	require.NoError(t, urlsErr)
+	assert.Equal(t, []string{"ldap://dc1.example.com:389"}, urls)
+}
+
+func TestShouldFailToDiscoverLDAPServersWithoutRecords(t *testing.T) {
+	var (
+		lookups int
+		err     error
+	)
+
+	records := []*net.SRV{{Target: ".", Port: 0}}
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	discovery := newTestLDAPServerDiscovery(clock, &lookups, &records, &err)
+
+	_, err = discovery.URLs()
+	assert.EqualError(t, err, "No LDAP server found in the SRV records of example.com")
+
+	err = errors.New("no such host")
+	clock.now = clock.now.Add(ldapServerDiscoveryRetryInterval)
+
+	_, urlsErr := discovery.URLs()
+	assert.EqualError(t, urlsErr, "Unable to lookup the SRV records of example.com. Cause: no such host")
+}
+
+func TestShouldBackOffAfterFailedDiscovery(t *testing.T) {
+	var (
+		lookups int
+		err     error
+	)
+
+	records := []*net.SRV{{Target: "dc1.example.com.", Port: 389}}
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	discovery := newTestLDAPServerDiscovery(clock, &lookups, &records, &err)
+
+	_, err = discovery.URLs()
+	require.NoError(t, err)
+
+	err = errors.New("no such host")
+	clock.now = clock.now.Add(time.Minute)
+
+	// The failed lookups are only attempted again after a backoff doubling with each failure up to the refresh
+	// interval while the last discovered servers are served meanwhile.
+	for _, backoff := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		lookupsBefore := lookups
+
+		urls, urlsErr := discovery.URLs()
+		require.NoError(t, urlsErr)
+		assert.Equal(t, []string{"ldap://dc1.example.com:389"}, urls)
+		assert.Equal(t, lookupsBefore+1, lookups)
+
+		clock.now = clock.now.Add(backoff - time.Second)
+
+		urls, urlsErr = discovery.URLs()
+		require.NoError(t, urlsErr)
+		assert.Equal(t, []string{"ldap://dc1.example.com:389"}, urls)
+		assert.Equal(t, lookupsBefore+1, lookups)
+
+		clock.now = clock.now.Add(time.Second)
+	}
+
+	err = nil
+	records = []*net.SRV{{Target: "dc2.example.com.", Port: 389}}
+
+	urls, urlsErr := discovery.URLs()
+	require.NoError(t, urlsErr)
+	assert.Equal(t, []string{"ldap://dc2.example.com:389"}, urls)
+	assert.Equal(t, 0, discovery.failures)
+}
+
+func TestShouldNotDiscoverAgainBeforeBackoffWithoutServers(t *testing.T) {
+	var lookups int
+
+	records := []*net.SRV{}
+	err := errors.New("no such host")
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	discovery := newTestLDAPServerDiscovery(clock, &lookups, &records, &err)
+
+	_, urlsErr := discovery.URLs()
+	assert.EqualError(t, urlsErr, "Unable to lookup the SRV records of example.com. Cause: no such host")
+
+	_, urlsErr = discovery.URLs()
+	assert.EqualError(t, urlsErr, "Unable to lookup the SRV records of example.com. Cause: no such host")
+	assert.Equal(t, 1, lookups)
+}
+
+func TestShouldDiscoverLDAPSServersWithLDAPSRecords(t *testing.T) {
+	var service string
+
+	discovery := newLDAPServerDiscovery("ldaps", "example.com", time.Minute, &testClock{now: time.Unix(1600000000, 0)})
+	discovery.lookupSRV = func(s, proto, name string) (string, []*net.SRV, error) {
+		service = s
+
+		return "_" + s + "._" + proto + "." + name + ".", []*net.SRV{{Target: "dc1.example.com.", Port: 636}}, nil
+	}
+
+	urls, err := discovery.URLs()
+	require.NoError(t, err)
+
+	assert.Equal(t, "ldaps", service)
+	assert.Equal(t, []string{"ldaps://dc1.example.com:636"}, urls)
+}
+
+func TestShouldLookupOnceWhileServersAreBeingDiscovered(t *testing.T) {
+	var lookups int32
+
+	release := make(chan struct{})
+
+	discovery := newLDAPServerDiscovery("ldap", "example.com", time.Minute, &testClock{now: time.Unix(1600000000, 0)})
+	discovery.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		atomic.AddInt32(&lookups, 1)
+		<-release
+
+		return "", []*net.SRV{{Target: "dc1.example.com.", Port: 389}}, nil
+	}
+
+	var wg sync.WaitGroup
+
+	results := make(chan []string, 5)
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			urls, err := discovery.URLs()
+			assert.NoError(t, err)
+
+			results <- urls
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for urls := range results {
+		assert.Equal(t, []string{"ldap://dc1.example.com:389"}, urls)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+}
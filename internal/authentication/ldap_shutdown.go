@@ -0,0 +1,114 @@
+package authentication
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+// ldapConnectionTracker holds the connections of the provider which are bound and not closed yet, so they're unbound
+// and closed rather than dropped when the provider is closed. Its zero value is ready to use.
+type ldapConnectionTracker struct {
+	mutex       sync.Mutex
+	closed      bool
+	connections map[*ldapTrackedConnection]struct{}
+}
+
+// track returns the bound connection tracked until it is closed, the connection is closed and ErrProviderClosed is
+// returned when the provider is already closed.
+func (t *ldapConnectionTracker) track(conn LDAPConnection) (LDAPConnection, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		unbindAndClose(conn)
+
+		return nil, ErrProviderClosed
+	}
+
+	if t.connections == nil {
+		t.connections = make(map[*ldapTrackedConnection]struct{})
+	}
+
+	tracked := &ldapTrackedConnection{LDAPConnection: conn, tracker: t}
+	t.connections[tracked] = struct{}{}
+
+	return tracked, nil
+}
+
+func (t *ldapConnectionTracker) untrack(conn *ldapTrackedConnection) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.connections, conn)
+}
+
+func (t *ldapConnectionTracker) isClosed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.closed
+}
+
+// close refuses the connections tracked from now on and returns the connections still open.
+func (t *ldapConnectionTracker) close() []*ldapTrackedConnection {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.closed = true
+
+	connections := make([]*ldapTrackedConnection, 0, len(t.connections))
+
+	for conn := range t.connections {
+		connections = append(connections, conn)
+	}
+
+	return connections
+}
+
+// ldapTrackedConnection is a bound connection tracked by the provider until it is closed. The connection is unbound
+// and closed at most once whether it is by the operation using it or by the provider being closed.
+type ldapTrackedConnection struct {
+	LDAPConnection
+
+	tracker *ldapConnectionTracker
+	unbind  sync.Once
+	close   sync.Once
+}
+
+// Unbind ends the session of the connection unless it has already been ended.
+func (c *ldapTrackedConnection) Unbind() (err error) {
+	c.unbind.Do(func() {
+		err = c.LDAPConnection.Unbind()
+	})
+
+	return err
+}
+
+// Close closes the connection unless it has already been closed and stops tracking it.
+func (c *ldapTrackedConnection) Close() {
+	c.close.Do(func() {
+		c.tracker.untrack(c)
+		c.LDAPConnection.Close()
+	})
+}
+
+// TLSConnectionState returns the TLS connection state of the tracked connection.
+func (c *ldapTrackedConnection) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	return ldapTLSConnectionState(c.LDAPConnection)
+}
+
+// Close unbinds and closes the connections to the LDAP servers the provider still holds, such as the connections of
+// the warm up or of the operations in progress when Authelia shuts down, so no session is left dangling on the LDAP
+// servers. The operations started afterwards are refused with ErrProviderClosed. The error is the first error of the
+// unbind requests, the connections are closed regardless.
+func (p *LDAPUserProvider) Close() (err error) {
+	for _, conn := range p.connections.close() {
+		if unbindErr := conn.Unbind(); unbindErr != nil && err == nil {
+			err = unbindErr
+		}
+
+		conn.Close()
+	}
+
+	return err
+}
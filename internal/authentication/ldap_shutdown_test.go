@@ -0,0 +1,91 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldUnbindOpenConnectionsOnClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	searching, closed := make(chan struct{}), make(chan struct{})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			DoAndReturn(func(_ *ldap.SearchRequest) (*ldap.SearchResult, error) {
+				// The search is in progress when the provider is closed.
+				close(searching)
+				<-closed
+
+				return nil, errors.New("ldap: connection closed")
+			}),
+	)
+
+	// The connection is unbound and closed once by the provider, the operation using it doesn't unbind it again.
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close().
+		Do(func() { close(closed) })
+
+	errs := make(chan error, 1)
+
+	go func() {
+		_, err := ldapClient.UserExists("john")
+		errs <- err
+	}()
+
+	<-searching
+
+	require.NoError(t, ldapClient.Close())
+	assert.Error(t, <-errs)
+
+	// The operations started once the provider is closed are refused without connecting.
+	_, err := ldapClient.UserExists("john")
+	assert.True(t, errors.Is(err, ErrProviderClosed))
+	assert.True(t, IsBackendUnavailableError(err))
+}
+
+func TestShouldUnbindConnectionBoundWhileClosing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			DoAndReturn(func(_, _ string) error {
+				require.NoError(t, ldapClient.Close())
+
+				return nil
+			}),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.UserExists("john")
+	assert.True(t, errors.Is(err, ErrProviderClosed))
+}
@@ -0,0 +1,70 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// enforceSizeLimits drops the values of the attributes larger than the maximum attribute value size and fails when the
+// entries of the search result, as returned by the LDAP server, are larger than the maximum search result size. The
+// LDAP library has already read the result in memory, the limits keep the oversized values out of the details of the
+// users, of their sessions and of the cache.
+func (p *LDAPUserProvider) enforceSizeLimits(ctx context.Context, searchRequest *ldap.SearchRequest, sr *ldap.SearchResult) error {
+	var size int
+
+	for _, entry := range sr.Entries {
+		size += len(entry.DN)
+
+		for _, attribute := range entry.Attributes {
+			size += len(attribute.Name)
+
+			for _, value := range attribute.Values {
+				size += len(value)
+			}
+
+			if p.configuration.MaxAttributeValueSize > 0 {
+				p.dropOversizedValues(ctx, entry.DN, attribute)
+			}
+		}
+	}
+
+	if p.configuration.MaxSearchResultSize > 0 && size > p.configuration.MaxSearchResultSize {
+		return fmt.Errorf("%w: the entries returned by the search with filter %s under %s total %d bytes, more than the max_search_result_size of %d bytes",
+			ErrSearchResultTooLarge, searchRequest.Filter, searchRequest.BaseDN, size, p.configuration.MaxSearchResultSize)
+	}
+
+	return nil
+}
+
+// dropOversizedValues removes the values of the attribute larger than the maximum attribute value size, from both the
+// string and the binary values, and logs each dropped value.
+func (p *LDAPUserProvider) dropOversizedValues(ctx context.Context, dn string, attribute *ldap.EntryAttribute) {
+	var (
+		values     []string
+		byteValues [][]byte
+		dropped    bool
+	)
+
+	for i, value := range attribute.Values {
+		if len(value) > p.configuration.MaxAttributeValueSize {
+			operationLogger(ctx).Warnf("Dropping a value of %d bytes of attribute %s of entry %s, more than the max_attribute_value_size of %d bytes",
+				len(value), attribute.Name, dn, p.configuration.MaxAttributeValueSize)
+
+			dropped = true
+
+			continue
+		}
+
+		values = append(values, value)
+
+		if i < len(attribute.ByteValues) {
+			byteValues = append(byteValues, attribute.ByteValues[i])
+		}
+	}
+
+	if dropped {
+		attribute.Values, attribute.ByteValues = values, byteValues
+	}
+}
@@ -0,0 +1,111 @@
+package authentication
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldDropAttributeValuesLargerThanMaxAttributeValueSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.PhotoAttribute = "jpegPhoto"
+	ldapClient.configuration.MaxAttributeValueSize = 1024
+
+	photo := append([]byte{0xff, 0xd8, 0xff, 0xe0}, bytes.Repeat([]byte{0x00}, 4092)...)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{
+						"uid":         {"john"},
+						"displayname": {"John Doe"},
+						"jpegPhoto":   {string(photo)},
+					}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "John Doe", details.DisplayName)
+	assert.Empty(t, details.Photo)
+	assert.Equal(t, "", details.PhotoMIMEType)
+
+	var entry *logrus.Entry
+
+	for i := range hook.AllEntries() {
+		if hook.AllEntries()[i].Level == logrus.WarnLevel {
+			entry = hook.AllEntries()[i]
+			break
+		}
+	}
+
+	require.NotNil(t, entry)
+	assert.Equal(t, "Dropping a value of 4096 bytes of attribute jpegPhoto of entry uid=john,dc=example,dc=com, more than the max_attribute_value_size of 1024 bytes", entry.Message)
+}
+
+func TestShouldFailSearchLargerThanMaxSearchResultSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.MaxSearchResultSize = 1024
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{
+						"uid":         {"john"},
+						"description": {string(bytes.Repeat([]byte{'a'}, 2048))},
+					}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	_, err := ldapClient.GetDetails("john")
+	assert.True(t, errors.Is(err, ErrSearchResultTooLarge))
+	assert.EqualError(t, err, "Cannot find user DN of user john. Cause: search result too large: the entries returned by the search with filter (uid=john) under dc=example,dc=com total 2092 bytes, more than the max_search_result_size of 1024 bytes")
+}
@@ -0,0 +1,471 @@
+package authentication
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// testLDAPServerEntry is an entry of the directory of the test LDAP server, the userPassword attribute holds the
+// password of the entry in plain text.
+type testLDAPServerEntry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// testLDAPServer is a minimal LDAP server listening on a real socket so the requests of the provider go through the
+// encoding of the LDAP library, unlike with the mocks of the connections. It supports the simple binds, the searches
+// with the and, or, not, equality, substrings and presence filters as well as the bitwise and extensible match of
+// Active Directory, the modifications and the Password Modify extended operation, which is enough to run the operations
+// of the provider end to end.
+type testLDAPServer struct {
+	listener net.Listener
+	entries  []*testLDAPServerEntry
+	lock     sync.Mutex
+	wg       sync.WaitGroup
+
+	// Searches records the filters of the searches, as decoded from the requests, in the string representation of the
+	// LDAP library.
+	Searches []string
+}
+
+// newTestLDAPServer starts a test LDAP server serving the entries on a random port of the loopback interface until the
+// end of the test or the benchmark.
+func newTestLDAPServer(t testing.TB, entries ...*testLDAPServerEntry) *testLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &testLDAPServer{listener: listener, entries: entries}
+
+	server.wg.Add(1)
+
+	go server.serve()
+
+	t.Cleanup(func() {
+		listener.Close()
+		server.wg.Wait()
+	})
+
+	return server
+}
+
+// URL returns the URL of the test LDAP server.
+func (s *testLDAPServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Entry returns the entry of the DN, nil when there is none.
+func (s *testLDAPServer) Entry(dn string) *testLDAPServerEntry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.entry(dn)
+}
+
+func (s *testLDAPServer) entry(dn string) *testLDAPServerEntry {
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+func (s *testLDAPServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	var boundDN string
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		messageID := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		var responses []*ber.Packet
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			var code uint16
+
+			code, boundDN = s.bind(request)
+			responses = append(responses, testLDAPResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(request)
+		case ldap.ApplicationModifyRequest:
+			responses = append(responses, testLDAPResult(ldap.ApplicationModifyResponse, s.modify(request)))
+		case ldap.ApplicationExtendedRequest:
+			responses = append(responses, testLDAPResult(ldap.ApplicationExtendedResponse, s.extended(request, boundDN)))
+		case ldap.ApplicationAbandonRequest:
+			continue
+		default:
+			responses = append(responses, testLDAPResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError))
+		}
+
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			envelope.AppendChild(response)
+
+			if _, err = conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks the password of a simple bind and returns the result code along with the DN bound.
+func (s *testLDAPServer) bind(request *ber.Packet) (code uint16, dn string) {
+	dn = testLDAPString(request.Children[1])
+	password := testLDAPString(request.Children[2])
+
+	// An empty DN is an anonymous bind.
+	if dn == "" {
+		return ldap.LDAPResultSuccess, ""
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := s.entry(dn)
+	if entry == nil || password == "" || len(entry.Attributes["userPassword"]) == 0 || entry.Attributes["userPassword"][0] != password {
+		return ldap.LDAPResultInvalidCredentials, ""
+	}
+
+	return ldap.LDAPResultSuccess, entry.DN
+}
+
+func (s *testLDAPServer) search(request *ber.Packet) []*ber.Packet {
+	baseDN := testLDAPString(request.Children[0])
+	scope := request.Children[1].Value.(int64)
+	sizeLimit := int(request.Children[3].Value.(int64))
+	filter := request.Children[6]
+
+	var attributes []string
+
+	for _, attribute := range request.Children[7].Children {
+		attributes = append(attributes, testLDAPString(attribute))
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if decoded, err := ldap.DecompileFilter(filter); err == nil {
+		s.Searches = append(s.Searches, decoded)
+	}
+
+	// The root DSE is an empty entry.
+	if baseDN == "" && scope == ldap.ScopeBaseObject {
+		return []*ber.Packet{
+			testLDAPSearchEntry(&testLDAPServerEntry{}, attributes),
+			testLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess),
+		}
+	}
+
+	if s.entry(baseDN) == nil {
+		return []*ber.Packet{testLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject)}
+	}
+
+	var responses []*ber.Packet
+
+	for _, entry := range s.entries {
+		if !testLDAPInScope(entry.DN, baseDN, scope) || !testLDAPMatches(entry, filter) {
+			continue
+		}
+
+		if sizeLimit > 0 && len(responses) == sizeLimit {
+			return append(responses, testLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+		}
+
+		responses = append(responses, testLDAPSearchEntry(entry, attributes))
+	}
+
+	return append(responses, testLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func (s *testLDAPServer) modify(request *ber.Packet) uint16 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := s.entry(testLDAPString(request.Children[0]))
+	if entry == nil {
+		return ldap.LDAPResultNoSuchObject
+	}
+
+	for _, change := range request.Children[1].Children {
+		operation := change.Children[0].Value.(int64)
+		name := testLDAPString(change.Children[1].Children[0])
+
+		var values []string
+
+		for _, value := range change.Children[1].Children[1].Children {
+			values = append(values, testLDAPString(value))
+		}
+
+		switch operation {
+		case ldap.AddAttribute:
+			entry.Attributes[name] = append(entry.Attributes[name], values...)
+		case ldap.DeleteAttribute:
+			delete(entry.Attributes, name)
+		case ldap.ReplaceAttribute:
+			entry.Attributes[name] = values
+		}
+	}
+
+	return ldap.LDAPResultSuccess
+}
+
+// extended supports the Password Modify extended operation of RFC 3062 only.
+func (s *testLDAPServer) extended(request *ber.Packet, boundDN string) uint16 {
+	if len(request.Children) < 2 || testLDAPString(request.Children[0]) != "1.3.6.1.4.1.4203.1.11.1" {
+		return ldap.LDAPResultProtocolError
+	}
+
+	value, err := ber.DecodePacketErr(request.Children[1].Data.Bytes())
+	if err != nil {
+		return ldap.LDAPResultProtocolError
+	}
+
+	dn, newPassword := boundDN, ""
+
+	for _, child := range value.Children {
+		switch child.Tag {
+		case 0:
+			dn = testLDAPString(child)
+		case 2:
+			newPassword = testLDAPString(child)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := s.entry(dn)
+	if entry == nil || newPassword == "" {
+		return ldap.LDAPResultUnwillingToPerform
+	}
+
+	entry.Attributes["userPassword"] = []string{newPassword}
+
+	return ldap.LDAPResultSuccess
+}
+
+// testLDAPString returns the content of a primitive element, whatever its class.
+func testLDAPString(packet *ber.Packet) string {
+	return packet.Data.String()
+}
+
+func testLDAPResult(tag ber.Tag, code uint16) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	return result
+}
+
+func testLDAPSearchEntry(entry *testLDAPServerEntry, attributes []string) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+
+	for name, values := range entry.Attributes {
+		if !testLDAPRequested(name, attributes) {
+			continue
+		}
+
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+
+	result.AppendChild(list)
+
+	return result
+}
+
+// testLDAPRequested returns true when the attribute is requested, no attribute and * request all the attributes while
+// 1.1 requests none.
+func testLDAPRequested(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func testLDAPInScope(dn string, baseDN string, scope int64) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		parts := strings.SplitN(dn, ",", 2)
+		return len(parts) == 2 && parts[1] == baseDN
+	default:
+		return dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+// testLDAPMatches evaluates the filter against the entry, the values are compared case insensitively like with the
+// caseIgnoreMatch matching rule.
+func testLDAPMatches(entry *testLDAPServerEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !testLDAPMatches(entry, child) {
+				return false
+			}
+		}
+
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if testLDAPMatches(entry, child) {
+				return true
+			}
+		}
+
+		return false
+	case ldap.FilterNot:
+		return !testLDAPMatches(entry, filter.Children[0])
+	case ldap.FilterPresent:
+		return len(testLDAPValues(entry, testLDAPString(filter))) != 0
+	case ldap.FilterEqualityMatch:
+		expected := testLDAPString(filter.Children[1])
+
+		for _, value := range testLDAPValues(entry, testLDAPString(filter.Children[0])) {
+			if strings.EqualFold(value, expected) {
+				return true
+			}
+		}
+
+		return false
+	case ldap.FilterSubstrings:
+		for _, value := range testLDAPValues(entry, testLDAPString(filter.Children[0])) {
+			if testLDAPMatchesSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+
+		return false
+	case ldap.FilterExtensibleMatch:
+		return testLDAPMatchesBitwiseAnd(entry, filter.Children)
+	default:
+		return false
+	}
+}
+
+// testLDAPMatchesBitwiseAnd evaluates the extensible match with the LDAP_MATCHING_RULE_BIT_AND rule of Active Directory
+// such as (userAccountControl:1.2.840.113556.1.4.803:=2), the other matching rules never match.
+func testLDAPMatchesBitwiseAnd(entry *testLDAPServerEntry, assertions []*ber.Packet) bool {
+	var rule, name, value string
+
+	for _, assertion := range assertions {
+		switch assertion.Tag {
+		case ldap.MatchingRuleAssertionMatchingRule:
+			rule = testLDAPString(assertion)
+		case ldap.MatchingRuleAssertionType:
+			name = testLDAPString(assertion)
+		case ldap.MatchingRuleAssertionMatchValue:
+			value = testLDAPString(assertion)
+		}
+	}
+
+	bits, err := strconv.ParseUint(value, 10, 32)
+	if rule != "1.2.840.113556.1.4.803" || err != nil {
+		return false
+	}
+
+	for _, v := range testLDAPValues(entry, name) {
+		if flags, err := strconv.ParseUint(v, 10, 32); err == nil && flags&bits == bits {
+			return true
+		}
+	}
+
+	return false
+}
+
+func testLDAPMatchesSubstrings(value string, substrings []*ber.Packet) bool {
+	for _, substring := range substrings {
+		part := strings.ToLower(testLDAPString(substring))
+
+		switch substring.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, part) {
+				return false
+			}
+
+			value = value[len(part):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, part)
+			if i < 0 {
+				return false
+			}
+
+			value = value[i+len(part):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, part) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func testLDAPValues(entry *testLDAPServerEntry, name string) []string {
+	for attribute, values := range entry.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+
+	return nil
+}
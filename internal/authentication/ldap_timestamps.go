@@ -0,0 +1,162 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// adFileTimeEpochOffset is the number of 100 nanoseconds intervals between the FILETIME epoch, January 1 1601, and
+// the Unix epoch.
+const adFileTimeEpochOffset = 116444736000000000
+
+// ldapGeneralizedTimeLayout is the layout of the generalized time syntax. The fraction of seconds is optional and
+// accepted by time.Parse even though the layout doesn't include it.
+const ldapGeneralizedTimeLayout = "20060102150405Z0700"
+
+// parsePasswordTimestamps parses the password and account timestamps of the entry into the profile. The timestamps
+// which are absent or can't be parsed are left zero. The password must be changed when Active Directory reports a
+// pwdLastSet of 0 or the password policy overlay reports a pwdReset of TRUE.
+func (p *LDAPUserProvider) parsePasswordTimestamps(ctx context.Context, entry *ldap.Entry, profile *ldapUserProfile) {
+	var err error
+
+	if p.configuration.Implementation == schema.LDAPImplementationActiveDirectory {
+		profile.MustChangePassword = entry.GetAttributeValue(adAttributePwdLastSet) == "0"
+
+		if profile.PasswordLastSet, err = adFileTime(entry.GetAttributeValue(adAttributePwdLastSet)); err != nil {
+			operationLogger(ctx).Debugf("Unable to parse the %s of user %s. Cause: %s", adAttributePwdLastSet, profile.DN, err)
+		}
+
+		if profile.PasswordExpires, err = adFileTime(entry.GetAttributeValue(adAttributeMSDSUserPasswordExpiryTimeComputed)); err != nil {
+			operationLogger(ctx).Debugf("Unable to parse the %s of user %s. Cause: %s", adAttributeMSDSUserPasswordExpiryTimeComputed, profile.DN, err)
+		}
+
+		if profile.AccountExpires, err = adFileTime(entry.GetAttributeValue(adAttributeAccountExpires)); err != nil {
+			operationLogger(ctx).Debugf("Unable to parse the %s of user %s. Cause: %s", adAttributeAccountExpires, profile.DN, err)
+		}
+
+		return
+	}
+
+	if profile.PasswordLastSet, err = ldapGeneralizedTime(entry.GetAttributeValue(ppolicyAttributePwdChangedTime)); err != nil {
+		operationLogger(ctx).Debugf("Unable to parse the %s of user %s. Cause: %s", ppolicyAttributePwdChangedTime, profile.DN, err)
+	}
+
+	profile.PasswordPolicySubentry = entry.GetAttributeValue(ppolicyAttributePwdPolicySubentry)
+	profile.MustChangePassword = strings.EqualFold(entry.GetAttributeValue(ppolicyAttributePwdReset), "TRUE")
+}
+
+// parseOperationalTimestamps parses the values of the operational attributes of the entry into the profile, such as
+// the createTimestamp and the modifyTimestamp. Every attribute of the entry is parsed when the + pseudo attribute
+// requests all the operational attributes. The values which aren't of the generalized time syntax are ignored.
+func (p *LDAPUserProvider) parseOperationalTimestamps(ctx context.Context, entry *ldap.Entry, profile *ldapUserProfile) {
+	if len(p.configuration.OperationalAttributes) == 0 {
+		return
+	}
+
+	all := utils.IsStringInSlice(ldapAllOperationalAttributes, p.configuration.OperationalAttributes)
+
+	profile.OperationalTimestamps = make(map[string]time.Time)
+
+	for _, attr := range entry.Attributes {
+		if len(attr.Values) == 0 {
+			continue
+		}
+
+		name := attr.Name
+
+		if !all {
+			var requested bool
+
+			for _, operationalAttribute := range p.configuration.OperationalAttributes {
+				if strings.EqualFold(attr.Name, operationalAttribute) {
+					name, requested = operationalAttribute, true
+					break
+				}
+			}
+
+			if !requested {
+				continue
+			}
+		}
+
+		t, err := ldapGeneralizedTime(attr.Values[0])
+		if err != nil || t.IsZero() {
+			if !all {
+				operationLogger(ctx).Debugf("Unable to parse the %s of user %s. Cause: %v", name, profile.DN, err)
+			}
+
+			continue
+		}
+
+		profile.OperationalTimestamps[name] = t
+	}
+}
+
+// passwordPolicyExpiry computes the time the password of the user expires from the maximum age of the passwords of
+// the password policy applying to the user. The time is zero when the passwords never expire or the policy can't be
+// retrieved.
+func (p *LDAPUserProvider) passwordPolicyExpiry(ctx context.Context, conn LDAPConnection, profile *ldapUserProfile) time.Time {
+	searchRequest := ldap.NewSearchRequest(
+		profile.PasswordPolicySubentry, ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		1, 0, false, "(objectClass=*)", []string{ppolicyAttributePwdMaxAge}, nil,
+	)
+
+	sr, err := p.search(ctx, conn, searchRequest)
+	if err != nil || len(sr.Entries) == 0 {
+		operationLogger(ctx).Warnf("Unable to retrieve the password policy %s of user %s. Cause: %v", profile.PasswordPolicySubentry, profile.Username, err)
+		return time.Time{}
+	}
+
+	maxAge, err := strconv.ParseInt(sr.Entries[0].GetAttributeValue(ppolicyAttributePwdMaxAge), 10, 64)
+	if err != nil || maxAge <= 0 {
+		return time.Time{}
+	}
+
+	return profile.PasswordLastSet.Add(time.Duration(maxAge) * time.Second)
+}
+
+// adFileTime converts an Active Directory FILETIME, the number of 100 nanoseconds intervals since January 1 1601, to a
+// time. The time is zero when the value is absent or represents a time which never comes.
+func adFileTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	fileTime, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid FILETIME %s. Cause: %s", value, err)
+	}
+
+	// The zero value and the maximum value mean never, for instance for an account which never expires.
+	if fileTime <= 0 || fileTime == math.MaxInt64 {
+		return time.Time{}, nil
+	}
+
+	fileTime -= adFileTimeEpochOffset
+
+	return time.Unix(fileTime/1e7, (fileTime%1e7)*100).UTC(), nil
+}
+
+// ldapGeneralizedTime converts a value of the generalized time syntax such as 20210101120000Z to a time. The time is
+// zero when the value is absent.
+func ldapGeneralizedTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(ldapGeneralizedTimeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid generalized time %s. Cause: %s", value, err)
+	}
+
+	return t.UTC(), nil
+}
@@ -0,0 +1,127 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldConvertActiveDirectoryFileTime(t *testing.T) {
+	converted, err := adFileTime("132539328000000000")
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), converted)
+
+	for _, never := range []string{"", "0", "9223372036854775807"} {
+		converted, err = adFileTime(never)
+		require.NoError(t, err)
+
+		assert.True(t, converted.IsZero())
+	}
+
+	_, err = adFileTime("abc")
+	assert.EqualError(t, err, "Invalid FILETIME abc. Cause: strconv.ParseInt: parsing \"abc\": invalid syntax")
+}
+
+func TestShouldConvertLDAPGeneralizedTime(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"20210101120000Z", time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{"20210101120000.5Z", time.Date(2021, time.January, 1, 12, 0, 0, 500000000, time.UTC)},
+		{"20210101140000+0200", time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{"", time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			converted, err := ldapGeneralizedTime(tc.value)
+			require.NoError(t, err)
+
+			assert.True(t, tc.expected.Equal(converted), "expected %s, got %s", tc.expected, converted)
+		})
+	}
+
+	_, err := ldapGeneralizedTime("yesterday")
+	assert.Error(t, err)
+}
+
+func TestShouldDetectMustChangePassword(t *testing.T) {
+	testCases := []struct {
+		name           string
+		implementation string
+		attribute      string
+		value          string
+		expected       bool
+	}{
+		{"ActiveDirectoryPwdLastSetZero", schema.LDAPImplementationActiveDirectory, "pwdLastSet", "0", true},
+		{"ActiveDirectoryPwdLastSet", schema.LDAPImplementationActiveDirectory, "pwdLastSet", "132539328000000000", false},
+		{"ActiveDirectoryIgnoresPwdReset", schema.LDAPImplementationActiveDirectory, "pwdReset", "TRUE", false},
+		{"PasswordPolicyPwdReset", schema.LDAPImplementationCustom, "pwdReset", "TRUE", true},
+		{"PasswordPolicyPwdResetFalse", schema.LDAPImplementationCustom, "pwdReset", "FALSE", false},
+		{"PasswordPolicyIgnoresPwdLastSet", schema.LDAPImplementationCustom, "pwdLastSet", "0", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{
+				Implementation: tc.implementation,
+			}, nil)
+
+			entry := ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{tc.attribute: {tc.value}})
+			profile := &ldapUserProfile{DN: entry.DN}
+
+			provider.parsePasswordTimestamps(context.Background(), entry, profile)
+
+			assert.Equal(t, tc.expected, profile.MustChangePassword)
+		})
+	}
+}
+
+func TestShouldParseOperationalTimestamps(t *testing.T) {
+	entry := ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{
+		"uid":             {"john"},
+		"createtimestamp": {"20210101120000Z"},
+		"modifyTimestamp": {"20210301120000Z"},
+		"entryUUID":       {"6a25c2f4-2ad2-4c8b-8d8f-5d3d4c5e0e7e"},
+	})
+
+	provider := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{
+		OperationalAttributes: []string{"createTimestamp", "modifyTimestamp", "entryUUID"},
+	}, nil)
+
+	profile := &ldapUserProfile{DN: entry.DN}
+
+	provider.parseOperationalTimestamps(context.Background(), entry, profile)
+
+	assert.Equal(t, map[string]time.Time{
+		"createTimestamp": time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC),
+		"modifyTimestamp": time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC),
+	}, profile.OperationalTimestamps)
+
+	// Every attribute of the generalized time syntax is parsed when all the operational attributes are requested.
+	provider.configuration.OperationalAttributes = []string{"+"}
+	profile = &ldapUserProfile{DN: entry.DN}
+
+	provider.parseOperationalTimestamps(context.Background(), entry, profile)
+
+	assert.Equal(t, map[string]time.Time{
+		"createtimestamp": time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC),
+		"modifyTimestamp": time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC),
+	}, profile.OperationalTimestamps)
+
+	// The profile has no timestamps when no operational attribute is requested.
+	provider.configuration.OperationalAttributes = nil
+	profile = &ldapUserProfile{DN: entry.DN}
+
+	provider.parseOperationalTimestamps(context.Background(), entry, profile)
+
+	assert.Nil(t, profile.OperationalTimestamps)
+}
@@ -0,0 +1,85 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ldapTLSConnection is implemented by the connections able to report their TLS connection state, which the mocks of
+// the connections are not.
+type ldapTLSConnection interface {
+	TLSConnectionState() (state tls.ConnectionState, ok bool)
+}
+
+// TLSConnectionState returns the TLS connection state of the connection, ok is false when it is not encrypted.
+func (lc *LDAPConnectionImpl) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	return lc.conn.TLSConnectionState()
+}
+
+// TLSConnectionState returns the TLS connection state of the underlying connection.
+func (c *ldapContextConnection) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	return ldapTLSConnectionState(c.LDAPConnection)
+}
+
+// TLSConnectionState returns the TLS connection state of the recorded connection.
+func (c *recordingLDAPConnection) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	return ldapTLSConnectionState(c.conn)
+}
+
+// ldapTLSConnectionState returns the TLS connection state of the connection when it is able to report it.
+func ldapTLSConnectionState(conn LDAPConnection) (state tls.ConnectionState, ok bool) {
+	if tlsConn, isTLSConn := conn.(ldapTLSConnection); isTLSConn {
+		return tlsConn.TLSConnectionState()
+	}
+
+	return state, false
+}
+
+// transportSecurity returns the transport security of the connection bound as a user. The binds of the users are
+// always performed against the URL, never against the global catalog.
+func (p *LDAPUserProvider) transportSecurity(conn LDAPConnection) *TransportSecurity {
+	if isLDAPIURL(p.configuration.URL) {
+		return &TransportSecurity{Transport: TransportLDAPI}
+	}
+
+	state, ok := ldapTLSConnectionState(conn)
+
+	switch {
+	case !ok:
+		return &TransportSecurity{Transport: TransportPlaintext}
+	case p.configuration.StartTLS:
+		return &TransportSecurity{Transport: TransportStartTLS, TLSVersion: tlsVersionName(state.Version)}
+	default:
+		return &TransportSecurity{Transport: TransportLDAPS, TLSVersion: tlsVersionName(state.Version)}
+	}
+}
+
+// logTransportSecurity logs the transport security the password of the user was checked over for auditing.
+func logTransportSecurity(ctx context.Context, inputUsername string, transport *TransportSecurity) {
+	fields := logrus.Fields{"transport": transport.Transport}
+
+	if transport.TLSVersion != "" {
+		fields["tls_version"] = transport.TLSVersion
+	}
+
+	operationLogger(ctx).WithFields(fields).Infof("The password of user %s was checked over a %s connection to the LDAP server", inputUsername, transport.Transport)
+}
+
+// tlsVersionName returns the name of the TLS version like TLS 1.3.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
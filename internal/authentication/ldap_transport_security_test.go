@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tlsTestLDAPConnection is a LDAPConnection reporting a TLS connection state like the connections over TLS.
+type tlsTestLDAPConnection struct {
+	LDAPConnection
+
+	version uint16
+}
+
+func (c *tlsTestLDAPConnection) TLSConnectionState() (state tls.ConnectionState, ok bool) {
+	return tls.ConnectionState{Version: c.version}, true
+}
+
+func TestShouldReturnTransportSecurityOfUserBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.StartTLS = true
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			StartTLS(gomock.Any()).
+			Return(nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(&tlsTestLDAPConnection{LDAPConnection: mockConn, version: tls.VersionTLS13}, nil),
+		mockConn.EXPECT().
+			StartTLS(gomock.Any()).
+			Return(nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	transport, err := ldapClient.CheckUserPasswordWithTransportSecurity(context.Background(), "john", "password")
+	require.NoError(t, err)
+
+	assert.Equal(t, &TransportSecurity{Transport: TransportStartTLS, TLSVersion: "TLS 1.3"}, transport)
+}
+
+func TestShouldDetermineTransportSecurity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The TLS connection state is reported through the connection bound to the context.
+	tlsConn := newLDAPContextConnection(ctx, &tlsTestLDAPConnection{LDAPConnection: mockConn, version: tls.VersionTLS12})
+
+	mockConn.EXPECT().Close()
+
+	defer tlsConn.Close()
+
+	assert.Equal(t, &TransportSecurity{Transport: TransportLDAPS, TLSVersion: "TLS 1.2"}, ldapClient.transportSecurity(tlsConn))
+	assert.Equal(t, &TransportSecurity{Transport: TransportPlaintext}, ldapClient.transportSecurity(mockConn))
+
+	ldapClient.configuration.StartTLS = true
+
+	assert.Equal(t, &TransportSecurity{Transport: TransportStartTLS, TLSVersion: "TLS 1.2"}, ldapClient.transportSecurity(tlsConn))
+
+	ldapClient.configuration.URL = "ldapi:///var/run/slapd/ldapi"
+
+	assert.Equal(t, &TransportSecurity{Transport: TransportLDAPI}, ldapClient.transportSecurity(mockConn))
+
+	assert.Equal(t, "0x0200", tlsVersionName(0x0200))
+}
@@ -0,0 +1,104 @@
+package authentication
+
+import (
+	"context"
+)
+
+// LDAPUserTestReport is the report of the resolution and the authentication of a user against the LDAP server, which
+// helps troubleshooting the users and groups filters.
+type LDAPUserTestReport struct {
+	// UsersFilter is the users filter computed from the input of the user.
+	UsersFilter string
+
+	// GroupsFilter is the groups filter computed from the input and the profile of the user, empty when the user is
+	// not found.
+	GroupsFilter string
+
+	// DN is the distinguished name of the user, empty when the user is not found.
+	DN string
+
+	// Username is the username of the user read from the username attribute.
+	Username string
+
+	// Groups are the names of the groups matched by the groups filter.
+	Groups []string
+
+	// Authenticated is true when the bind with the DN and the password of the user succeeded.
+	Authenticated bool
+
+	// BindError is the error of the bind with the DN and the password of the user, nil when the bind succeeded or no
+	// password was given.
+	BindError error
+
+	// Transport is the transport security of the connection of the bind, nil when the bind failed or was skipped.
+	Transport *TransportSecurity
+
+	// AuthzID is the authorization identity the LDAP server associates with the bind of the user, empty unless the Who
+	// Am I? extended operation is enabled and supported by the LDAP server.
+	AuthzID string
+}
+
+// TestUser resolves the user with the given input and binds with the given password to report the computed filters,
+// the DN, the groups and the result of the bind. The bind is skipped when the password is empty.
+func (p *LDAPUserProvider) TestUser(inputUsername string, password string) (*LDAPUserTestReport, error) {
+	return p.TestUserWithContext(context.Background(), inputUsername, password)
+}
+
+// TestUserWithContext resolves the user with the given input and binds with the given password to report the
+// computed filters, the DN, the groups and the result of the bind. The operations are neither retried nor cached so
+// the report reflects the current state of the LDAP server. The report is returned along with the error with the
+// steps completed before the error occurred.
+func (p *LDAPUserProvider) TestUserWithContext(ctx context.Context, inputUsername string, password string) (*LDAPUserTestReport, error) {
+	ctx = newOperationContext(ctx, "test_user", inputUsername)
+
+	report := &LDAPUserTestReport{
+		UsersFilter: p.resolveUsersFilter(p.configuration.UsersFilter, inputUsername),
+	}
+
+	conn, err := p.connectSearch(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer unbindAndClose(conn)
+
+	profile, err := p.getUserProfile(ctx, conn, inputUsername)
+	if err != nil {
+		return report, err
+	}
+
+	report.DN = profile.DN
+	report.Username = profile.Username
+
+	if report.GroupsFilter, err = p.resolveGroupsFilter(inputUsername, profile); err != nil {
+		return report, err
+	}
+
+	details, err := p.getUserDetails(ctx, conn, inputUsername, profile)
+	if err != nil {
+		return report, err
+	}
+
+	report.Groups = details.Groups
+
+	if password == "" {
+		return report, nil
+	}
+
+	userConn, _, err := p.connectUser(ctx, inputUsername, profile, password)
+	if err != nil {
+		report.BindError = err
+
+		return report, nil
+	}
+	defer unbindAndClose(userConn)
+
+	report.Authenticated = true
+	report.Transport = p.transportSecurity(userConn)
+
+	// The failure is logged with the step, the report only tells the identity when the LDAP server returned it.
+	if p.configuration.WhoAmI {
+		report.AuthzID, _ = p.whoAmI(ctx, userConn)
+	}
+
+	return report, nil
+}
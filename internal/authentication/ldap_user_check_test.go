@@ -0,0 +1,199 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// userCheckTestConfiguration is the configuration of the provider of the user check tests.
+var userCheckTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	URL:                  "ldap://127.0.0.1:389",
+	User:                 "cn=admin,dc=example,dc=com",
+	Password:             "password",
+	UsernameAttribute:    "uid",
+	MailAttribute:        "mail",
+	DisplayNameAttribute: "displayname",
+	UsersFilter:          "(uid={input})",
+	GroupsFilter:         "(member={dn})",
+	GroupNameAttribute:   "cn",
+	BaseDN:               "dc=example,dc=com",
+}
+
+func userCheckTestSearchResult() *ldap.SearchResult {
+	return &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: "uid=john,dc=example,dc=com",
+				Attributes: []*ldap.EntryAttribute{
+					{
+						Name:   "displayname",
+						Values: []string{"John Doe"},
+					},
+					{
+						Name:   "mail",
+						Values: []string{"john@example.com"},
+					},
+					{
+						Name:   "uid",
+						Values: []string{"john"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestShouldReportResolvedUserAndSuccessfulBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+			Return(createSearchResultWithAttributeValues("admins", "dev"), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("secret")).
+			Return(nil),
+		mockConn.EXPECT().
+			Close().Times(2),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+
+	report, err := ldapClient.TestUser("john", "secret")
+	require.NoError(t, err)
+
+	assert.Equal(t, &LDAPUserTestReport{
+		UsersFilter:   "(uid=john)",
+		GroupsFilter:  "(member=uid=john,dc=example,dc=com)",
+		DN:            "uid=john,dc=example,dc=com",
+		Username:      "john",
+		Groups:        []string{"admins", "dev"},
+		Authenticated: true,
+		Transport:     &TransportSecurity{Transport: TransportPlaintext},
+	}, report)
+}
+
+func TestShouldReportFailedBindWithoutError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("wrong")).
+			Return(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))),
+		mockConn.EXPECT().
+			Close().
+			Times(2),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil)
+
+	report, err := ldapClient.TestUser("john", "wrong")
+	require.NoError(t, err)
+
+	assert.Equal(t, "uid=john,dc=example,dc=com", report.DN)
+	assert.Equal(t, []string{"admins"}, report.Groups)
+	assert.False(t, report.Authenticated)
+	assert.True(t, errors.Is(report.BindError, ErrInvalidCredentials))
+}
+
+func TestShouldSkipBindWhenPasswordIsEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	report, err := ldapClient.TestUser("john", "")
+	require.NoError(t, err)
+
+	assert.False(t, report.Authenticated)
+	assert.NoError(t, report.BindError)
+}
+
+func TestShouldReportComputedUsersFilterWhenUserIsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	report, err := ldapClient.TestUser("j*hn", "secret")
+
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+	assert.Equal(t, &LDAPUserTestReport{UsersFilter: "(uid=j\\2ahn)"}, report)
+}
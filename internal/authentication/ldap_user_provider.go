@@ -1,13 +1,24 @@
 package authentication
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-ldap/ldap/v3"
-	"golang.org/x/text/encoding/unicode"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/proxy"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
@@ -16,12 +27,47 @@ import (
 
 // LDAPUserProvider is a provider using a LDAP or AD as a user database.
 type LDAPUserProvider struct {
-	configuration     schema.LDAPAuthenticationBackendConfiguration
-	tlsConfig         *tls.Config
-	dialOpts          ldap.DialOpt
-	connectionFactory LDAPConnectionFactory
-	usersDN           string
-	groupsDN          string
+	configuration         schema.LDAPAuthenticationBackendConfiguration
+	tlsConfig             *tls.Config
+	startTLSConfig        *tls.Config
+	connectionFactory     LDAPConnectionFactory
+	proxyDialer           proxy.Dialer
+	usersDN               string
+	usersDNs              []string
+	preferredUsersDN      string
+	groupsDN              string
+	groupsDNs             []string
+	usersScope            int
+	groupsScope           int
+	derefAliases          int
+	cache                 *userDetailsCache
+	mailFilter            string
+	canonicalUsersFilter  string
+	listUsersFilter       string
+	discovery             *ldapServerDiscovery
+	usernameAttributes    []string
+	mailAttributes        []string
+	displayNameAttributes []string
+	groupNameAttributes   []string
+	escapedRunes          string
+	usernameNormalization *norm.Form
+	groupNamesFilter      string
+	groupsAllowlist       []*regexp.Regexp
+	groupsDenylist        []*regexp.Regexp
+	metrics               MetricsRecorder
+	entryMapper           LDAPEntryMapper
+	retryBackoff          time.Duration
+	userBindTimeout       time.Duration
+	circuitBreaker        *ldapCircuitBreaker
+	kerberos              ldapKerberos
+	connections           ldapConnectionTracker
+
+	passwordModifyAssertion ldap.Control
+	passwordEncoder         ldapPasswordEncoder
+
+	globalCatalogTLSConfig      *tls.Config
+	globalCatalogStartTLSConfig *tls.Config
+	referralHosts               []string
 }
 
 // NewLDAPUserProvider creates a new instance of LDAPUserProvider.
@@ -30,26 +76,111 @@ func NewLDAPUserProvider(configuration schema.LDAPAuthenticationBackendConfigura
 		configuration.TLS = schema.DefaultLDAPAuthenticationBackendConfiguration.TLS
 	}
 
-	tlsConfig := utils.NewTLSConfig(configuration.TLS, tls.VersionTLS12, certPool)
+	setLDAPImplementationDefaults(&configuration)
 
-	var dialOpts ldap.DialOpt
+	tlsConfig := newLDAPTLSConfig(configuration.TLS, certPool)
 
-	if tlsConfig != nil {
-		dialOpts = ldap.DialWithTLSConfig(tlsConfig)
+	// StartTLS uses the tls section unless it has TLS options of its own.
+	startTLSConfig := tlsConfig
+
+	if configuration.StartTLSConfig != nil {
+		startTLSConfig = newLDAPTLSConfig(configuration.StartTLSConfig, certPool)
 	}
 
 	provider := &LDAPUserProvider{
 		configuration:     configuration,
 		tlsConfig:         tlsConfig,
-		dialOpts:          dialOpts,
+		startTLSConfig:    startTLSConfig,
 		connectionFactory: NewLDAPConnectionFactoryImpl(),
+		metrics:           noopMetricsRecorder{},
+		retryBackoff:      ldapRetryBackoff,
 	}
 
 	provider.parseDynamicConfiguration()
 
+	if configuration.WarmUp.Enabled {
+		provider.enableTLSSessionResumption()
+	}
+
 	return provider
 }
 
+// setLDAPImplementationDefaults sets the attributes left empty to the well-known attributes of the implementation so
+// only the attributes differing from them have to be configured. The validator sets the same defaults, this covers the
+// providers created from a configuration which has not been through the validator.
+func setLDAPImplementationDefaults(configuration *schema.LDAPAuthenticationBackendConfiguration) {
+	defaults := schema.DefaultLDAPAuthenticationBackendConfiguration
+	if configuration.Implementation == schema.LDAPImplementationActiveDirectory {
+		defaults = schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration
+	}
+
+	if configuration.UsernameAttribute == "" {
+		configuration.UsernameAttribute = defaults.UsernameAttribute
+	}
+
+	if configuration.MailAttribute == "" {
+		configuration.MailAttribute = defaults.MailAttribute
+	}
+
+	if configuration.DisplayNameAttribute == "" {
+		configuration.DisplayNameAttribute = defaults.DisplayNameAttribute
+	}
+
+	if configuration.GroupNameAttribute == "" {
+		configuration.GroupNameAttribute = defaults.GroupNameAttribute
+	}
+
+	if configuration.StableIDAttribute == "" {
+		configuration.StableIDAttribute = defaults.StableIDAttribute
+	}
+
+	if configuration.DNAttribute == "" {
+		configuration.DNAttribute = defaults.DNAttribute
+	}
+
+	if configuration.MemberOfAttribute == "" {
+		configuration.MemberOfAttribute = defaults.MemberOfAttribute
+	}
+}
+
+// ldapJoinDN joins the DN relative to the base DN with the base DN once the stray whitespaces and commas around them are
+// trimmed. A malformed DN is logged since every search in it would be rejected by the LDAP server.
+func ldapJoinDN(name string, relativeDN string, baseDN string) string {
+	dn := utils.TrimDN(baseDN)
+
+	if relativeDN = utils.TrimDN(relativeDN); relativeDN != "" {
+		dn = relativeDN + "," + dn
+	}
+
+	if _, err := ldap.ParseDN(dn); err != nil {
+		logging.Logger().Errorf("The LDAP %s %s is not a valid DN. Cause: %s", name, dn, err)
+	}
+
+	return dn
+}
+
+// newLDAPTLSConfig generates the TLS configuration of the connections to the LDAP server along with the client
+// certificate if any. The certificate has already been validated so an error loading it is only logged.
+func newLDAPTLSConfig(config *schema.TLSConfig, certPool *x509.CertPool) *tls.Config {
+	tlsConfig := utils.NewTLSConfig(config, tls.VersionTLS12, certPool)
+
+	certificates, err := utils.NewTLSCertificates(config)
+	if err != nil {
+		logging.Logger().Errorf("Unable to load the client certificate of the LDAP server. Cause: %s", err)
+	}
+
+	tlsConfig.Certificates = certificates
+
+	return tlsConfig
+}
+
+// setLDAPTLSServerName sets the server name of the TLS configuration unless one is configured.
+func setLDAPTLSServerName(tlsConfig *tls.Config, serverName string) {
+	if tlsConfig != nil && tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = serverName
+	}
+}
+
 // NewLDAPUserProviderWithFactory creates a new instance of LDAPUserProvider with existing factory.
 func NewLDAPUserProviderWithFactory(configuration schema.LDAPAuthenticationBackendConfiguration, certPool *x509.CertPool, connectionFactory LDAPConnectionFactory) *LDAPUserProvider {
 	provider := NewLDAPUserProvider(configuration, certPool)
@@ -58,6 +189,22 @@ func NewLDAPUserProviderWithFactory(configuration schema.LDAPAuthenticationBacke
 	return provider
 }
 
+// SetVerifyConnection sets a custom verification of the TLS connections to the LDAP server, for instance to check an
+// extension of the certificate of the server. The verification runs after the verification of the certificate chain,
+// of the stapled OCSP response and of the pins when configured, and fails the handshake when it returns an error.
+func (p *LDAPUserProvider) SetVerifyConnection(verify func(state tls.ConnectionState) error) {
+	seen := make(map[*tls.Config]bool)
+
+	for _, tlsConfig := range []*tls.Config{p.tlsConfig, p.startTLSConfig, p.globalCatalogTLSConfig, p.globalCatalogStartTLSConfig} {
+		if tlsConfig == nil || seen[tlsConfig] {
+			continue
+		}
+
+		seen[tlsConfig] = true
+		tlsConfig.VerifyConnection = utils.ChainVerifyConnection(tlsConfig.VerifyConnection, verify)
+	}
+}
+
 func (p *LDAPUserProvider) parseDynamicConfiguration() {
 	logger := logging.Logger() // Deprecated: This is temporary for deprecation notice purposes. TODO: Remove in 4.28.
 
@@ -82,244 +229,1863 @@ func (p *LDAPUserProvider) parseDynamicConfiguration() {
 		p.configuration.GroupsFilter = strings.ReplaceAll(p.configuration.GroupsFilter, "{1}", "{username}")
 	}
 
-	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{username_attribute}", p.configuration.UsernameAttribute)
-	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{mail_attribute}", p.configuration.MailAttribute)
-	p.configuration.UsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{display_name_attribute}", p.configuration.DisplayNameAttribute)
+	// The users filters of Active Directory are restricted to the user accounts unless the operator opts out, for
+	// instance to match the contacts or the managed service accounts.
+	if p.configuration.Implementation == schema.LDAPImplementationActiveDirectory && !p.configuration.DisableUsersFilterConstraint {
+		p.configuration.UsersFilter = adConstrainUsersFilter(p.configuration.UsersFilter)
 
-	if p.configuration.AdditionalUsersDN != "" {
-		p.usersDN = p.configuration.AdditionalUsersDN + "," + p.configuration.BaseDN
-	} else {
-		p.usersDN = p.configuration.BaseDN
+		if p.configuration.ListUsersFilter != "" {
+			p.configuration.ListUsersFilter = adConstrainUsersFilter(p.configuration.ListUsersFilter)
+		}
 	}
 
-	if p.configuration.AdditionalGroupsDN != "" {
-		p.groupsDN = p.configuration.AdditionalGroupsDN + "," + p.configuration.BaseDN
-	} else {
-		p.groupsDN = p.configuration.BaseDN
+	// The disabled users are excluded by the searches themselves rather than checked once their entry is returned.
+	p.configuration.UsersFilter = p.configuration.ExcludeDisabledUsersFilter(p.configuration.UsersFilter)
+
+	if p.configuration.ListUsersFilter != "" {
+		p.configuration.ListUsersFilter = p.configuration.ExcludeDisabledUsersFilter(p.configuration.ListUsersFilter)
+	}
+
+	// The admin connections bind with the identity of the client certificate or of the Kerberos principal, which is
+	// requested by an empty user.
+	if p.configuration.AuthMethod == schema.LDAPAuthMethodExternal || p.configuration.AuthMethod == schema.LDAPAuthMethodGSSAPI {
+		p.configuration.User, p.configuration.Password = "", ""
+	}
+
+	attributes := []string{
+		"{login_attribute}", p.configuration.LoginAttribute,
+		"{username_attribute}", p.configuration.UsernameAttribute,
+		"{mail_attribute}", p.configuration.MailAttribute,
+		"{display_name_attribute}", p.configuration.DisplayNameAttribute,
+	}
+
+	// The custom placeholders are replaced by their attribute the same way, for instance {employee_id} by employeeID.
+	for _, placeholder := range p.configuration.AttributePlaceholders {
+		attributes = append(attributes, "{"+placeholder.Name+"}", placeholder.Attribute)
+	}
+
+	attributesReplacer := strings.NewReplacer(attributes...)
+
+	// The users logging in with the login attribute are looked up with their canonical username afterwards, for instance
+	// when their details are refreshed, so the users filter matching the username attribute is kept as a fallback.
+	if p.configuration.LoginAttribute != "" && p.configuration.LoginAttribute != p.configuration.UsernameAttribute &&
+		strings.Contains(p.configuration.UsersFilter, "{login_attribute}") {
+		p.canonicalUsersFilter = attributesReplacer.Replace(
+			strings.ReplaceAll(p.configuration.UsersFilter, "{login_attribute}", p.configuration.UsernameAttribute))
+	}
+
+	p.configuration.UsersFilter = attributesReplacer.Replace(p.configuration.UsersFilter)
+
+	p.usernameNormalization = ldapUsernameNormalizationForm(p.configuration.UsernameNormalization)
+
+	p.escapedRunes = p.configuration.EscapedCharacters
+	if p.escapedRunes == "" {
+		p.escapedRunes = specialLDAPRunes
+	}
+
+	p.usernameAttributes = append([]string{p.configuration.UsernameAttribute}, p.configuration.UsernameAttributeFallbacks...)
+	p.mailAttributes = append([]string{p.configuration.MailAttribute}, p.configuration.MailAttributeFallbacks...)
+	p.displayNameAttributes = append([]string{p.configuration.DisplayNameAttribute}, p.configuration.DisplayNameAttributeFallbacks...)
+	p.groupNameAttributes = append([]string{p.configuration.GroupNameAttribute}, p.configuration.GroupNameAttributeFallbacks...)
+
+	p.mailFilter = "(" + p.configuration.MailAttribute + "={input})"
+
+	if len(p.mailAttributes) > 1 {
+		p.mailFilter = "(|(" + strings.Join(p.mailAttributes, "={input})(") + "={input}))"
+	}
+
+	// The mail filter is restricted like the users filter, so the contacts and the disabled users sharing the mail of a
+	// user are neither returned nor make the email ambiguous.
+	if p.configuration.Implementation == schema.LDAPImplementationActiveDirectory && !p.configuration.DisableUsersFilterConstraint {
+		p.mailFilter = adConstrainUsersFilter(p.mailFilter)
+	}
+
+	p.mailFilter = p.configuration.ExcludeDisabledUsersFilter(p.mailFilter)
+
+	// The users filter matching any input matches all the users when no filter is dedicated to the listing.
+	p.listUsersFilter = attributesReplacer.Replace(p.configuration.ListUsersFilter)
+
+	if p.listUsersFilter == "" {
+		p.listUsersFilter = strings.ReplaceAll(p.configuration.UsersFilter, "{input}", "*")
+	}
+
+	p.usersDN = ldapJoinDN("users DN", p.configuration.AdditionalUsersDN, p.configuration.BaseDN)
+	p.groupsDN = ldapJoinDN("groups DN", p.configuration.AdditionalGroupsDN, p.configuration.BaseDN)
+	p.usersDNs = ldapJoinDNs("extra users DN", p.usersDN, p.configuration.ExtraUsersDNs, p.configuration.BaseDN)
+	p.groupsDNs = ldapJoinDNs("extra groups DN", p.groupsDN, p.configuration.ExtraGroupsDNs, p.configuration.BaseDN)
+
+	if p.configuration.PreferredUsersDN != "" {
+		p.preferredUsersDN = ldapJoinDN("preferred users DN", p.configuration.PreferredUsersDN, p.configuration.BaseDN)
+	}
+
+	// The URL has already been validated, a srv or srvs URL only contains the domain of the SRV records.
+	if u, err := url.Parse(p.configuration.URL); err == nil && u.Scheme == ldapSchemeSRV {
+		p.discovery = newLDAPServerDiscovery("ldap", u.Hostname(), ldapServerDiscoveryRefreshInterval, utils.RealClock{})
+	} else if err == nil && u.Scheme == ldapSchemeSRVS {
+		p.discovery = newLDAPServerDiscovery("ldaps", u.Hostname(), ldapServerDiscoveryRefreshInterval, utils.RealClock{})
+	} else if err == nil && u.Hostname() != "" {
+		// The server name would otherwise be derived from the address dialed by the TLS library, which keeps the
+		// brackets of the IPv6 literal addresses. The host name of the URL has none.
+		setLDAPTLSServerName(p.tlsConfig, u.Hostname())
+		setLDAPTLSServerName(p.startTLSConfig, u.Hostname())
+	}
+
+	// The proxy URL has already been validated, the dial timeout also applies to the connections to the proxy.
+	if u, err := url.Parse(p.configuration.ProxyURL); err == nil && p.configuration.ProxyURL != "" {
+		if p.proxyDialer, err = proxy.FromURL(u, &net.Dialer{Timeout: ldap.DefaultTimeout}); err != nil {
+			logging.Logger().Errorf("Unable to create the dialer of the LDAP proxy %s. Cause: %s", u.Redacted(), err)
+		}
+	}
+
+	// The global catalog is usually served by another host than the one of the URL.
+	if u, err := url.Parse(p.configuration.GlobalCatalogURL); err == nil && p.configuration.GlobalCatalogURL != "" {
+		p.globalCatalogTLSConfig = p.tlsConfig
+		p.globalCatalogStartTLSConfig = p.startTLSConfig
+
+		if p.tlsConfig != nil {
+			p.globalCatalogTLSConfig = p.tlsConfig.Clone()
+			p.globalCatalogTLSConfig.ServerName = u.Hostname()
+		}
+
+		if p.startTLSConfig != nil {
+			p.globalCatalogStartTLSConfig = p.startTLSConfig.Clone()
+			p.globalCatalogStartTLSConfig.ServerName = u.Hostname()
+		}
+	}
+
+	p.passwordEncoder = newLDAPPasswordEncoder(p.configuration.Implementation)
+
+	p.groupNamesFilter = ldapGroupNamesFilter(p.groupNameAttributes, p.configuration.GroupNamePatterns)
+	p.groupsAllowlist = compileGroupsList("groups_allowlist", p.configuration.GroupsAllowlist)
+	p.groupsDenylist = compileGroupsList("groups_denylist", p.configuration.GroupsDenylist)
+
+	p.usersScope = ldapSearchScope(p.configuration.UsersSearchScope)
+	p.groupsScope = ldapSearchScope(p.configuration.GroupsSearchScope)
+	p.derefAliases = ldapDerefAliases(p.configuration.DerefAliases)
+
+	// The duration has already been validated, an invalid or zero duration disables the cache.
+	if ttl, err := utils.ParseDurationString(p.configuration.GroupsCacheTTL); err == nil && ttl > 0 {
+		p.cache = newUserDetailsCache(ttl, utils.RealClock{})
+	}
+
+	if timeout, err := utils.ParseDurationString(p.configuration.UserBindTimeout); err == nil {
+		p.userBindTimeout = timeout
+	}
+
+	// The filter has already been validated, an invalid filter would make every password update fail.
+	if p.configuration.PasswordModifyAssertionFilter != "" {
+		if control, err := newLDAPAssertionControl(p.configuration.PasswordModifyAssertionFilter); err == nil {
+			p.passwordModifyAssertion = control
+		}
 	}
+
+	// The circuit breaker is disabled unless both the threshold and the cool-down are configured.
+	if cooldown, err := utils.ParseDurationString(p.configuration.CircuitBreakerCooldown); err == nil && cooldown > 0 && p.configuration.CircuitBreakerThreshold > 0 {
+		p.circuitBreaker = newLDAPCircuitBreaker(p.configuration.CircuitBreakerThreshold, cooldown, utils.RealClock{})
+	}
+
+	p.referralHosts = ldapReferralHosts(p.configuration)
 }
 
-func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnection, error) {
-	conn, err := p.connectionFactory.DialURL(p.configuration.URL, p.dialOpts)
-	if err != nil {
-		return nil, err
+// ldapSearchScope converts a configured search scope to the ldap scope, defaulting to the whole subtree.
+func ldapSearchScope(scope string) int {
+	switch scope {
+	case schema.LDAPSearchScopeBase:
+		return ldap.ScopeBaseObject
+	case schema.LDAPSearchScopeOne:
+		return ldap.ScopeSingleLevel
+	default:
+		return ldap.ScopeWholeSubtree
+	}
+}
+
+// ldapDerefAliases converts a configured aliases dereferencing policy to the ldap policy, defaulting to never.
+func ldapDerefAliases(derefAliases string) int {
+	switch derefAliases {
+	case schema.LDAPDerefAliasesSearching:
+		return ldap.DerefInSearching
+	case schema.LDAPDerefAliasesFinding:
+		return ldap.DerefFindingBaseObj
+	case schema.LDAPDerefAliasesAlways:
+		return ldap.DerefAlways
+	default:
+		return ldap.NeverDerefAliases
+	}
+}
+
+// ldapGroupNamesFilter builds the filter matching the groups whose name, in any of the group name attributes, matches
+// one of the patterns, where * matches any sequence of characters. The filter is empty when there are no patterns.
+func ldapGroupNamesFilter(groupNameAttributes []string, patterns []string) string {
+	if len(patterns) == 0 {
+		return ""
 	}
 
-	if p.configuration.StartTLS {
-		if err := conn.StartTLS(p.tlsConfig); err != nil {
-			return nil, err
+	filters := make([]string, 0, len(patterns)*len(groupNameAttributes))
+
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, "*")
+		for j, part := range parts {
+			parts[j] = ldap.EscapeFilter(part)
+		}
+
+		for _, attribute := range groupNameAttributes {
+			filters = append(filters, "("+attribute+"="+strings.Join(parts, "*")+")")
 		}
 	}
 
-	if err := conn.Bind(userDN, password); err != nil {
-		return nil, err
+	if len(filters) == 1 {
+		return filters[0]
 	}
 
-	return conn, nil
+	return "(|" + strings.Join(filters, "") + ")"
 }
 
-// CheckUserPassword checks if provided password matches for the given user.
-func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password string) (bool, error) {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
-	if err != nil {
-		return false, err
+// connectAdmin connects to the LDAP server and binds with the identity searching or writing the directory, either the
+// admin user or the password modify user. The user is a DN or, with Active Directory only, a UPN like user@example.com
+// or a down-level logon name like EXAMPLE\user which are passed as is as the name of the simple bind. An empty user
+// results in an anonymous bind, or a SASL EXTERNAL bind with the external auth method. The error wraps
+// ErrConnectionFailed when the LDAP server is unreachable and is the error of the bind otherwise, the callers adding
+// the operation it failed.
+func (p *LDAPUserProvider) connectAdmin(ctx context.Context, user string, password string) (LDAPConnection, error) {
+	conn, _, err := p.connectWithPasswordPolicy(ctx, user, password)
+
+	return conn, err
+}
+
+// connectSearch connects with the admin user to the server searching the users and their groups, which is the global
+// catalog when configured. The global catalog is read only, the binds of the users and the writes are always performed
+// against the URL.
+func (p *LDAPUserProvider) connectSearch(ctx context.Context) (LDAPConnection, error) {
+	if p.configuration.GlobalCatalogURL == "" {
+		return p.connectAdmin(ctx, p.configuration.User, p.configuration.Password)
 	}
-	defer conn.Close()
 
-	profile, err := p.getUserProfile(conn, inputUsername)
-	if err != nil {
-		return false, err
+	conn, _, err := p.connectURL(ctx, p.configuration.GlobalCatalogURL, p.globalCatalogTLSConfig, p.globalCatalogStartTLSConfig,
+		p.configuration.User, p.configuration.Password)
+
+	return conn, err
+}
+
+// connectWithPasswordPolicy connects and binds like connectAdmin and also returns the password policy response control
+// returned by the LDAP server for the bind, even when the bind fails. The control is nil unless the ppolicy control is
+// enabled and the LDAP server supports it.
+func (p *LDAPUserProvider) connectWithPasswordPolicy(ctx context.Context, userDN string, password string) (LDAPConnection, *ldap.ControlBeheraPasswordPolicy, error) {
+	if p.discovery == nil {
+		return p.connectURL(ctx, p.configuration.URL, p.tlsConfig, p.startTLSConfig, userDN, password)
 	}
 
-	userConn, err := p.connect(profile.DN, password)
+	urls, err := p.discovery.URLs()
 	if err != nil {
-		return false, fmt.Errorf("Authentication of user %s failed. Cause: %s", inputUsername, err)
+		return nil, nil, fmt.Errorf("%w. Cause: %s", ErrConnectionFailed, err)
 	}
-	defer userConn.Close()
 
-	return true, nil
+	// The discovered servers are tried in order until one of them is reachable.
+	for _, address := range urls {
+		tlsConfig, startTLSConfig := p.discoveredServerTLSConfigs(address)
+
+		var (
+			conn   LDAPConnection
+			policy *ldap.ControlBeheraPasswordPolicy
+		)
+
+		conn, policy, err = p.connectURL(ctx, address, tlsConfig, startTLSConfig, userDN, password)
+		if err == nil || !errors.Is(err, ErrConnectionFailed) {
+			return conn, policy, err
+		}
+
+		operationLogger(ctx).Debugf("Unable to connect to the discovered LDAP server %s, trying the next one. Cause: %s", address, err)
+	}
+
+	return nil, nil, err
 }
 
-func (p *LDAPUserProvider) ldapEscape(inputUsername string) string {
-	inputUsername = ldap.EscapeFilter(inputUsername)
-	for _, c := range specialLDAPRunes {
-		inputUsername = strings.ReplaceAll(inputUsername, string(c), fmt.Sprintf("\\%c", c))
+// discoveredServerTLSConfigs returns the TLS configurations of the connections to a discovered LDAP server, which
+// verify the certificate of the server against its host name unless a server name is configured.
+func (p *LDAPUserProvider) discoveredServerTLSConfigs(address string) (tlsConfig *tls.Config, startTLSConfig *tls.Config) {
+	tlsConfig, startTLSConfig = p.tlsConfig.Clone(), p.startTLSConfig.Clone()
+
+	if u, err := url.Parse(address); err == nil {
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+
+		if startTLSConfig.ServerName == "" {
+			startTLSConfig.ServerName = u.Hostname()
+		}
 	}
 
-	return inputUsername
+	return tlsConfig, startTLSConfig
 }
 
-type ldapUserProfile struct {
-	DN          string
-	Emails      []string
-	DisplayName string
-	Username    string
+// dial connects to the LDAP server of the URL, through the proxy when one is configured. StartTLS is negotiated over
+// the tunnel of the proxy like over a direct connection.
+func (p *LDAPUserProvider) dial(ctx context.Context, address string, tlsConfig *tls.Config) (LDAPConnection, error) {
+	if p.proxyDialer != nil {
+		return p.connectionFactory.DialURLWithProxy(ctx, address, p.proxyDialer, tlsConfig)
+	}
+
+	var dialOpts ldap.DialOpt
+
+	// The connections over a Unix domain socket are never encrypted.
+	if tlsConfig != nil && !isLDAPIURL(address) {
+		dialOpts = ldap.DialWithTLSConfig(tlsConfig)
+	}
+
+	// The dial must not outlive the deadline of the context.
+	if deadline, ok := ctx.Deadline(); ok {
+		dialOpts = dialOptsWithDeadline(dialOpts, deadline)
+	}
+
+	return p.connectionFactory.DialURL(address, dialOpts)
 }
 
-func (p *LDAPUserProvider) resolveUsersFilter(userFilter string, inputUsername string) string {
-	inputUsername = p.ldapEscape(inputUsername)
+// isLDAPIURL returns true when the URL is the one of an LDAP server listening on a Unix domain socket.
+func isLDAPIURL(address string) bool {
+	return strings.HasPrefix(address, ldapSchemeLDAPI+"://")
+}
 
-	// The {input} placeholder is replaced by the users username input.
-	userFilter = strings.ReplaceAll(userFilter, "{input}", inputUsername)
+// connectURL connects and binds to the LDAP server of the URL, failing fast while the circuit breaker of the LDAP
+// server is open.
+func (p *LDAPUserProvider) connectURL(ctx context.Context, address string, tlsConfig *tls.Config, startTLSConfig *tls.Config, userDN string, password string) (conn LDAPConnection, policy *ldap.ControlBeheraPasswordPolicy, err error) {
+	err = p.connectURLWithCircuitBreaker(ctx, address, func() (err error) {
+		conn, policy, err = p.dialAndBind(ctx, address, tlsConfig, startTLSConfig, userDN, password)
 
-	return userFilter
+		return err
+	})
+
+	return conn, policy, err
 }
 
-func (p *LDAPUserProvider) getUserProfile(conn LDAPConnection, inputUsername string) (*ldapUserProfile, error) {
-	userFilter := p.resolveUsersFilter(p.configuration.UsersFilter, inputUsername)
-	logging.Logger().Tracef("Computed user filter is %s", userFilter)
+func (p *LDAPUserProvider) dialAndBind(ctx context.Context, address string, tlsConfig *tls.Config, startTLSConfig *tls.Config, userDN string, password string) (LDAPConnection, *ldap.ControlBeheraPasswordPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if p.connections.isClosed() {
+		return nil, nil, ErrProviderClosed
+	}
 
-	attributes := []string{"dn",
-		p.configuration.DisplayNameAttribute,
-		p.configuration.MailAttribute,
-		p.configuration.UsernameAttribute}
+	start := time.Now()
 
-	// Search for the given username.
-	searchRequest := ldap.NewSearchRequest(
-		p.usersDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		1, 0, false, userFilter, attributes, nil,
-	)
+	// The dial and the bind are bounded by the connect timeout of the call when there is one, while the connection
+	// itself lives as long as the context.
+	connectCtx, cancel := withConnectDeadline(ctx)
+	defer cancel()
+
+	conn, err := p.dial(connectCtx, address, tlsConfig)
+	logOperationStep(ctx, ldapStepDial, start, logrus.Fields{"url": address}, err)
 
-	sr, err := conn.Search(searchRequest)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot find user DN of user %s. Cause: %s", inputUsername, err)
+		return nil, nil, fmt.Errorf("%w %s. Cause: %s", ErrConnectionFailed, address, err)
 	}
 
-	if len(sr.Entries) == 0 {
-		return nil, ErrUserNotFound
-	}
+	conn = newLDAPContextConnection(ctx, conn)
 
-	if len(sr.Entries) > 1 {
-		return nil, fmt.Errorf("Multiple users %s found", inputUsername)
+	stop := closeOnDone(connectCtx, conn)
+
+	if p.configuration.StartTLS && !isLDAPIURL(address) {
+		if err := conn.StartTLS(startTLSConfig); err != nil {
+			stop()
+			conn.Close()
+
+			return nil, nil, fmt.Errorf("%w %s with StartTLS. Cause: %s", ErrConnectionFailed, address, connectError(connectCtx, err))
+		}
 	}
 
-	userProfile := ldapUserProfile{
-		DN: sr.Entries[0].DN,
+	start = time.Now()
+
+	var policy *ldap.ControlBeheraPasswordPolicy
+
+	// An empty user DN is only used by the admin connection and represents an anonymous bind, a SASL EXTERNAL bind with
+	// the client certificate when the external auth method is configured or a SASL GSSAPI bind with the Kerberos
+	// principal when the gssapi auth method is configured.
+	switch {
+	case userDN == "" && p.configuration.AuthMethod == schema.LDAPAuthMethodExternal:
+		err = conn.ExternalBind()
+	case userDN == "" && p.configuration.AuthMethod == schema.LDAPAuthMethodGSSAPI:
+		err = p.gssapiBind(conn, address)
+	case userDN == "":
+		err = conn.UnauthenticatedBind("")
+	case p.configuration.PPolicyControl:
+		policy, err = bindWithPasswordPolicy(conn, userDN, password)
+	default:
+		err = conn.Bind(userDN, password)
 	}
 
-	for _, attr := range sr.Entries[0].Attributes {
-		if attr.Name == p.configuration.DisplayNameAttribute {
-			userProfile.DisplayName = attr.Values[0]
-		}
+	stop()
 
-		if attr.Name == p.configuration.MailAttribute {
-			userProfile.Emails = attr.Values
-		}
+	// The connection closed by the connect timeout during the bind is reported with a distinct error, the LDAP server
+	// answered the dial so it is neither unreachable nor rejecting the bind.
+	if connectCtx.Err() != nil && ctx.Err() == nil {
+		err = fmt.Errorf("%w %s. Cause: %s", ErrBindTimeout, address, connectError(connectCtx, err))
+	}
 
-		if attr.Name == p.configuration.UsernameAttribute {
-			if len(attr.Values) != 1 {
-				return nil, fmt.Errorf("User %s cannot have multiple value for attribute %s",
-					inputUsername, p.configuration.UsernameAttribute)
-			}
+	logOperationStep(ctx, ldapStepBind, start, logrus.Fields{"url": address, "dn": userDN}, err)
+	p.recordOperation(ldapMetricBind, start, err)
 
-			userProfile.Username = attr.Values[0]
-		}
+	if err != nil {
+		conn.Close()
+
+		return nil, policy, err
 	}
 
-	if userProfile.DN == "" {
-		return nil, fmt.Errorf("No DN has been found for user %s", inputUsername)
+	// The bound connection is held by the provider until it is closed so it is unbound when the provider is closed.
+	if conn, err = p.connections.track(conn); err != nil {
+		return nil, nil, err
 	}
 
-	return &userProfile, nil
+	return conn, policy, nil
 }
 
-func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ldapUserProfile) (string, error) { //nolint:unparam
-	inputUsername = p.ldapEscape(inputUsername)
+// unbindAndClose ends the session of the connection with an unbind request before closing it as RFC4511 section 4.3
+// asks. The connections failing to bind are closed without it since no session was established.
+func unbindAndClose(conn LDAPConnection) {
+	_ = conn.Unbind()
 
-	// The {input} placeholder is replaced by the users username input.
-	groupFilter := strings.ReplaceAll(p.configuration.GroupsFilter, "{input}", inputUsername)
+	conn.Close()
+}
 
-	if profile != nil {
-		groupFilter = strings.ReplaceAll(groupFilter, "{username}", ldap.EscapeFilter(profile.Username))
-		groupFilter = strings.ReplaceAll(groupFilter, "{dn}", ldap.EscapeFilter(profile.DN))
+// bindWithPasswordPolicy binds with the password policy request control and returns the password policy response
+// control of the LDAP server if any.
+func bindWithPasswordPolicy(conn LDAPConnection, userDN string, password string) (*ldap.ControlBeheraPasswordPolicy, error) {
+	result, err := conn.SimpleBind(&ldap.SimpleBindRequest{
+		Username: userDN,
+		Password: password,
+		Controls: []ldap.Control{ldap.NewControlBeheraPasswordPolicy()},
+	})
+
+	if result == nil {
+		return nil, err
 	}
 
-	return groupFilter, nil
+	policy, _ := ldap.FindControl(result.Controls, ldap.ControlTypeBeheraPasswordPolicy).(*ldap.ControlBeheraPasswordPolicy)
+
+	return policy, err
 }
 
-// GetDetails retrieve the groups a user belongs to.
-func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error) {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
-	if err != nil {
-		return nil, err
+// search performs the search request and handles the referrals returned by the LDAP server.
+func (p *LDAPUserProvider) search(ctx context.Context, conn LDAPConnection, searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	return p.searchWithPaging(ctx, conn, searchRequest, 0)
+}
+
+// searchWithPaging performs the search request with the paging control unless the paging size is 0 and handles the
+// referrals returned by the LDAP server. The entries received before an error are returned along with the error, for
+// instance when the size limit is exceeded.
+func (p *LDAPUserProvider) searchWithPaging(ctx context.Context, conn LDAPConnection, searchRequest *ldap.SearchRequest, pagingSize uint32) (sr *ldap.SearchResult, err error) {
+	start := time.Now()
+
+	if pagingSize > 0 {
+		sr, err = conn.SearchWithPaging(searchRequest, pagingSize)
+	} else {
+		sr, err = conn.Search(searchRequest)
 	}
-	defer conn.Close()
 
-	profile, err := p.getUserProfile(conn, inputUsername)
+	logOperationStep(ctx, ldapStepSearch, start, searchFields(searchRequest, sr), err)
+
 	if err != nil {
+		// The partial results returned along with an error, like a size limit exceeded, are also bounded.
+		if sr != nil {
+			if sizeErr := p.enforceSizeLimits(ctx, searchRequest, sr); sizeErr != nil {
+				return nil, sizeErr
+			}
+		}
+
+		return sr, err
+	}
+
+	p.handleReferrals(ctx, searchRequest, sr)
+
+	if err = p.enforceSizeLimits(ctx, searchRequest, sr); err != nil {
 		return nil, err
 	}
 
-	groupsFilter, err := p.resolveGroupsFilter(inputUsername, profile)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to create group filter for user %s. Cause: %s", inputUsername, err)
+	return sr, nil
+}
+
+// searchFields returns the fields describing a search in the logs.
+func searchFields(searchRequest *ldap.SearchRequest, sr *ldap.SearchResult) logrus.Fields {
+	fields := logrus.Fields{
+		"base_dn": searchRequest.BaseDN,
+		"filter":  searchRequest.Filter,
 	}
 
-	logging.Logger().Tracef("Computed groups filter is %s", groupsFilter)
+	if sr != nil {
+		fields["entry_count"] = len(sr.Entries)
+	}
 
-	// Search for the given username.
-	searchGroupRequest := ldap.NewSearchRequest(
-		p.groupsDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
-		0, 0, false, groupsFilter, []string{p.configuration.GroupNameAttribute}, nil,
-	)
+	return fields
+}
 
-	sr, err := conn.Search(searchGroupRequest)
+// handleReferrals either ignores the referrals of a search result or, when configured to follow them, appends the
+// entries found by the referred servers to the search result. Referrals returned by the referred servers are not
+// followed.
+func (p *LDAPUserProvider) handleReferrals(ctx context.Context, searchRequest *ldap.SearchRequest, sr *ldap.SearchResult) {
+	if len(sr.Referrals) == 0 {
+		return
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("Unable to retrieve groups of user %s. Cause: %s", inputUsername, err)
+	if !p.configuration.FollowReferrals {
+		operationLogger(ctx).Tracef("Ignoring referrals returned by the LDAP server: %s", strings.Join(sr.Referrals, ", "))
+		return
 	}
 
-	groups := make([]string, 0)
+	for _, referral := range sr.Referrals {
+		entries, err := p.searchReferral(ctx, referral, searchRequest)
+		if err != nil {
+			operationLogger(ctx).Warnf("Unable to follow the LDAP referral %s. Cause: %s", referral, err)
+			continue
+		}
 
-	for _, res := range sr.Entries {
-		if len(res.Attributes) == 0 {
-			logging.Logger().Warningf("No groups retrieved from LDAP for user %s", inputUsername)
-			break
+		sr.Entries = append(sr.Entries, entries...)
+	}
+}
+
+// ldapReferralHosts returns the hosts the referrals may be followed to, the hosts of the URL and of the global catalog
+// URL, the domain of the SRV records and the configured referral hosts.
+func ldapReferralHosts(configuration schema.LDAPAuthenticationBackendConfiguration) (hosts []string) {
+	for _, address := range []string{configuration.URL, configuration.GlobalCatalogURL} {
+		if u, err := url.Parse(address); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(u.Hostname()))
 		}
-		// Append all values of the document. Normally there should be only one per document.
-		groups = append(groups, res.Attributes[0].Values...)
 	}
 
-	return &UserDetails{
-		Username:    profile.Username,
-		DisplayName: profile.DisplayName,
-		Emails:      profile.Emails,
-		Groups:      groups,
-	}, nil
+	for _, host := range configuration.ReferralHosts {
+		hosts = append(hosts, strings.ToLower(strings.TrimPrefix(host, ".")))
+	}
+
+	return hosts
 }
 
-// UpdatePassword update the password of the given user.
-func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
+// isReferralHostAllowed returns true when the host is one of the referral hosts or a subdomain of one of them.
+func (p *LDAPUserProvider) isReferralHostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, allowed := range p.referralHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkReferral verifies the referral targets an allowed host over TLS, either with an ldaps URL or with StartTLS,
+// so the configured credentials are neither sent to an arbitrary server nor sent in clear text.
+func (p *LDAPUserProvider) checkReferral(referralURL *url.URL) error {
+	switch {
+	case referralURL.Scheme == "ldaps":
+	case referralURL.Scheme == "ldap" && p.configuration.StartTLS:
+	default:
+		return fmt.Errorf("the referral does not use TLS, the %s scheme is only followed when start_tls is enabled", referralURL.Scheme)
+	}
+
+	if !p.isReferralHostAllowed(referralURL.Hostname()) {
+		return fmt.Errorf("the host %s is not one of the referral hosts", referralURL.Hostname())
+	}
+
+	return nil
+}
+
+func (p *LDAPUserProvider) searchReferral(ctx context.Context, referral string, searchRequest *ldap.SearchRequest) ([]*ldap.Entry, error) {
+	referralURL, err := url.Parse(referral)
 	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+		return nil, err
+	}
+
+	if err = p.checkReferral(referralURL); err != nil {
+		return nil, err
 	}
-	defer conn.Close()
 
-	profile, err := p.getUserProfile(conn, inputUsername)
+	tlsConfig, startTLSConfig := p.tlsConfig.Clone(), p.startTLSConfig.Clone()
+	tlsConfig.ServerName = referralURL.Hostname()
+	startTLSConfig.ServerName = referralURL.Hostname()
 
+	conn, _, err := p.connectURL(ctx, fmt.Sprintf("%s://%s", referralURL.Scheme, referralURL.Host),
+		tlsConfig, startTLSConfig, p.configuration.User, p.configuration.Password)
 	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+		return nil, err
 	}
+	defer unbindAndClose(conn)
 
-	modifyRequest := ldap.NewModifyRequest(profile.DN, nil)
+	referralRequest := *searchRequest
 
-	switch p.configuration.Implementation {
-	case schema.LDAPImplementationActiveDirectory:
-		utf16 := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
-		// The password needs to be enclosed in quotes
-		// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-adts/6e803168-f140-4d23-b2d3-c3a8ab5917d2
-		pwdEncoded, _ := utf16.NewEncoder().String(fmt.Sprintf("\"%s\"", newPassword))
-		modifyRequest.Replace("unicodePwd", []string{pwdEncoded})
-	default:
-		modifyRequest.Replace("userPassword", []string{newPassword})
+	if baseDN := strings.TrimPrefix(referralURL.Path, "/"); baseDN != "" {
+		referralRequest.BaseDN = baseDN
 	}
 
-	err = conn.Modify(modifyRequest)
+	start := time.Now()
+
+	sr, err := conn.Search(&referralRequest)
+
+	fields := searchFields(&referralRequest, sr)
+	fields["referral"] = referral
+
+	logOperationStep(ctx, ldapStepSearch, start, fields, err)
 
 	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+		return nil, err
 	}
 
-	return nil
+	return sr.Entries, nil
+}
+
+// StartupCheck verifies the LDAP server is reachable with the configured credentials and that the configured
+// base DNs and users filter are usable.
+func (p *LDAPUserProvider) StartupCheck() error {
+	ctx := newOperationContext(context.Background(), "startup_check", "")
+
+	if !strings.Contains(p.configuration.UsersFilter, "{input}") {
+		return fmt.Errorf("The users filter %s does not contain the {input} placeholder", p.configuration.UsersFilter)
+	}
+
+	conn, err := p.connectAdmin(ctx, p.configuration.User, p.configuration.Password)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to the LDAP server with user %s. Cause: %w", p.configuration.User, err)
+	}
+	defer unbindAndClose(conn)
+
+	if p.configuration.WhoAmI {
+		p.checkWhoAmI(ctx, conn, p.configuration.User)
+	}
+
+	var usersDNEntries int
+
+	baseDNs := append(append([]string{}, p.usersDNs...), p.groupsDNs...)
+
+	for i, baseDN := range baseDNs {
+		searchRequest := ldap.NewSearchRequest(
+			baseDN, ldap.ScopeBaseObject, p.derefAliases,
+			1, 0, false, "(objectClass=*)", []string{"1.1"}, nil,
+		)
+
+		sr, err := conn.Search(searchRequest)
+		if err != nil {
+			return fmt.Errorf("Unable to find the base DN %s. Cause: %s", baseDN, err)
+		}
+
+		if i == 0 {
+			usersDNEntries = len(sr.Entries)
+		}
+	}
+
+	// An empty directory is valid so the diagnostic doesn't prevent the startup.
+	if err := p.probeUsersSearch(conn, usersDNEntries); err != nil {
+		operationLogger(ctx).Warnf("%s, every login will fail with user not found", err)
+	}
+
+	if p.configuration.WarmUp.Enabled {
+		return p.warmUp(ctx)
+	}
+
+	return nil
+}
+
+// Healthcheck verifies the LDAP server answers a search of the root DSE over a short-lived connection.
+func (p *LDAPUserProvider) Healthcheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ldapHealthcheckTimeout)
+	defer cancel()
+
+	ctx = newOperationContext(ctx, "healthcheck", "")
+
+	conn, err := p.connectAdmin(ctx, p.configuration.User, p.configuration.Password)
+	if err != nil {
+		return err
+	}
+	defer unbindAndClose(conn)
+
+	if p.whoAmIEnabled(ctx) {
+		p.checkWhoAmI(ctx, conn, p.configuration.User)
+	}
+
+	// The 1.1 attribute requests no attributes at all which keeps the response as small as possible.
+	searchRequest := ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		1, int(ldapHealthcheckTimeout.Seconds()), false, "(objectClass=*)", []string{"1.1"}, nil,
+	)
+
+	start := time.Now()
+
+	sr, err := conn.Search(searchRequest)
+	logOperationStep(ctx, ldapStepSearch, start, searchFields(searchRequest, sr), err)
+
+	if err != nil {
+		return fmt.Errorf("Unable to search the root DSE. Cause: %s", err)
+	}
+
+	return nil
+}
+
+// CheckUserPassword checks if provided password matches for the given user.
+func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password string) (bool, error) {
+	return p.CheckUserPasswordWithContext(context.Background(), inputUsername, password)
+}
+
+// CheckUserPasswordWithContext checks if provided password matches for the given user, the LDAP operations are
+// aborted when the context is cancelled or its deadline is exceeded.
+func (p *LDAPUserProvider) CheckUserPasswordWithContext(ctx context.Context, inputUsername string, password string) (bool, error) {
+	if _, err := p.CheckUserPasswordWithPasswordPolicy(ctx, inputUsername, password); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CheckUserPasswordWithPasswordPolicy checks if provided password matches for the given user and returns the warnings
+// of the password policy of the LDAP server, nil when there is none. The error wraps ErrPasswordExpired or
+// ErrAccountLocked when the password policy of the LDAP server rejects the bind. The password policy is only reported
+// when the ppolicy control is enabled. With Active Directory, the error wraps ErrInvalidCredentials,
+// ErrAccountDisabled, ErrAccountLocked, ErrPasswordExpired or ErrPasswordMustChange according to the sub-error code of
+// the failed bind.
+func (p *LDAPUserProvider) CheckUserPasswordWithPasswordPolicy(ctx context.Context, inputUsername string, password string) (*PasswordPolicyWarnings, error) {
+	warnings, _, err := p.checkUserPassword(ctx, inputUsername, password)
+
+	return warnings, err
+}
+
+// CheckUserPasswordWithTransportSecurity checks if provided password matches for the given user and returns the
+// transport security of the connection the password was sent over, which tells whether it was sent over LDAPS,
+// StartTLS, a Unix domain socket or in plaintext along with the negotiated TLS version.
+func (p *LDAPUserProvider) CheckUserPasswordWithTransportSecurity(ctx context.Context, inputUsername string, password string) (*TransportSecurity, error) {
+	_, transport, err := p.checkUserPassword(ctx, inputUsername, password)
+
+	return transport, err
+}
+
+func (p *LDAPUserProvider) checkUserPassword(ctx context.Context, inputUsername string, password string) (*PasswordPolicyWarnings, *TransportSecurity, error) {
+	ctx = newOperationContext(ctx, "check_user_password", inputUsername)
+
+	// The DN of the user is built from the template instead of being searched, which saves the admin bind and the
+	// search of the user.
+	if p.configuration.BindDNTemplate != "" {
+		return p.checkTemplatePassword(ctx, inputUsername, password)
+	}
+
+	conn, profile, err := p.connectAndGetUserProfile(ctx, inputUsername)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unbindAndClose(conn)
+
+	return p.checkProfilePassword(ctx, inputUsername, profile, password)
+}
+
+// CheckUserPasswordAndGetDetails checks if provided password matches for the given user and retrieves their details.
+func (p *LDAPUserProvider) CheckUserPasswordAndGetDetails(inputUsername string, password string) (bool, *UserDetails, error) {
+	return p.CheckUserPasswordAndGetDetailsWithContext(context.Background(), inputUsername, password)
+}
+
+// CheckUserPasswordAndGetDetailsWithContext checks if provided password matches for the given user and retrieves their
+// details. The profile search and the groups search share a single admin connection which saves a bind compared to
+// calling CheckUserPassword and GetDetails in a row, as done on every login. The details are nil when the password
+// doesn't match. When Active Directory refuses the valid password of the user because it must be changed, the error
+// wraps ErrPasswordMustChange and the details are returned with MustChangePassword set.
+func (p *LDAPUserProvider) CheckUserPasswordAndGetDetailsWithContext(ctx context.Context, inputUsername string, password string) (bool, *UserDetails, error) {
+	ctx = newOperationContext(ctx, "check_user_password_and_get_details", inputUsername)
+
+	// The user is not searched before the bind with the DN of the template, the details are retrieved once bound.
+	if p.configuration.BindDNTemplate != "" {
+		if _, _, err := p.checkTemplatePassword(ctx, inputUsername, password); err != nil {
+			return false, nil, err
+		}
+
+		details, err := p.GetDetailsWithContext(ctx, inputUsername)
+
+		return true, details, err
+	}
+
+	conn, profile, err := p.connectAndGetUserProfile(ctx, inputUsername)
+	if err != nil {
+		return false, nil, err
+	}
+	defer unbindAndClose(conn)
+
+	warnings, _, err := p.checkProfilePassword(ctx, inputUsername, profile, password)
+	if errors.Is(err, ErrPasswordMustChange) {
+		return false, p.getMustChangePasswordDetails(ctx, conn, inputUsername, profile), err
+	}
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	details, err := p.getUserDetails(ctx, conn, inputUsername, profile)
+	if err != nil {
+		return true, nil, err
+	}
+
+	// The password policy control also reports a password reset by an administrator.
+	if warnings != nil && warnings.MustChangePassword {
+		details.MustChangePassword = true
+	}
+
+	if p.cache != nil {
+		p.cache.Set(p.cacheKey(inputUsername), details)
+	}
+
+	return true, details, nil
+}
+
+// getMustChangePasswordDetails retrieves the details of the user whose password must be changed, so the password change
+// can be required of them. The details are nil when they can't be retrieved.
+func (p *LDAPUserProvider) getMustChangePasswordDetails(ctx context.Context, conn LDAPConnection, inputUsername string, profile *ldapUserProfile) *UserDetails {
+	details, err := p.getUserDetails(ctx, conn, inputUsername, profile)
+	if err != nil {
+		operationLogger(ctx).Debugf("Unable to retrieve the details of user %s whose password must be changed. Cause: %s", inputUsername, err)
+
+		return nil
+	}
+
+	details.MustChangePassword = true
+
+	return details
+}
+
+// checkProfilePassword binds with the DN of the profile to verify the password of the user and returns the warnings
+// of the password policy of the LDAP server along with the transport security of the bind, which is logged.
+func (p *LDAPUserProvider) checkProfilePassword(ctx context.Context, inputUsername string, profile *ldapUserProfile, password string) (*PasswordPolicyWarnings, *TransportSecurity, error) {
+	userConn, policy, err := p.connectUser(ctx, inputUsername, profile, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unbindAndClose(userConn)
+
+	transport := p.transportSecurity(userConn)
+	logTransportSecurity(ctx, inputUsername, transport)
+
+	if p.whoAmIEnabled(ctx) {
+		// The failure is logged with the step, the password of the user matched regardless.
+		_, _ = p.whoAmI(ctx, userConn)
+	}
+
+	return passwordPolicyWarnings(ctx, policy), transport, nil
+}
+
+// connectUser binds with the DN of the profile and the password of the user to verify it and returns the connection
+// along with the password policy response control. Unlike the admin user, the users are never bound anonymously. The
+// error wraps ErrConnectionFailed when the LDAP server is unreachable, ErrBindTimeout when the bind exceeds the user
+// bind timeout, the LDAP error when the LDAP server is busy or unavailable and the error matching the reason the bind
+// failed otherwise.
+func (p *LDAPUserProvider) connectUser(ctx context.Context, inputUsername string, profile *ldapUserProfile, password string) (LDAPConnection, *ldap.ControlBeheraPasswordPolicy, error) {
+	// Many LDAP servers treat a simple bind with a DN and an empty password as an unauthenticated bind which succeeds
+	// whatever the password of the user, so the empty passwords are rejected without binding.
+	if password == "" {
+		return nil, nil, fmt.Errorf("%w for user %s. Cause: the password is empty", ErrInvalidCredentials, inputUsername)
+	}
+
+	// An empty DN would result in an anonymous bind succeeding whatever the password.
+	if profile.DN == "" {
+		return nil, nil, fmt.Errorf("%w for user %s. Cause: the DN of the user is empty", ErrInvalidCredentials, inputUsername)
+	}
+
+	// The bind verifying the password fails fast when the user bind timeout is configured, regardless of the timeout of
+	// the operation.
+	if p.userBindTimeout > 0 {
+		ctx = withConnectTimeout(ctx, p.userBindTimeout)
+	}
+
+	userConn, policy, err := p.connectWithPasswordPolicy(ctx, profile.DN, password)
+	if err != nil {
+		if errors.Is(err, ErrConnectionFailed) || errors.Is(err, ErrBindTimeout) {
+			return nil, nil, err
+		}
+
+		// The LDAP server busy or unavailable didn't reject the credentials, the error is kept so the failed login
+		// isn't regulated.
+		if isTransientLDAPError(err) {
+			return nil, nil, fmt.Errorf("Unable to bind user %s. Cause: %w", inputUsername, err)
+		}
+
+		return nil, nil, fmt.Errorf("%w for user %s. Cause: %s", p.bindError(err, policy), inputUsername, err)
+	}
+
+	return userConn, policy, nil
+}
+
+// passwordPolicyWarnings converts the password policy response control returned on a successful bind to the warnings
+// of the password policy, nil when there is none.
+func passwordPolicyWarnings(ctx context.Context, policy *ldap.ControlBeheraPasswordPolicy) *PasswordPolicyWarnings {
+	if policy == nil || (policy.Expire < 0 && policy.Grace < 0 && policy.Error != ldap.BeheraChangeAfterReset) {
+		return nil
+	}
+
+	warnings := &PasswordPolicyWarnings{
+		GraceLoginsRemaining: int(policy.Grace),
+		MustChangePassword:   policy.Error == ldap.BeheraChangeAfterReset,
+	}
+
+	if policy.Expire > 0 {
+		warnings.ExpiresIn = time.Duration(policy.Expire) * time.Second
+	}
+
+	operationLogger(ctx).Debugf("The password policy of the LDAP server returned warnings: expires in %s, %d grace logins remaining, must change password %t",
+		warnings.ExpiresIn, warnings.GraceLoginsRemaining, warnings.MustChangePassword)
+
+	return warnings
+}
+
+// ldapEscape escapes the input of the user to be used as a value in a filter. The escaped characters are replaced by
+// the hexadecimal form of their bytes, the only escaping form allowed in a filter by RFC4515. The input is escaped in a
+// single pass so the escape sequences are never escaped again whatever the configured characters.
+func (p *LDAPUserProvider) ldapEscape(inputUsername string) string {
+	var builder strings.Builder
+
+	builder.Grow(len(inputUsername))
+
+	for i := 0; i < len(inputUsername); {
+		r, size := utf8.DecodeRuneInString(inputUsername[i:])
+
+		// The invalid UTF-8 bytes are decoded one at a time as the replacement character and are escaped as well.
+		if r >= utf8.RuneSelf || strings.ContainsRune(ldapFilterRunes, r) || strings.ContainsRune(p.escapedRunes, r) {
+			builder.WriteString(ldapEscapeBinary([]byte(inputUsername[i : i+size])))
+		} else {
+			builder.WriteRune(r)
+		}
+
+		i += size
+	}
+
+	return builder.String()
+}
+
+// ldapEscapeBinary escapes every byte of a binary value so it can be used in an LDAP filter.
+func ldapEscapeBinary(value []byte) string {
+	var builder strings.Builder
+
+	for _, b := range value {
+		fmt.Fprintf(&builder, "\\%02x", b)
+	}
+
+	return builder.String()
+}
+
+type ldapUserProfile struct {
+	DN             string
+	Emails         []string
+	DisplayName    string
+	Username       string
+	Extra          map[string][]string
+	ObjectSID      []byte
+	PrimaryGroupID string
+	Photo          []byte
+	StableID       string
+	MemberOf       []string
+	Groups         []string
+
+	OperationalTimestamps  map[string]time.Time
+	PasswordLastSet        time.Time
+	PasswordExpires        time.Time
+	AccountExpires         time.Time
+	PasswordPolicySubentry string
+	MustChangePassword     bool
+}
+
+// ldapUsernameNormalizationForm returns the Unicode normalization form of the usernames, NFC unless another form is
+// configured. The form is nil when the usernames are searched as entered.
+func ldapUsernameNormalizationForm(normalization string) *norm.Form {
+	var form norm.Form
+
+	switch normalization {
+	case schema.LDAPUsernameNormalizationNone:
+		return nil
+	case schema.LDAPUsernameNormalizationNFD:
+		form = norm.NFD
+	case schema.LDAPUsernameNormalizationNFKC:
+		form = norm.NFKC
+	case schema.LDAPUsernameNormalizationNFKD:
+		form = norm.NFKD
+	default:
+		form = norm.NFC
+	}
+
+	return &form
+}
+
+// normalizeUsername normalizes the input of the user to the configured Unicode normalization form, so the usernames
+// entered with composed or decomposed characters match the form stored in the directory.
+func (p *LDAPUserProvider) normalizeUsername(inputUsername string) string {
+	if p.usernameNormalization == nil {
+		return inputUsername
+	}
+
+	return p.usernameNormalization.String(inputUsername)
+}
+
+func (p *LDAPUserProvider) resolveUsersFilter(userFilter string, inputUsername string) string {
+	inputUsername = p.ldapEscape(p.normalizeUsername(inputUsername))
+
+	// The {input} placeholder is replaced by the users username input.
+	userFilter = strings.ReplaceAll(userFilter, "{input}", inputUsername)
+
+	return userFilter
+}
+
+// getUserProfile retrieves the profile of the user matching the input with the users filter. The user is looked up by
+// its canonical username when no user matches the input and the users filter matches the login attribute instead of
+// the username attribute, since the canonical username is the input of the lookups once the user is logged in.
+func (p *LDAPUserProvider) getUserProfile(ctx context.Context, conn LDAPConnection, inputUsername string) (*ldapUserProfile, error) {
+	profile, err := p.getUserProfileWithFilter(ctx, conn, p.configuration.UsersFilter, inputUsername)
+	if p.canonicalUsersFilter == "" || !errors.Is(err, ErrUserNotFound) {
+		return profile, err
+	}
+
+	operationLogger(ctx).Tracef("No user matches the login %s, looking the user up by its username", inputUsername)
+
+	return p.getUserProfileWithFilter(ctx, conn, p.canonicalUsersFilter, inputUsername)
+}
+
+// connectAndGetUserProfile connects with the admin user and retrieves the profile of the user, retrying on transient
+// errors. The connection is left open for the caller to close when the profile is found.
+func (p *LDAPUserProvider) connectAndGetUserProfile(ctx context.Context, inputUsername string) (LDAPConnection, *ldapUserProfile, error) {
+	var (
+		conn    LDAPConnection
+		profile *ldapUserProfile
+	)
+
+	err := p.retry(ctx, func() (err error) {
+		if conn, err = p.connectSearch(ctx); err != nil {
+			return err
+		}
+
+		if profile, err = p.getUserProfile(ctx, conn, inputUsername); err != nil {
+			unbindAndClose(conn)
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, profile, nil
+}
+
+func (p *LDAPUserProvider) getUserProfileWithFilter(ctx context.Context, conn LDAPConnection, filter string, inputUsername string) (*ldapUserProfile, error) {
+	userFilter := p.resolveUsersFilter(filter, inputUsername)
+	operationLogger(ctx).Tracef("Computed user filter is %s", userFilter)
+
+	// The DN is not an attribute, it is always returned as the name of the entry. Requesting it as an attribute makes
+	// some LDAP servers return warnings.
+	attributes := append([]string{}, p.displayNameAttributes...)
+	attributes = append(attributes, p.mailAttributes...)
+	attributes = append(attributes, p.usernameAttributes...)
+
+	if p.configuration.PrimaryMailAttribute != "" {
+		attributes = append(attributes, p.configuration.PrimaryMailAttribute)
+	}
+	attributes = append(attributes, p.configuration.AdditionalAttributes...)
+	attributes = append(attributes, p.configuration.OperationalAttributes...)
+
+	if p.configuration.PhotoAttribute != "" {
+		attributes = append(attributes, p.configuration.PhotoAttribute)
+	}
+
+	if p.configuration.StableIDAttribute != "" {
+		attributes = append(attributes, p.configuration.StableIDAttribute)
+	}
+
+	if p.configuration.DNAttribute != "" {
+		attributes = append(attributes, p.configuration.DNAttribute)
+	}
+
+	if p.configuration.GroupsSearchMode == schema.LDAPGroupsSearchModeMemberOf {
+		attributes = append(attributes, p.configuration.MemberOfAttribute)
+	}
+
+	if p.configuration.Implementation == schema.LDAPImplementationActiveDirectory {
+		attributes = append(attributes, adAttributeObjectSID, adAttributePrimaryGroupID,
+			adAttributePwdLastSet, adAttributeAccountExpires, adAttributeMSDSUserPasswordExpiryTimeComputed)
+	} else {
+		attributes = append(attributes, ppolicyAttributePwdChangedTime, ppolicyAttributePwdPolicySubentry, ppolicyAttributePwdReset)
+	}
+
+	// Search for the given username. Two users are enough to reject an ambiguous input, a size limit of one would let
+	// some LDAP servers return a single entry when several users match. Every matching user is requested when one of
+	// them is selected by the multiple users policy.
+	sizeLimit := 2
+	if p.configuration.MultipleUsersPolicy == schema.LDAPMultipleUsersPolicyFirst ||
+		p.configuration.MultipleUsersPolicy == schema.LDAPMultipleUsersPolicyPreferredDN {
+		sizeLimit = 0
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		p.usersDN, p.usersScope, p.derefAliases,
+		sizeLimit, 0, false, userFilter, attributes, nil,
+	)
+
+	start := time.Now()
+
+	// The users matching in different users DNs are selected like the users matching in the same users DN.
+	sr, err := p.searchBaseDNs(ctx, conn, searchRequest, p.usersDNs, 0)
+	p.recordOperation(ldapMetricSearchUser, start, err)
+
+	// The LDAP server exceeding the size limit found more users than requested, the input is ambiguous whatever the
+	// multiple users policy since the selected user would depend on the entries the LDAP server returned.
+	var ldapErr *ldap.Error
+
+	if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultSizeLimitExceeded {
+		return nil, fmt.Errorf("%w with input %s", ErrMultipleUsersFound, inputUsername)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot find user DN of user %s. Cause: %w", inputUsername, err)
+	}
+
+	if len(sr.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	// A few directories and proxies return the entries without their DN which is only held by a virtual attribute.
+	if p.configuration.DNAttribute != "" {
+		for _, entry := range sr.Entries {
+			if entry.DN == "" {
+				entry.DN = entry.GetEqualFoldAttributeValue(p.configuration.DNAttribute)
+			}
+		}
+	}
+
+	entry, err := p.selectUserEntry(ctx, sr.Entries, inputUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	userProfile := ldapUserProfile{
+		DN:             entry.DN,
+		Extra:          make(map[string][]string),
+		ObjectSID:      entry.GetRawAttributeValue(adAttributeObjectSID),
+		PrimaryGroupID: entry.GetAttributeValue(adAttributePrimaryGroupID),
+	}
+
+	// The picture is binary, the raw value is used since the string value is not meant to hold arbitrary bytes.
+	if p.configuration.PhotoAttribute != "" {
+		userProfile.Photo = entry.GetRawAttributeValue(p.configuration.PhotoAttribute)
+	}
+
+	if p.configuration.StableIDAttribute != "" {
+		p.parseStableID(ctx, entry, &userProfile)
+	}
+
+	if p.configuration.GroupsSearchMode == schema.LDAPGroupsSearchModeMemberOf {
+		userProfile.MemberOf = entry.GetEqualFoldAttributeValues(p.configuration.MemberOfAttribute)
+	}
+
+	p.parsePasswordTimestamps(ctx, entry, &userProfile)
+	p.parseOperationalTimestamps(ctx, entry, &userProfile)
+
+	// The attributes may be returned without any value, for instance when the admin user is not allowed to read them.
+	for _, attr := range entry.Attributes {
+		if len(attr.Values) == 0 {
+			continue
+		}
+
+		for _, name := range p.configuration.AdditionalAttributes {
+			if strings.EqualFold(attr.Name, name) {
+				userProfile.Extra[name] = attr.Values
+			}
+		}
+	}
+
+	// The first populated attribute supplies the display name, the attributes are ordered by preference.
+	for _, attribute := range p.displayNameAttributes {
+		if value := entry.GetAttributeValue(attribute); value != "" {
+			userProfile.DisplayName = value
+			break
+		}
+	}
+
+	// The first populated attribute supplies the emails, the attributes are ordered by preference.
+	for _, attribute := range p.mailAttributes {
+		if values := mailAttributeValues(entry, attribute); len(values) != 0 {
+			userProfile.Emails = values
+			break
+		}
+	}
+
+	if p.configuration.PrimaryMailAttribute != "" {
+		userProfile.Emails = primaryEmailsFirst(mailAttributeValues(entry, p.configuration.PrimaryMailAttribute), userProfile.Emails)
+	}
+
+	// The first populated attribute supplies the username, the attributes are ordered by preference.
+	for _, attribute := range p.usernameAttributes {
+		values := entry.GetAttributeValues(attribute)
+		if len(values) == 0 {
+			continue
+		}
+
+		if len(values) != 1 {
+			return nil, fmt.Errorf("User %s cannot have multiple value for attribute %s",
+				inputUsername, attribute)
+		}
+
+		userProfile.Username = values[0]
+
+		break
+	}
+
+	if userProfile.DN == "" {
+		return nil, fmt.Errorf("No DN has been found for user %s", inputUsername)
+	}
+
+	if userProfile.DisplayName == "" {
+		operationLogger(ctx).Debugf("No display name found for user %s in the attributes %s, the username is used instead",
+			userProfile.Username, strings.Join(p.displayNameAttributes, ", "))
+
+		userProfile.DisplayName = userProfile.Username
+	}
+
+	if len(userProfile.Emails) == 0 {
+		operationLogger(ctx).Warnf("No mail address found for user %s in the attributes %s, the emails such as the "+
+			"password reset emails can't be sent to this user", userProfile.Username, strings.Join(p.mailAttributes, ", "))
+	}
+
+	if p.entryMapper != nil {
+		if err := p.mapEntry(entry, &userProfile); err != nil {
+			return nil, fmt.Errorf("Unable to map the entry of user %s. Cause: %w", inputUsername, err)
+		}
+	}
+
+	return &userProfile, nil
+}
+
+// selectUserEntry selects the entry of the user among the entries matching the input according to the multiple users
+// policy. The entries are rejected when several of them match unless the policy selects one of them.
+func (p *LDAPUserProvider) selectUserEntry(ctx context.Context, entries []*ldap.Entry, inputUsername string) (*ldap.Entry, error) {
+	if len(entries) == 1 {
+		return entries[0], nil
+	}
+
+	candidates := entries
+
+	switch p.configuration.MultipleUsersPolicy {
+	case schema.LDAPMultipleUsersPolicyFirst:
+	case schema.LDAPMultipleUsersPolicyPreferredDN:
+		candidates = nil
+
+		for _, entry := range entries {
+			if isDNDescendantOf(entry.DN, p.preferredUsersDN) {
+				candidates = append(candidates, entry)
+			}
+		}
+
+		if len(candidates) != 1 {
+			return nil, fmt.Errorf("%w with input %s, %d of them in the preferred users DN %s", ErrMultipleUsersFound, inputUsername, len(candidates), p.preferredUsersDN)
+		}
+	default:
+		return nil, fmt.Errorf("%w with input %s", ErrMultipleUsersFound, inputUsername)
+	}
+
+	// The entries are sorted so the selected entry doesn't depend on the order the LDAP server returns them in.
+	sorted := make([]*ldap.Entry, len(candidates))
+	copy(sorted, candidates)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].DN) < strings.ToLower(sorted[j].DN)
+	})
+
+	operationLogger(ctx).Warnf("%d users match the input %s, the user %s is selected by the multiple users policy %s",
+		len(entries), inputUsername, sorted[0].DN, p.configuration.MultipleUsersPolicy)
+
+	return sorted[0], nil
+}
+
+// isDNDescendantOf returns true when the DN is the base DN or one of its descendants. The DNs are compared case
+// insensitively.
+func isDNDescendantOf(dn string, baseDN string) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+
+	return dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+}
+
+func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ldapUserProfile) (string, error) { //nolint:unparam
+	inputUsername = p.ldapEscape(p.normalizeUsername(inputUsername))
+
+	// The {input} placeholder is replaced by the users username input.
+	groupFilter := strings.ReplaceAll(p.configuration.GroupsFilter, "{input}", inputUsername)
+
+	if profile != nil {
+		groupFilter = strings.ReplaceAll(groupFilter, "{username}", ldap.EscapeFilter(profile.Username))
+		groupFilter = strings.ReplaceAll(groupFilter, "{dn}", ldap.EscapeFilter(profile.DN))
+	}
+
+	// Only the groups matching the group name patterns are requested when some are configured.
+	if p.groupNamesFilter != "" {
+		groupFilter = "(&" + groupFilter + p.groupNamesFilter + ")"
+	}
+
+	return groupFilter, nil
+}
+
+// GetDetails retrieve the groups a user belongs to.
+func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error) {
+	return p.GetDetailsWithContext(context.Background(), inputUsername)
+}
+
+// GetDetailsWithContext retrieve the groups a user belongs to, the LDAP operations are aborted when the context is
+// cancelled or its deadline is exceeded.
+func (p *LDAPUserProvider) GetDetailsWithContext(ctx context.Context, inputUsername string) (*UserDetails, error) {
+	ctx = newOperationContext(ctx, "get_details", inputUsername)
+
+	if p.cache == nil {
+		return p.getDetails(ctx, inputUsername)
+	}
+
+	if details, ok := p.cache.Get(p.cacheKey(inputUsername)); ok {
+		return details, nil
+	}
+
+	details, err := p.getDetails(ctx, inputUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cache.Set(p.cacheKey(inputUsername), details)
+
+	return details, nil
+}
+
+// UserExists returns true when a user matches the input, without checking any password. It is meant for the internal
+// callers only, see UserProvider.
+func (p *LDAPUserProvider) UserExists(inputUsername string) (bool, error) {
+	return p.UserExistsWithContext(context.Background(), inputUsername)
+}
+
+// UserExistsWithContext returns true when a user matches the input, the LDAP operations are aborted when the context
+// is cancelled or its deadline is exceeded. The cache of the details is not used since the user may have been deleted
+// since. An input matching several users is an error, as is an unavailable LDAP server.
+func (p *LDAPUserProvider) UserExistsWithContext(ctx context.Context, inputUsername string) (bool, error) {
+	ctx = newOperationContext(ctx, "user_exists", inputUsername)
+
+	err := p.retry(ctx, func() error {
+		conn, err := p.connectSearch(ctx)
+		if err != nil {
+			return err
+		}
+		defer unbindAndClose(conn)
+
+		_, err = p.getUserProfile(ctx, conn, inputUsername)
+
+		return err
+	})
+
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrUserNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// cacheKey returns the key of the details of the user in the cache. The input of the users only differing by case
+// share the same entry when the usernames are case insensitive, as do the inputs only differing by normalization form.
+func (p *LDAPUserProvider) cacheKey(inputUsername string) string {
+	inputUsername = p.normalizeUsername(inputUsername)
+
+	if p.configuration.CaseInsensitiveUsernames {
+		return strings.ToLower(inputUsername)
+	}
+
+	return inputUsername
+}
+
+// getDetails retrieves the details of the user, retrying on transient errors.
+func (p *LDAPUserProvider) getDetails(ctx context.Context, inputUsername string) (details *UserDetails, err error) {
+	err = p.retry(ctx, func() error {
+		conn, err := p.connectSearch(ctx)
+		if err != nil {
+			return err
+		}
+		defer unbindAndClose(conn)
+
+		profile, err := p.getUserProfile(ctx, conn, inputUsername)
+		if err != nil {
+			return err
+		}
+
+		details, err = p.getUserDetails(ctx, conn, inputUsername, profile)
+
+		return err
+	})
+
+	return details, err
+}
+
+// GetDetailsByEmail retrieve the details of the single user whose mail attribute matches the given email.
+func (p *LDAPUserProvider) GetDetailsByEmail(email string) (*UserDetails, error) {
+	ctx := newOperationContext(context.Background(), "get_details_by_email", "")
+
+	var details *UserDetails
+
+	err := p.retry(ctx, func() error {
+		conn, err := p.connectSearch(ctx)
+		if err != nil {
+			return err
+		}
+		defer unbindAndClose(conn)
+
+		profile, err := p.getUserProfileWithFilter(ctx, conn, p.mailFilter, email)
+		if err != nil {
+			return err
+		}
+
+		details, err = p.getUserDetails(ctx, conn, profile.Username, profile)
+
+		return err
+	})
+
+	return details, err
+}
+
+// getUserDetails retrieves the groups of the user profile and combines them into the user details.
+func (p *LDAPUserProvider) getUserDetails(ctx context.Context, conn LDAPConnection, inputUsername string, profile *ldapUserProfile) (*UserDetails, error) {
+	var (
+		primaryGroupEntries []*ldap.Entry
+		passwordExpires     = profile.PasswordExpires
+		secondaryDone       chan struct{}
+	)
+
+	searchPrimaryGroup := p.configuration.Implementation == schema.LDAPImplementationActiveDirectory
+	searchPasswordPolicy := passwordExpires.IsZero() && profile.PasswordPolicySubentry != "" && !profile.PasswordLastSet.IsZero()
+
+	// The searches of the primary group and the password policy only depend on the profile.
+	secondarySearches := func(conn LDAPConnection) {
+		if searchPrimaryGroup {
+			primaryGroupEntries = p.searchPrimaryGroup(ctx, conn, profile)
+		}
+
+		if searchPasswordPolicy {
+			passwordExpires = p.passwordPolicyExpiry(ctx, conn, profile)
+		}
+	}
+
+	// When the searches are concurrent, the secondary searches run over a second admin connection while the groups are
+	// searched. They're run after the groups search over the connection of the profile when it can't be opened.
+	if p.configuration.ConcurrentSearches && (searchPrimaryGroup || searchPasswordPolicy) {
+		secondaryConn, err := p.connectSearch(ctx)
+		if err != nil {
+			operationLogger(ctx).Debugf("Searching the primary group and the password policy of user %s sequentially, "+
+				"the second connection can't be opened. Cause: %s", inputUsername, err)
+		} else {
+			secondaryDone = make(chan struct{})
+
+			go func() {
+				defer close(secondaryDone)
+				defer unbindAndClose(secondaryConn)
+
+				secondarySearches(secondaryConn)
+			}()
+		}
+	}
+
+	var (
+		entries      []*ldap.Entry
+		groupsFilter string
+		err          error
+	)
+
+	if p.configuration.GroupsSearchMode == schema.LDAPGroupsSearchModeMemberOf {
+		entries, err = p.searchMemberOfGroups(ctx, conn, inputUsername, profile)
+	} else {
+		entries, groupsFilter, err = p.searchFilterGroups(ctx, conn, inputUsername, profile)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0)
+
+	var groupDisplayNames map[string]string
+
+	if p.configuration.GroupDisplayNameAttribute != "" {
+		groupDisplayNames = make(map[string]string)
+	}
+
+	for _, res := range entries {
+		// The names are selected by attribute name since the LDAP servers may return the attributes in any order,
+		// along with attributes which weren't requested. Normally there is only one value.
+		names := p.groupNames(res)
+		if len(names) == 0 {
+			operationLogger(ctx).Debugf("Skipping group %s of user %s which has none of the group name attributes %s",
+				res.DN, inputUsername, strings.Join(p.groupNameAttributes, ", "))
+
+			continue
+		}
+
+		if groupDisplayNames != nil {
+			p.addGroupDisplayNames(res, names, groupDisplayNames)
+		}
+
+		groups = append(groups, names...)
+
+		// The limit is also checked here for the servers ignoring the size limit and the entries of the referrals.
+		if p.configuration.MaxGroups > 0 && len(groups) > p.configuration.MaxGroups {
+			return nil, p.tooManyGroupsError(ctx, inputUsername, groupsFilter)
+		}
+	}
+
+	// The groups mapped from the entry of the user by the entry mapper are merged with the groups searched.
+	for _, group := range profile.Groups {
+		if !utils.IsStringInSlice(group, groups) {
+			groups = append(groups, group)
+		}
+	}
+
+	if secondaryDone != nil {
+		<-secondaryDone
+	} else {
+		secondarySearches(conn)
+	}
+
+	groups = p.appendPrimaryGroup(primaryGroupEntries, groups, groupDisplayNames)
+	groups, groupDisplayNames = p.normalizeGroupNames(groups, groupDisplayNames)
+
+	switch {
+	case len(groups) != 0:
+	case p.configuration.GroupsSearchMode == schema.LDAPGroupsSearchModeMemberOf:
+		operationLogger(ctx).Warnf("User %s doesn't belong to any group, the %s attribute of the user lists no group in the groups DNs",
+			inputUsername, p.configuration.MemberOfAttribute)
+	default:
+		operationLogger(ctx).Warnf("User %s doesn't belong to any group, the groups_filter is likely misconfigured", inputUsername)
+		operationLogger(ctx).Debugf("The groups filter of user %s matching no group is %s", inputUsername, groupsFilter)
+	}
+
+	// The groups are filtered once the warning is logged since the groups lists drop the groups deliberately.
+	groups, groupDisplayNames = p.filterGroups(groups, groupDisplayNames)
+
+	return &UserDetails{
+		Username:              profile.Username,
+		StableID:              profile.StableID,
+		DisplayName:           profile.DisplayName,
+		Emails:                profile.Emails,
+		Groups:                groups,
+		GroupDisplayNames:     groupDisplayNames,
+		Extra:                 profile.Extra,
+		OperationalTimestamps: profile.OperationalTimestamps,
+		PasswordLastSet:       profile.PasswordLastSet,
+		PasswordExpires:       passwordExpires,
+		AccountExpires:        profile.AccountExpires,
+		Photo:                 profile.Photo,
+		PhotoMIMEType:         photoMIMEType(profile.Photo),
+		MustChangePassword:    profile.MustChangePassword,
+	}, nil
+}
+
+// searchFilterGroups searches the groups DNs for the groups matching the groups filter of the user. The computed groups
+// filter is returned along with the group entries.
+func (p *LDAPUserProvider) searchFilterGroups(ctx context.Context, conn LDAPConnection, inputUsername string, profile *ldapUserProfile) ([]*ldap.Entry, string, error) {
+	groupsFilter, err := p.resolveGroupsFilter(inputUsername, profile)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to create group filter for user %s. Cause: %s", inputUsername, err)
+	}
+
+	operationLogger(ctx).Tracef("Computed groups filter is %s", groupsFilter)
+
+	// Search for the given username. The size limit bounds the number of groups returned by the LDAP server, a server
+	// enforcing it returns a size limit exceeded error instead of the groups.
+	searchGroupRequest := ldap.NewSearchRequest(
+		p.groupsDN, p.groupsScope, p.derefAliases,
+		p.configuration.MaxGroups, 0, false, groupsFilter, p.groupAttributes(), nil,
+	)
+
+	start := time.Now()
+
+	sr, err := p.searchBaseDNs(ctx, conn, searchGroupRequest, p.groupsDNs, uint32(p.configuration.PageSize))
+
+	// The searches matching no group are recorded apart since they are almost always caused by a misconfigured groups
+	// filter, which operators can then alert on.
+	if err == nil && len(sr.Entries) == 0 {
+		p.recordOperationResult(ldapMetricSearchGroups, ldapMetricResultEmpty, start)
+	} else {
+		p.recordOperation(ldapMetricSearchGroups, start, err)
+	}
+
+	var ldapErr *ldap.Error
+
+	sizeLimitExceeded := errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultSizeLimitExceeded
+
+	// The size limit of the request is the maximum number of groups, the size limit is exceeded before reaching it
+	// when the LDAP server enforces a smaller size limit.
+	switch {
+	case sizeLimitExceeded && sr != nil && (p.configuration.MaxGroups == 0 || len(sr.Entries) < p.configuration.MaxGroups):
+		operationLogger(ctx).Warnf("The LDAP server limited the groups search of user %s to %d groups, the groups of the user are partially retrieved. "+
+			"Please raise the size limit of the LDAP server or configure the page_size", inputUsername, len(sr.Entries))
+	case sizeLimitExceeded:
+		return nil, groupsFilter, p.tooManyGroupsError(ctx, inputUsername, groupsFilter)
+	case err != nil:
+		return nil, groupsFilter, fmt.Errorf("Unable to retrieve groups of user %s. Cause: %w", inputUsername, err)
+	}
+
+	return sr.Entries, groupsFilter, nil
+}
+
+// tooManyGroupsError logs the groups filter matching more groups than the maximum number of groups of a user, which is
+// usually the result of a misconfigured groups filter, and returns the error reporting it. The member of attribute of the
+// user is logged instead when the groups are read from it.
+func (p *LDAPUserProvider) tooManyGroupsError(ctx context.Context, inputUsername string, groupsFilter string) error {
+	if p.configuration.GroupsSearchMode == schema.LDAPGroupsSearchModeMemberOf {
+		operationLogger(ctx).Warnf("The %s attribute of user %s lists more than the maximum of %d groups",
+			p.configuration.MemberOfAttribute, inputUsername, p.configuration.MaxGroups)
+
+		return fmt.Errorf("%w of user %s, the maximum is %d", ErrTooManyGroups, inputUsername, p.configuration.MaxGroups)
+	}
+
+	operationLogger(ctx).Warnf("The groups filter %s matches more than the maximum of %d groups for user %s, please review the groups filter",
+		groupsFilter, p.configuration.MaxGroups, inputUsername)
+
+	return fmt.Errorf("%w of user %s, the maximum is %d", ErrTooManyGroups, inputUsername, p.configuration.MaxGroups)
+}
+
+// photoMIMEType detects the MIME type of the picture of a user, for instance image/jpeg for the thumbnailPhoto and
+// jpegPhoto attributes. It is empty when there is no picture.
+func photoMIMEType(photo []byte) string {
+	if len(photo) == 0 {
+		return ""
+	}
+
+	return http.DetectContentType(photo)
+}
+
+// searchPrimaryGroup searches the Active Directory primary group of the user since it is not returned by the groups
+// filter. No entry is returned when the primary group cannot be resolved.
+func (p *LDAPUserProvider) searchPrimaryGroup(ctx context.Context, conn LDAPConnection, profile *ldapUserProfile) []*ldap.Entry {
+	if len(profile.ObjectSID) == 0 || profile.PrimaryGroupID == "" {
+		return nil
+	}
+
+	groupSID, err := adPrimaryGroupSID(profile.ObjectSID, profile.PrimaryGroupID)
+	if err != nil {
+		operationLogger(ctx).Warnf("Unable to compute the primary group of user %s. Cause: %s", profile.Username, err)
+		return nil
+	}
+
+	filter := fmt.Sprintf("(%s=%s)", adAttributeObjectSID, ldapEscapeBinary(groupSID))
+	if p.groupNamesFilter != "" {
+		filter = "(&" + filter + p.groupNamesFilter + ")"
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		p.configuration.BaseDN, ldap.ScopeWholeSubtree, p.derefAliases,
+		1, 0, false, filter, p.groupAttributes(), nil,
+	)
+
+	sr, err := p.search(ctx, conn, searchRequest)
+	if err != nil {
+		operationLogger(ctx).Warnf("Unable to retrieve the primary group of user %s. Cause: %s", profile.Username, err)
+		return nil
+	}
+
+	return sr.Entries
+}
+
+// appendPrimaryGroup appends the names of the primary group entries to the groups, skipping the groups the user
+// already belongs to.
+func (p *LDAPUserProvider) appendPrimaryGroup(entries []*ldap.Entry, groups []string, groupDisplayNames map[string]string) []string {
+	for _, entry := range entries {
+		names := p.groupNames(entry)
+
+		if groupDisplayNames != nil {
+			p.addGroupDisplayNames(entry, names, groupDisplayNames)
+		}
+
+		for _, name := range names {
+			if !utils.IsStringInSlice(name, groups) {
+				groups = append(groups, name)
+			}
+		}
+	}
+
+	return groups
+}
+
+// normalizeGroupNames normalizes the names of the groups according to the group name normalization, dropping the
+// duplicates it results in, for instance Admins and admins once lowercased. The display names are mapped from the
+// normalized names.
+func (p *LDAPUserProvider) normalizeGroupNames(groups []string, groupDisplayNames map[string]string) ([]string, map[string]string) {
+	normalization := p.configuration.GroupNameNormalization
+	if normalization == "" || normalization == schema.LDAPGroupNameNormalizationNone {
+		return groups, groupDisplayNames
+	}
+
+	normalized := make([]string, 0, len(groups))
+
+	for _, group := range groups {
+		group = utils.NormalizeGroupName(group, normalization)
+
+		if !utils.IsStringInSlice(group, normalized) {
+			normalized = append(normalized, group)
+		}
+	}
+
+	if groupDisplayNames == nil {
+		return normalized, nil
+	}
+
+	normalizedDisplayNames := make(map[string]string, len(groupDisplayNames))
+
+	for group, displayName := range groupDisplayNames {
+		normalizedDisplayNames[utils.NormalizeGroupName(group, normalization)] = displayName
+	}
+
+	return normalized, normalizedDisplayNames
+}
+
+// groupAttributes returns the attributes requested by the groups searches, the group name attributes and the group
+// display name attribute when configured.
+func (p *LDAPUserProvider) groupAttributes() []string {
+	if p.configuration.GroupDisplayNameAttribute == "" {
+		return p.groupNameAttributes
+	}
+
+	return append(append([]string{}, p.groupNameAttributes...), p.configuration.GroupDisplayNameAttribute)
+}
+
+// groupNames returns the names of a group entry from the first populated group name attribute, the attributes are
+// ordered by preference.
+func (p *LDAPUserProvider) groupNames(entry *ldap.Entry) []string {
+	for _, attribute := range p.groupNameAttributes {
+		if names := entry.GetEqualFoldAttributeValues(attribute); len(names) != 0 {
+			return names
+		}
+	}
+
+	return nil
+}
+
+// addGroupDisplayNames maps the names of a group entry to its display name. The groups without a display name are
+// displayed by their name and are not mapped.
+func (p *LDAPUserProvider) addGroupDisplayNames(entry *ldap.Entry, names []string, groupDisplayNames map[string]string) {
+	displayNames := entry.GetEqualFoldAttributeValues(p.configuration.GroupDisplayNameAttribute)
+	if len(displayNames) == 0 || displayNames[0] == "" {
+		return
+	}
+
+	for _, name := range names {
+		groupDisplayNames[name] = displayNames[0]
+	}
+}
+
+// UpdatePassword update the password of the given user.
+func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
+	return p.UpdatePasswordWithContext(context.Background(), inputUsername, newPassword)
+}
+
+// UpdatePasswordWithContext update the password of the given user, the LDAP operations are aborted when the context
+// is cancelled or its deadline is exceeded.
+func (p *LDAPUserProvider) UpdatePasswordWithContext(ctx context.Context, inputUsername string, newPassword string) error {
+	ctx = newOperationContext(ctx, "update_password", inputUsername)
+
+	if err := checkPasswordPolicy(p.configuration.PasswordPolicy, inputUsername, newPassword); err != nil {
+		return err
+	}
+
+	if p.cache != nil {
+		defer p.cache.Delete(p.cacheKey(inputUsername))
+	}
+
+	user, password := p.passwordModifyCredentials()
+
+	conn, err := p.connectAdmin(ctx, user, password)
+	if err != nil {
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+	defer unbindAndClose(conn)
+
+	profile, err := p.getUserProfile(ctx, conn, inputUsername)
+
+	if err != nil {
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+
+	if err = p.preReadPasswordModify(ctx, conn, profile.DN); err != nil {
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+
+	start := time.Now()
+
+	err = p.modifyPassword(conn, profile.DN, newPassword)
+	logOperationStep(ctx, ldapStepModify, start, logrus.Fields{"dn": profile.DN}, err)
+	p.recordOperation(ldapMetricModifyPassword, start, err)
+
+	return passwordModifyError(inputUsername, err)
+}
+
+// passwordModifyCredentials returns the credentials of the user updating the passwords. The passwords are updated by
+// a dedicated account when configured so the main account only needs to search.
+func (p *LDAPUserProvider) passwordModifyCredentials() (user string, password string) {
+	if p.configuration.PasswordModifyUser != "" {
+		return p.configuration.PasswordModifyUser, p.configuration.PasswordModifyPassword
+	}
+
+	return p.configuration.User, p.configuration.Password
+}
+
+// passwordModifyError maps the error of the LDAP server updating the password of the user to the matching error, nil
+// when the password has been updated.
+func passwordModifyError(inputUsername string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultConstraintViolation):
+		return fmt.Errorf("%w of user %s. Cause: %s", passwordPolicyViolation(err), inputUsername, err)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights):
+		return fmt.Errorf("%w for user %s. Cause: %s", ErrPasswordChangeNotPermitted, inputUsername, err)
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultAssertionFailed):
+		return fmt.Errorf("%w for user %s. Cause: %s", ErrPasswordAssertionFailed, inputUsername, err)
+	default:
+		return fmt.Errorf("%w of user %s. Cause: %s", ErrPasswordUpdateFailed, inputUsername, err)
+	}
+}
+
+// ldapPasswordPolicyViolations are the fragments of the diagnostic messages of OpenLDAP, Samba and 389-DS reporting the
+// violations of the password policy, Active Directory only reports the 0000052D code for all of them.
+var ldapPasswordPolicyViolations = []struct {
+	fragments []string
+	err       error
+}{
+	{[]string{"too short"}, ErrPasswordTooShort},
+	{[]string{"history", "already used", "already been used"}, ErrPasswordInHistory},
+	{[]string{"too young", "too recently", "minimum age"}, ErrPasswordTooYoung},
+}
+
+// passwordPolicyViolation returns the violation of the password policy reported in the diagnostic message of the error
+// of the LDAP server, ErrPasswordPolicyViolation when it isn't a known one.
+func passwordPolicyViolation(err error) error {
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.Err == nil {
+		return ErrPasswordPolicyViolation
+	}
+
+	message := strings.ToLower(ldapErr.Err.Error())
+
+	for _, violation := range ldapPasswordPolicyViolations {
+		for _, fragment := range violation.fragments {
+			if strings.Contains(message, fragment) {
+				return violation.err
+			}
+		}
+	}
+
+	return ErrPasswordPolicyViolation
+}
+
+// modifyPassword replaces the password of the user. The Password Modify extended operation lets the LDAP server hash
+// the password and enforce its password policy, otherwise the password is set in the attribute and the encoding of the
+// password encoder of the implementation. The modify requests carry the assertion of the
+// password_modify_assertion_filter when configured.
+func (p *LDAPUserProvider) modifyPassword(conn LDAPConnection, userDN string, newPassword string) error {
+	if p.configuration.PasswordModifyExtendedOperation && p.configuration.Implementation != schema.LDAPImplementationActiveDirectory {
+		_, err := conn.PasswordModify(ldap.NewPasswordModifyRequest(userDN, "", newPassword))
+
+		return err
+	}
+
+	attribute, values := p.passwordEncoder.Encode(newPassword)
+
+	modifyRequest := ldap.NewModifyRequest(userDN, p.passwordModifyControls())
+	modifyRequest.Replace(attribute, values)
+
+	return conn.Modify(modifyRequest)
 }
@@ -1,17 +1,111 @@
 package authentication
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
 
+// newTestLDAPUserProvider creates a provider with the given configuration over a mocked connection factory and returns
+// it with the mocked factory and connection. The retries of the provider are not delayed.
+func newTestLDAPUserProvider(ctrl *gomock.Controller, configuration schema.LDAPAuthenticationBackendConfiguration) (*LDAPUserProvider, *MockLDAPConnectionFactory, *MockLDAPConnection) {
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(configuration, nil, mockFactory)
+	ldapClient.retryBackoff = 0
+
+	return ldapClient, mockFactory, mockConn
+}
+
+func TestShouldSetImplementationDefaultAttributes(t *testing.T) {
+	testCases := []struct {
+		name                                                                       string
+		configuration                                                              schema.LDAPAuthenticationBackendConfiguration
+		usernameAttribute, mailAttribute, displayNameAttribute, groupNameAttribute string
+	}{
+		{
+			"Custom",
+			schema.LDAPAuthenticationBackendConfiguration{},
+			"uid", "mail", "displayname", "cn",
+		},
+		{
+			"ActiveDirectory",
+			schema.LDAPAuthenticationBackendConfiguration{Implementation: schema.LDAPImplementationActiveDirectory},
+			"sAMAccountName", "mail", "displayName", "cn",
+		},
+		{
+			"ActiveDirectoryOverride",
+			schema.LDAPAuthenticationBackendConfiguration{Implementation: schema.LDAPImplementationActiveDirectory, UsernameAttribute: "userPrincipalName"},
+			"userPrincipalName", "mail", "displayName", "cn",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ldapClient := NewLDAPUserProvider(tc.configuration, nil)
+
+			assert.Equal(t, tc.usernameAttribute, ldapClient.configuration.UsernameAttribute)
+			assert.Equal(t, tc.mailAttribute, ldapClient.configuration.MailAttribute)
+			assert.Equal(t, tc.displayNameAttribute, ldapClient.configuration.DisplayNameAttribute)
+			assert.Equal(t, tc.groupNameAttribute, ldapClient.configuration.GroupNameAttribute)
+		})
+	}
+}
+
+func TestShouldTrimStrayCommasAndWhitespacesOfDNs(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			BaseDN:             " dc=example,dc=com,",
+			AdditionalUsersDN:  "ou=users, ",
+			AdditionalGroupsDN: ",ou=groups",
+			PreferredUsersDN:   "ou=employees,ou=users,",
+		},
+		nil)
+
+	assert.Equal(t, "ou=users,dc=example,dc=com", ldapClient.usersDN)
+	assert.Equal(t, "ou=groups,dc=example,dc=com", ldapClient.groupsDN)
+	assert.Equal(t, "ou=employees,ou=users,dc=example,dc=com", ldapClient.preferredUsersDN)
+}
+
+func TestShouldLogMalformedDNs(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			BaseDN:            "dc=example,dc=com",
+			AdditionalUsersDN: "ou=users,dc",
+		},
+		nil)
+
+	assert.Equal(t, "ou=users,dc,dc=example,dc=com", ldapClient.usersDN)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Contains(t, entry.Message, "The LDAP users DN ou=users,dc,dc=example,dc=com is not a valid DN")
+}
+
 func TestShouldCreateRawConnectionWhenSchemeIsLDAP(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -34,7 +128,7 @@ func TestShouldCreateRawConnectionWhenSchemeIsLDAP(t *testing.T) {
 		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
 		Return(nil)
 
-	_, err := ldapClient.connect("cn=admin,dc=example,dc=com", "password")
+	_, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
 
 	require.NoError(t, err)
 }
@@ -61,120 +155,174 @@ func TestShouldCreateTLSConnectionWhenSchemeIsLDAPS(t *testing.T) {
 		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
 		Return(nil)
 
-	_, err := ldapClient.connect("cn=admin,dc=example,dc=com", "password")
+	_, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
 
 	require.NoError(t, err)
 }
 
-func TestEscapeSpecialCharsFromUserInput(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestShouldSendConfiguredServerNameWhenSchemeIsLDAPS(t *testing.T) {
+	certificate, err := tls.LoadX509KeyPair("../suites/common/ssl/cert.pem", "../suites/common/ssl/key.pem")
+	require.NoError(t, err)
 
-	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	serverNames := make(chan string, 1)
 
-	ldapClient := NewLDAPUserProviderWithFactory(
-		schema.LDAPAuthenticationBackendConfiguration{
-			URL: "ldaps://127.0.0.1:389",
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
 		},
-		nil,
-		mockFactory)
-
-	// No escape
-	assert.Equal(t, "xyz", ldapClient.ldapEscape("xyz"))
+	})
+	require.NoError(t, err)
 
-	// Escape
-	assert.Equal(t, "test\\,abc", ldapClient.ldapEscape("test,abc"))
-	assert.Equal(t, "test\\5cabc", ldapClient.ldapEscape("test\\abc"))
-	assert.Equal(t, "test\\2aabc", ldapClient.ldapEscape("test*abc"))
-	assert.Equal(t, "test \\28abc\\29", ldapClient.ldapEscape("test (abc)"))
-	assert.Equal(t, "test\\#abc", ldapClient.ldapEscape("test#abc"))
-	assert.Equal(t, "test\\+abc", ldapClient.ldapEscape("test+abc"))
-	assert.Equal(t, "test\\<abc", ldapClient.ldapEscape("test<abc"))
-	assert.Equal(t, "test\\>abc", ldapClient.ldapEscape("test>abc"))
-	assert.Equal(t, "test\\;abc", ldapClient.ldapEscape("test;abc"))
-	assert.Equal(t, "test\\\"abc", ldapClient.ldapEscape("test\"abc"))
-	assert.Equal(t, "test\\=abc", ldapClient.ldapEscape("test=abc"))
-	assert.Equal(t, "test\\,\\5c\\28abc\\29", ldapClient.ldapEscape("test,\\(abc)"))
-}
+	defer listener.Close()
 
-func TestEscapeSpecialCharsInGroupsFilter(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
 
-	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+		_ = conn.(*tls.Conn).Handshake()
+		_ = conn.Close()
+	}()
 
-	ldapClient := NewLDAPUserProviderWithFactory(
+	ldapClient := NewLDAPUserProvider(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:          "ldaps://127.0.0.1:389",
-			GroupsFilter: "(|(member={dn})(uid={username})(uid={input}))",
+			URL: "ldaps://" + listener.Addr().String(),
+			TLS: &schema.TLSConfig{
+				ServerName: "ldap.example.com",
+				SkipVerify: true,
+			},
 		},
-		nil,
-		mockFactory)
+		nil)
 
-	profile := ldapUserProfile{
-		DN:          "cn=john (external),dc=example,dc=com",
-		Username:    "john",
-		DisplayName: "John Doe",
-		Emails:      []string{"john.doe@authelia.com"},
-	}
+	assert.Equal(t, "ldap.example.com", ldapClient.tlsConfig.ServerName)
 
-	filter, _ := ldapClient.resolveGroupsFilter("john", &profile)
-	assert.Equal(t, "(|(member=cn=john \\28external\\29,dc=example,dc=com)(uid=john)(uid=john))", filter)
+	conn, err := ldapClient.connectionFactory.DialURL(ldapClient.configuration.URL, ldap.DialWithTLSConfig(ldapClient.tlsConfig))
+	require.NoError(t, err)
 
-	filter, _ = ldapClient.resolveGroupsFilter("john#=(abc,def)", &profile)
-	assert.Equal(t, "(|(member=cn=john \\28external\\29,dc=example,dc=com)(uid=john)(uid=john\\#\\=\\28abc\\,def\\29))", filter)
-}
+	conn.Close()
 
-type SearchRequestMatcher struct {
-	expected string
+	select {
+	case serverName := <-serverNames:
+		assert.Equal(t, "ldap.example.com", serverName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the LDAP server did not receive the TLS handshake")
+	}
 }
 
-func NewSearchRequestMatcher(expected string) *SearchRequestMatcher {
-	return &SearchRequestMatcher{expected}
-}
+// startSOCKS5Server starts a SOCKS5 server without authentication which tunnels every connection to the target
+// address and reports the address requested by the client.
+func startSOCKS5Server(t *testing.T, target string) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
 
-func (srm *SearchRequestMatcher) Matches(x interface{}) bool {
-	sr := x.(*ldap.SearchRequest)
-	return sr.Filter == srm.expected
-}
+	t.Cleanup(func() { _ = listener.Close() })
 
-func (srm *SearchRequestMatcher) String() string {
-	return ""
+	requested := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The greeting is answered with the no authentication method, the request is a CONNECT to a domain name.
+		greeting := make([]byte, 3)
+		if _, err = io.ReadFull(conn, greeting); err != nil {
+			return
+		}
+
+		_, _ = conn.Write([]byte{0x05, 0x00})
+
+		header := make([]byte, 5)
+		if _, err = io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		request := make([]byte, int(header[4])+2)
+		if _, err = io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		port := int(request[len(request)-2])<<8 | int(request[len(request)-1])
+		requested <- net.JoinHostPort(string(request[:len(request)-2]), strconv.Itoa(port))
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+
+		_, _ = conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+		go func() { _, _ = io.Copy(upstream, conn) }()
+
+		_, _ = io.Copy(conn, upstream)
+	}()
+
+	return listener.Addr().String(), requested
 }
 
-func TestShouldEscapeUserInput(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func TestShouldNegotiateTLSThroughSOCKS5ProxyWhenSchemeIsLDAPS(t *testing.T) {
+	certificate, err := tls.LoadX509KeyPair("../suites/common/ssl/cert.pem", "../suites/common/ssl/key.pem")
+	require.NoError(t, err)
 
-	mockFactory := NewMockLDAPConnectionFactory(ctrl)
-	mockConn := NewMockLDAPConnection(ctrl)
+	serverNames := make(chan string, 1)
 
-	ldapClient := NewLDAPUserProviderWithFactory(
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		},
+	})
+	require.NoError(t, err)
+
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		_ = conn.(*tls.Conn).Handshake()
+		_ = conn.Close()
+	}()
+
+	proxyAddress, requested := startSOCKS5Server(t, listener.Addr().String())
+
+	ldapClient := NewLDAPUserProvider(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:                  "ldap://127.0.0.1:389",
-			User:                 "cn=admin,dc=example,dc=com",
-			UsersFilter:          "(|({username_attribute}={input})({mail_attribute}={input}))",
-			UsernameAttribute:    "uid",
-			MailAttribute:        "mail",
-			DisplayNameAttribute: "displayname",
-			Password:             "password",
-			AdditionalUsersDN:    "ou=users",
-			BaseDN:               "dc=example,dc=com",
+			URL:      "ldaps://ldap.example.com",
+			ProxyURL: "socks5://" + proxyAddress,
+			TLS: &schema.TLSConfig{
+				SkipVerify: true,
+			},
 		},
-		nil,
-		mockFactory)
+		nil)
 
-	mockConn.EXPECT().
-		// Here we ensure that the input has been correctly escaped.
-		Search(NewSearchRequestMatcher("(|(uid=john\\=abc)(mail=john\\=abc))")).
-		Return(&ldap.SearchResult{}, nil)
+	require.NotNil(t, ldapClient.proxyDialer)
 
-	_, err := ldapClient.getUserProfile(mockConn, "john=abc")
-	require.Error(t, err)
-	assert.EqualError(t, err, "user not found")
+	conn, err := ldapClient.dial(context.Background(), ldapClient.configuration.URL, ldapClient.tlsConfig)
+	require.NoError(t, err)
+
+	conn.Close()
+
+	assert.Equal(t, "ldap.example.com:636", <-requested)
+
+	select {
+	case serverName := <-serverNames:
+		assert.Equal(t, "ldap.example.com", serverName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the LDAP server did not receive the TLS handshake")
+	}
 }
 
-func TestShouldCombineUsernameFilterAndUsersFilter(t *testing.T) {
+func TestShouldStartTLSOverProxyConnection(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -183,43 +331,33 @@ func TestShouldCombineUsernameFilterAndUsersFilter(t *testing.T) {
 
 	ldapClient := NewLDAPUserProviderWithFactory(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:                  "ldap://127.0.0.1:389",
-			User:                 "cn=admin,dc=example,dc=com",
-			UsernameAttribute:    "uid",
-			UsersFilter:          "(&({username_attribute}={input})(&(objectCategory=person)(objectClass=user)))",
-			Password:             "password",
-			AdditionalUsersDN:    "ou=users",
-			BaseDN:               "dc=example,dc=com",
-			MailAttribute:        "mail",
-			DisplayNameAttribute: "displayname",
+			URL:      "ldap://ldap.example.com:389",
+			ProxyURL: "socks5://bastion.example.com:1080",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			StartTLS: true,
 		},
 		nil,
 		mockFactory)
 
-	mockConn.EXPECT().
-		Search(NewSearchRequestMatcher("(&(uid=john)(&(objectCategory=person)(objectClass=user)))")).
-		Return(&ldap.SearchResult{}, nil)
-
-	_, err := ldapClient.getUserProfile(mockConn, "john")
-	require.Error(t, err)
-	assert.EqualError(t, err, "user not found")
-}
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURLWithProxy(gomock.Any(), gomock.Eq("ldap://ldap.example.com:389"), gomock.Eq(ldapClient.proxyDialer), gomock.Eq(ldapClient.tlsConfig)).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			StartTLS(ldapClient.startTLSConfig).
+			Return(nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
 
-func createSearchResultWithAttributes(attributes ...*ldap.EntryAttribute) *ldap.SearchResult {
-	return &ldap.SearchResult{
-		Entries: []*ldap.Entry{
-			{Attributes: attributes},
-		},
-	}
-}
+	_, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
 
-func createSearchResultWithAttributeValues(values ...string) *ldap.SearchResult {
-	return createSearchResultWithAttributes(&ldap.EntryAttribute{
-		Values: values,
-	})
+	require.NoError(t, err)
 }
 
-func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
+func TestShouldBindAnonymouslyWhenUserIsEmpty(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -228,15 +366,7 @@ func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
 
 	ldapClient := NewLDAPUserProviderWithFactory(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:                  "ldap://127.0.0.1:389",
-			User:                 "cn=admin,dc=example,dc=com",
-			Password:             "password",
-			UsernameAttribute:    "uid",
-			MailAttribute:        "mail",
-			DisplayNameAttribute: "displayname",
-			UsersFilter:          "uid={input}",
-			AdditionalUsersDN:    "ou=users",
-			BaseDN:               "dc=example,dc=com",
+			URL: "ldap://127.0.0.1:389",
 		},
 		nil,
 		mockFactory)
@@ -246,89 +376,2103 @@ func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
 		Return(mockConn, nil)
 
 	mockConn.EXPECT().
-		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		UnauthenticatedBind(gomock.Eq("")).
 		Return(nil)
 
-	mockConn.EXPECT().
-		Close()
-
-	searchGroups := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(createSearchResultWithAttributes(), nil)
-	searchProfile := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(&ldap.SearchResult{
-			Entries: []*ldap.Entry{
-				{
-					DN: "uid=test,dc=example,dc=com",
-					Attributes: []*ldap.EntryAttribute{
-						{
-							Name:   "displayname",
-							Values: []string{"John Doe"},
-						},
-						{
-							Name:   "mail",
-							Values: []string{"test@example.com"},
-						},
-						{
-							Name:   "uid",
-							Values: []string{"john"},
-						},
-					},
-				},
-			},
-		}, nil)
-
-	gomock.InOrder(searchProfile, searchGroups)
+	_, err := ldapClient.connectAdmin(context.Background(), ldapClient.configuration.User, ldapClient.configuration.Password)
 
-	details, err := ldapClient.GetDetails("john")
 	require.NoError(t, err)
-
-	assert.ElementsMatch(t, details.Groups, []string{})
-	assert.ElementsMatch(t, details.Emails, []string{"test@example.com"})
-	assert.Equal(t, details.DisplayName, "John Doe")
-	assert.Equal(t, details.Username, "john")
 }
 
-func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
+func TestShouldNeverBindUserAnonymously(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockFactory := NewMockLDAPConnectionFactory(ctrl)
-	mockConn := NewMockLDAPConnection(ctrl)
-
+	// The admin user binds anonymously while the users are rejected without dialing, the mock fails on any dial.
 	ldapClient := NewLDAPUserProviderWithFactory(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:               "ldap://127.0.0.1:389",
-			User:              "cn=admin,dc=example,dc=com",
-			Password:          "password",
-			UsernameAttribute: "uid",
-			UsersFilter:       "uid={input}",
-			AdditionalUsersDN: "ou=users",
-			BaseDN:            "dc=example,dc=com",
+			URL: "ldap://127.0.0.1:389",
 		},
 		nil,
-		mockFactory)
+		NewMockLDAPConnectionFactory(ctrl))
 
-	mockFactory.EXPECT().
-		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
-		Return(mockConn, nil)
+	_, _, err := ldapClient.connectUser(context.Background(), "john", &ldapUserProfile{}, "password")
+	assert.EqualError(t, err, "invalid credentials for user john. Cause: the DN of the user is empty")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
 
-	mockConn.EXPECT().
-		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
-		Return(nil)
+	_, _, err = ldapClient.connectUser(context.Background(), "john", &ldapUserProfile{DN: "uid=john,dc=example,dc=com"}, "")
+	assert.EqualError(t, err, "invalid credentials for user john. Cause: the password is empty")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
 
-	mockConn.EXPECT().
-		Close()
+func TestShouldReadDNFromDNAttribute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-	searchGroups := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(createSearchResultWithAttributeValues("group1", "group2"), nil)
-	searchProfile := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(&ldap.SearchResult{
-			Entries: []*ldap.Entry{
-				{
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	// The entry is returned without its DN which is only held by the entryDN virtual attribute.
+	searchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			ldap.NewEntry("", map[string][]string{
+				"uid":     {"john"},
+				"entryDN": {"uid=john,dc=example,dc=com"},
+			}),
+		},
+	}
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(searchResult, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestShouldBindWithClientCertificateWhenAuthMethodIsExternal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:        "ldaps://127.0.0.1:636",
+			AuthMethod: schema.LDAPAuthMethodExternal,
+			User:       "cn=admin,dc=example,dc=com",
+			Password:   "password",
+			TLS: &schema.TLSConfig{
+				Certificate: "../suites/common/ssl/cert.pem",
+				Key:         "../suites/common/ssl/key.pem",
+			},
+		},
+		nil,
+		mockFactory)
+
+	require.Len(t, ldapClient.tlsConfig.Certificates, 1)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldaps://127.0.0.1:636"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			ExternalBind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	conn, err := ldapClient.connectSearch(context.Background())
+	require.NoError(t, err)
+
+	conn.Close()
+}
+
+func TestEscapeSpecialCharsFromUserInput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL: "ldaps://127.0.0.1:389",
+		},
+		nil,
+		mockFactory)
+
+	// No escape
+	assert.Equal(t, "xyz", ldapClient.ldapEscape("xyz"))
+
+	// Escape
+	assert.Equal(t, "test\\2cabc", ldapClient.ldapEscape("test,abc"))
+	assert.Equal(t, "test\\5cabc", ldapClient.ldapEscape("test\\abc"))
+	assert.Equal(t, "test\\2aabc", ldapClient.ldapEscape("test*abc"))
+	assert.Equal(t, "test \\28abc\\29", ldapClient.ldapEscape("test (abc)"))
+	assert.Equal(t, "test\\23abc", ldapClient.ldapEscape("test#abc"))
+	assert.Equal(t, "test\\2babc", ldapClient.ldapEscape("test+abc"))
+	assert.Equal(t, "test\\3cabc", ldapClient.ldapEscape("test<abc"))
+	assert.Equal(t, "test\\3eabc", ldapClient.ldapEscape("test>abc"))
+	assert.Equal(t, "test\\3babc", ldapClient.ldapEscape("test;abc"))
+	assert.Equal(t, "test\\22abc", ldapClient.ldapEscape("test\"abc"))
+	assert.Equal(t, "test\\3dabc", ldapClient.ldapEscape("test=abc"))
+	assert.Equal(t, "test\\2c\\5c\\28abc\\29", ldapClient.ldapEscape("test,\\(abc)"))
+	assert.Equal(t, "test\\00abc", ldapClient.ldapEscape("test\x00abc"))
+}
+
+func TestShouldProduceValidFiltersFromEdgeCaseUsernames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:         "ldaps://127.0.0.1:389",
+			UsersFilter: "(&(uid={input})(objectClass=person))",
+		},
+		nil,
+		mockFactory)
+
+	for _, username := range []string{"name+surname", "name\x00surname", "name,surname=x", "(name)*\\", "ñame#"} {
+		filter := ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, username)
+
+		packet, err := ldap.CompileFilter(filter)
+		require.NoError(t, err, "filter %s of username %q is malformed", filter, username)
+
+		decompiled, err := ldap.DecompileFilter(packet)
+		require.NoError(t, err)
+
+		// The decompiled filter carries the escaped values of the ldap library which must match the username exactly.
+		assert.Equal(t, "(&(uid="+ldap.EscapeFilter(username)+")(objectClass=person))", decompiled)
+	}
+}
+
+func TestShouldNormalizeUsernamesBeforeResolvingFilters(t *testing.T) {
+	composed, decomposed := "Jos\u00e9", "Jose\u0301"
+
+	testCases := []struct {
+		normalization string
+		equal         bool
+	}{
+		{"", true},
+		{schema.LDAPUsernameNormalizationNFC, true},
+		{schema.LDAPUsernameNormalizationNFD, true},
+		{schema.LDAPUsernameNormalizationNFKC, true},
+		{schema.LDAPUsernameNormalizationNone, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.normalization, func(t *testing.T) {
+			ldapClient := NewLDAPUserProvider(
+				schema.LDAPAuthenticationBackendConfiguration{
+					UsersFilter:           "(uid={input})",
+					GroupsFilter:          "(memberUid={input})",
+					UsernameNormalization: tc.normalization,
+				},
+				nil)
+
+			usersFilter := ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, decomposed)
+			groupsFilter, err := ldapClient.resolveGroupsFilter(decomposed, nil)
+			require.NoError(t, err)
+
+			if tc.equal {
+				assert.Equal(t, ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, composed), usersFilter)
+				assert.Equal(t, ldapClient.cacheKey(composed), ldapClient.cacheKey(decomposed))
+			} else {
+				assert.Equal(t, "(uid="+ldap.EscapeFilter(decomposed)+")", usersFilter)
+				assert.NotEqual(t, ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, composed), usersFilter)
+			}
+
+			assert.Equal(t, strings.Replace(usersFilter, "uid", "memberUid", 1), groupsFilter)
+		})
+	}
+
+	ldapClient := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{UsersFilter: "(uid={input})"}, nil)
+
+	assert.Equal(t, "(uid=Jos\\c3\\a9)", ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, decomposed))
+}
+
+func TestShouldEscapeConfiguredCharacters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldaps://127.0.0.1:389",
+			EscapedCharacters: "+/",
+		},
+		nil,
+		mockFactory)
+
+	assert.Equal(t, "name\\2bsurname\\2fx,y", ldapClient.ldapEscape("name+surname/x,y"))
+	assert.Equal(t, "\\00\\2a", ldapClient.ldapEscape("\x00*"))
+}
+
+func TestShouldNotEscapeTheEscapeSequencesAgain(t *testing.T) {
+	// The hexadecimal digits are escaped like any other configured character without altering the escape sequences.
+	ldapClient := NewLDAPUserProvider(schema.LDAPAuthenticationBackendConfiguration{EscapedCharacters: "a2\\"}, nil)
+
+	assert.Equal(t, "\\61\\2a", ldapClient.ldapEscape("a*"))
+	assert.Equal(t, "j\\61ne\\5c\\32\\28", ldapClient.ldapEscape("jane\\2("))
+	assert.Equal(t, "Jos\\c3\\a9\\ff", ldapClient.ldapEscape("Jos\u00e9\xff"))
+
+	_, err := ldap.CompileFilter("(uid=" + ldapClient.ldapEscape("a*(2)\\") + ")")
+	assert.NoError(t, err)
+}
+
+func TestEscapeSpecialCharsInGroupsFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:          "ldaps://127.0.0.1:389",
+			GroupsFilter: "(|(member={dn})(uid={username})(uid={input}))",
+		},
+		nil,
+		mockFactory)
+
+	profile := ldapUserProfile{
+		DN:          "cn=john (external),dc=example,dc=com",
+		Username:    "john",
+		DisplayName: "John Doe",
+		Emails:      []string{"john.doe@authelia.com"},
+	}
+
+	filter, _ := ldapClient.resolveGroupsFilter("john", &profile)
+	assert.Equal(t, "(|(member=cn=john \\28external\\29,dc=example,dc=com)(uid=john)(uid=john))", filter)
+
+	filter, _ = ldapClient.resolveGroupsFilter("john#=(abc,def)", &profile)
+	assert.Equal(t, "(|(member=cn=john \\28external\\29,dc=example,dc=com)(uid=john)(uid=john\\23\\3d\\28abc\\2cdef\\29))", filter)
+}
+
+func TestShouldRetrieveRFC2307GroupsByUsername(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "uid",
+			UsersFilter:        "(uid={input})",
+			GroupsFilter:       "(&(memberUid={username})(objectClass=posixGroup))",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=John\\2a)")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john*,ou=people,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{Name: "uid", Values: []string{"john*"}},
+						},
+					},
+				},
+			}, nil),
+		// The memberUid holds the username stored in the directory, not the DN nor the input of the user.
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(&(memberUid=john\\2a)(objectClass=posixGroup))")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN:         "cn=developers,ou=groups,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{{Name: "cn", Values: []string{"developers"}}},
+					},
+					{
+						DN:         "cn=users,ou=groups,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{{Name: "cn", Values: []string{"users"}}},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("John*")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john*", details.Username)
+	assert.Equal(t, []string{"developers", "users"}, details.Groups)
+}
+
+func TestShouldRestrictGroupsFilterToGroupNamePatterns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldaps://127.0.0.1:389",
+			GroupsFilter:       "(member={dn})",
+			GroupNameAttribute: "cn",
+			GroupNamePatterns:  []string{"admins", "app-*", "dev (ops)"},
+		},
+		nil,
+		mockFactory)
+
+	profile := ldapUserProfile{
+		DN:       "uid=john,dc=example,dc=com",
+		Username: "john",
+	}
+
+	filter, _ := ldapClient.resolveGroupsFilter("john", &profile)
+	assert.Equal(t, "(&(member=uid=john,dc=example,dc=com)(|(cn=admins)(cn=app-*)(cn=dev \\28ops\\29)))", filter)
+
+	assert.Equal(t, "", ldapGroupNamesFilter([]string{"cn"}, nil))
+	assert.Equal(t, "(cn=*-admins)", ldapGroupNamesFilter([]string{"cn"}, []string{"*-admins"}))
+	assert.Equal(t, "(|(cn=*-admins)(name=*-admins))", ldapGroupNamesFilter([]string{"cn", "name"}, []string{"*-admins"}))
+}
+
+type SearchRequestMatcher struct {
+	expected string
+}
+
+func NewSearchRequestMatcher(expected string) *SearchRequestMatcher {
+	return &SearchRequestMatcher{expected}
+}
+
+func (srm *SearchRequestMatcher) Matches(x interface{}) bool {
+	sr := x.(*ldap.SearchRequest)
+	return sr.Filter == srm.expected
+}
+
+func (srm *SearchRequestMatcher) String() string {
+	return ""
+}
+
+type SearchRequestBaseDNMatcher struct {
+	expected string
+}
+
+func NewSearchRequestBaseDNMatcher(expected string) *SearchRequestBaseDNMatcher {
+	return &SearchRequestBaseDNMatcher{expected}
+}
+
+func (srm *SearchRequestBaseDNMatcher) Matches(x interface{}) bool {
+	sr := x.(*ldap.SearchRequest)
+	return sr.BaseDN == srm.expected
+}
+
+func (srm *SearchRequestBaseDNMatcher) String() string {
+	return srm.expected
+}
+
+type SearchRequestAttributesMatcher struct {
+	expected []string
+}
+
+func NewSearchRequestAttributesMatcher(expected ...string) *SearchRequestAttributesMatcher {
+	return &SearchRequestAttributesMatcher{expected}
+}
+
+func (srm *SearchRequestAttributesMatcher) Matches(x interface{}) bool {
+	sr := x.(*ldap.SearchRequest)
+	return reflect.DeepEqual(sr.Attributes, srm.expected)
+}
+
+func (srm *SearchRequestAttributesMatcher) String() string {
+	return strings.Join(srm.expected, ",")
+}
+
+func TestShouldReturnGroupDisplayNames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                       "ldap://127.0.0.1:389",
+			User:                      "cn=admin,dc=example,dc=com",
+			Password:                  "password",
+			UsernameAttribute:         "uid",
+			UsersFilter:               "(uid={input})",
+			GroupsFilter:              "(member={dn})",
+			GroupNameAttribute:        "cn",
+			GroupDisplayNameAttribute: "displayname",
+			BaseDN:                    "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{"uid": {"john"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestAttributesMatcher("cn", "displayname")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "cn=admins,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "displayName",
+								Values: []string{"Administrators"},
+							},
+							{
+								Name:   "cn",
+								Values: []string{"admins"},
+							},
+						},
+					},
+					ldap.NewEntry("cn=dev,dc=example,dc=com", map[string][]string{"cn": {"dev"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "dev"}, details.Groups)
+	assert.Equal(t, map[string]string{"admins": "Administrators"}, details.GroupDisplayNames)
+}
+
+func TestShouldReturnGroupNamesFromFallbackAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                         "ldap://127.0.0.1:389",
+			User:                        "cn=admin,dc=example,dc=com",
+			Password:                    "password",
+			UsernameAttribute:           "uid",
+			UsersFilter:                 "(uid={input})",
+			GroupsFilter:                "(member={dn})",
+			GroupNameAttribute:          "cn",
+			GroupNameAttributeFallbacks: []string{"mailNickname", "name"},
+			BaseDN:                      "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					ldap.NewEntry("uid=john,dc=example,dc=com", map[string][]string{"uid": {"john"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestAttributesMatcher("cn", "mailNickname", "name")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					// The security group exposes both cn and name, cn is preferred.
+					{
+						DN: "cn=admins,ou=security,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "name",
+								Values: []string{"Administrators"},
+							},
+							{
+								Name:   "cn",
+								Values: []string{"admins"},
+							},
+						},
+					},
+					ldap.NewEntry("ou=staff,ou=distribution,dc=example,dc=com", map[string][]string{"mailnickname": {"staff"}, "name": {"All Staff"}}),
+					ldap.NewEntry("ou=dev,ou=distribution,dc=example,dc=com", map[string][]string{"name": {"dev"}}),
+					ldap.NewEntry("ou=unnamed,ou=distribution,dc=example,dc=com", map[string][]string{"description": {"no name"}}),
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "staff", "dev"}, details.Groups)
+}
+
+func TestShouldNormalizeGroupNames(t *testing.T) {
+	testCases := []struct {
+		normalization        string
+		expectedGroups       []string
+		expectedDisplayNames map[string]string
+	}{
+		{schema.LDAPGroupNameNormalizationNone, []string{"Admins", " admins", "Dev "}, map[string]string{"Admins": "Administrators"}},
+		{schema.LDAPGroupNameNormalizationTrim, []string{"Admins", "admins", "Dev"}, map[string]string{"Admins": "Administrators"}},
+		{schema.LDAPGroupNameNormalizationLowercase, []string{"admins", "dev"}, map[string]string{"admins": "Administrators"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.normalization, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+			ldapClient.configuration.GroupDisplayNameAttribute = "displayname"
+			ldapClient.configuration.GroupNameNormalization = tc.normalization
+
+			gomock.InOrder(
+				mockFactory.EXPECT().
+					DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+					Return(mockConn, nil),
+				mockConn.EXPECT().
+					Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+					Return(nil),
+				mockConn.EXPECT().
+					Search(NewSearchRequestMatcher("(uid=john)")).
+					Return(userCheckTestSearchResult(), nil),
+				mockConn.EXPECT().
+					Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+					Return(&ldap.SearchResult{
+						Entries: []*ldap.Entry{
+							ldap.NewEntry("cn=Admins,dc=example,dc=com", map[string][]string{"cn": {"Admins"}, "displayName": {"Administrators"}}),
+							ldap.NewEntry("cn=admins,ou=legacy,dc=example,dc=com", map[string][]string{"cn": {" admins"}}),
+							ldap.NewEntry("cn=Dev,dc=example,dc=com", map[string][]string{"cn": {"Dev "}}),
+						},
+					}, nil),
+				mockConn.EXPECT().
+					Unbind().
+					Return(nil),
+				mockConn.EXPECT().
+					Close(),
+			)
+
+			details, err := ldapClient.GetDetails("john")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedGroups, details.Groups)
+			assert.Equal(t, tc.expectedDisplayNames, details.GroupDisplayNames)
+		})
+	}
+}
+
+func TestShouldEscapeUserInput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			UsersFilter:          "(|({username_attribute}={input})({mail_attribute}={input}))",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			Password:             "password",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		// Here we ensure that the input has been correctly escaped.
+		Search(NewSearchRequestMatcher("(|(uid=john\\3dabc)(mail=john\\3dabc))")).
+		Return(&ldap.SearchResult{}, nil)
+
+	_, err := ldapClient.getUserProfile(context.Background(), mockConn, "john=abc")
+	require.Error(t, err)
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestShouldCombineUsernameFilterAndUsersFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			UsernameAttribute:    "uid",
+			UsersFilter:          "(&({username_attribute}={input})(&(objectCategory=person)(objectClass=user)))",
+			Password:             "password",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(&(uid=john)(&(objectCategory=person)(objectClass=user)))")).
+		Return(&ldap.SearchResult{}, nil)
+
+	_, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	require.Error(t, err)
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestShouldIgnoreReferralsByDefault(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(uid={input})",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Referrals: []string{"ldap://dc2.example.com/dc=child,dc=example,dc=com"},
+		}, nil)
+
+	_, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestShouldFollowReferralsWhenEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+	mockReferralConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldaps://dc1.example.com",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(uid={input})",
+			BaseDN:            "dc=example,dc=com",
+			FollowReferrals:   true,
+			ReferralHosts:     []string{"example.com"},
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Referrals: []string{"ldaps://dc2.example.com/dc=child,dc=example,dc=com"},
+			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldaps://dc2.example.com"), gomock.Any()).
+			Return(mockReferralConn, nil),
+		mockReferralConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockReferralConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("dc=child,dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=child,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockReferralConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockReferralConn.EXPECT().
+			Close(),
+	)
+
+	profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "uid=john,dc=child,dc=example,dc=com", profile.DN)
+	assert.Equal(t, "john", profile.Username)
+}
+
+func TestShouldNotFollowReferralsToForeignHosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldaps://dc1.example.com",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(uid={input})",
+			BaseDN:            "dc=example,dc=com",
+			FollowReferrals:   true,
+			ReferralHosts:     []string{"child.example.com"},
+		},
+		nil,
+		mockFactory)
+
+	// The credentials must neither be sent to a host outside of the referral hosts nor in clear text.
+	mockConn.EXPECT().
+		Search(NewSearchRequestBaseDNMatcher("dc=example,dc=com")).
+		Return(&ldap.SearchResult{
+			Referrals: []string{
+				"ldaps://evil.example.org/dc=child,dc=example,dc=com",
+				"ldaps://dc1.example.com.evil.example.org/dc=child,dc=example,dc=com",
+				"ldap://dc2.child.example.com/dc=child,dc=example,dc=com",
+			},
+		}, nil)
+
+	_, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestShouldCheckReferrals(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:              "ldap://dc1.example.com",
+			GlobalCatalogURL: "ldap://gc.example.com:3268",
+			StartTLS:         true,
+			ReferralHosts:    []string{".Child.Example.com"},
+		},
+		nil)
+
+	testCases := []struct {
+		referral string
+		err      string
+	}{
+		{"ldap://dc1.example.com/dc=example,dc=com", ""},
+		{"ldaps://GC.example.com/dc=example,dc=com", ""},
+		{"ldap://dc2.child.example.com./dc=child,dc=example,dc=com", ""},
+		{"ldap://child.example.com/dc=child,dc=example,dc=com", ""},
+		{"ldap://example.com/dc=example,dc=com", "the host example.com is not one of the referral hosts"},
+		{"ldap://notchild.example.com/dc=example,dc=com", "the host notchild.example.com is not one of the referral hosts"},
+		{"cldap://dc1.example.com/dc=example,dc=com", "the referral does not use TLS, the cldap scheme is only followed when start_tls is enabled"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.referral, func(t *testing.T) {
+			referralURL, err := url.Parse(tc.referral)
+			require.NoError(t, err)
+
+			err = ldapClient.checkReferral(referralURL)
+
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.err)
+			}
+		})
+	}
+
+	ldapClient.configuration.StartTLS = false
+
+	referralURL, err := url.Parse("ldap://dc1.example.com/dc=example,dc=com")
+	require.NoError(t, err)
+
+	assert.EqualError(t, ldapClient.checkReferral(referralURL), "the referral does not use TLS, the ldap scheme is only followed when start_tls is enabled")
+}
+
+func createSearchResultWithAttributes(attributes ...*ldap.EntryAttribute) *ldap.SearchResult {
+	return &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{Attributes: attributes},
+		},
+	}
+}
+
+func createSearchResultWithAttributeValues(values ...string) *ldap.SearchResult {
+	return createSearchResultWithAttributes(&ldap.EntryAttribute{
+		Name:   "cn",
+		Values: values,
+	})
+}
+
+func TestShouldNotCrashWhenGroupsAreNotRetrievedFromLDAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributes(), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "displayname",
+							Values: []string{"John Doe"},
+						},
+						{
+							Name:   "mail",
+							Values: []string{"test@example.com"},
+						},
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, details.Groups, []string{})
+	assert.ElementsMatch(t, details.Emails, []string{"test@example.com"})
+	assert.Equal(t, details.DisplayName, "John Doe")
+	assert.Equal(t, details.Username, "john")
+}
+
+func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			AdditionalUsersDN: "ou=users",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1", "group2"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, details.Groups, []string{"group1", "group2"})
+	assert.ElementsMatch(t, details.Emails, []string{})
+	assert.Equal(t, details.Username, "john")
+}
+
+func TestShouldReturnUsernameFromLDAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1", "group2"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "displayname",
+							Values: []string{"John Doe"},
+						},
+						{
+							Name:   "mail",
+							Values: []string{"test@example.com"},
+						},
+						{
+							Name:   "uid",
+							Values: []string{"John"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, details.Groups, []string{"group1", "group2"})
+	assert.ElementsMatch(t, details.Emails, []string{"test@example.com"})
+	assert.Equal(t, details.DisplayName, "John Doe")
+	assert.Equal(t, details.Username, "John")
+}
+
+func TestShouldSelectGroupNamesByAttributeName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	// The LDAP server returns an attribute which wasn't requested before the group name attribute, and a group without
+	// the group name attribute which is skipped.
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "cn=admins,ou=groups,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{Name: "objectClass", Values: []string{"groupOfNames"}},
+						{Name: "cn", Values: []string{"admins"}},
+					},
+				},
+				{
+					DN: "cn=dev,ou=groups,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{Name: "objectClass", Values: []string{"groupOfNames"}},
+					},
+				},
+				{
+					DN: "cn=ops,ou=groups,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{Name: "CN", Values: []string{"ops"}},
+					},
+				},
+			},
+		}, nil)
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", &ldapUserProfile{
+		DN:       "uid=john,ou=users,dc=example,dc=com",
+		Username: "john",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "ops"}, details.Groups)
+}
+
+func TestShouldReturnUsernameAndEmailsFromFallbackAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                        "ldap://127.0.0.1:389",
+			User:                       "cn=admin,dc=example,dc=com",
+			Password:                   "password",
+			UsernameAttribute:          "sAMAccountName",
+			UsernameAttributeFallbacks: []string{"uid"},
+			MailAttribute:              "mail",
+			MailAttributeFallbacks:     []string{"otherMailbox"},
+			DisplayNameAttribute:       "displayName",
+			UsersFilter:                "(|(sAMAccountName={input})(uid={input}))",
+			BaseDN:                     "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestAttributesMatcher("displayName", "mail", "otherMailbox", "sAMAccountName", "uid", "entryDN", "pwdChangedTime", "pwdPolicySubentry", "pwdReset")).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=svc-backup,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"svc-backup"},
+						},
+						{
+							Name:   "otherMailbox",
+							Values: []string{"backup@example.com"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "svc-backup")
+	require.NoError(t, err)
+
+	assert.Equal(t, "svc-backup", profile.Username)
+	assert.Equal(t, []string{"backup@example.com"}, profile.Emails)
+	assert.Equal(t, "(|(mail={input})(otherMailbox={input}))", ldapClient.mailFilter)
+}
+
+func TestShouldPreferFirstPopulatedUsernameAttribute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                        "ldap://127.0.0.1:389",
+			User:                       "cn=admin,dc=example,dc=com",
+			Password:                   "password",
+			UsernameAttribute:          "sAMAccountName",
+			UsernameAttributeFallbacks: []string{"uid"},
+			UsersFilter:                "(|(sAMAccountName={input})(uid={input}))",
+			BaseDN:                     "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "cn=John,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john", "jdoe"},
+						},
+						{
+							Name:   "sAMAccountName",
+							Values: []string{"John"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	require.NoError(t, err)
+	assert.Equal(t, "John", profile.Username)
+}
+
+func TestShouldFailWhenSupplyingUsernameAttributeHasMultipleValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                        "ldap://127.0.0.1:389",
+			User:                       "cn=admin,dc=example,dc=com",
+			Password:                   "password",
+			UsernameAttribute:          "sAMAccountName",
+			UsernameAttributeFallbacks: []string{"uid"},
+			UsersFilter:                "(|(sAMAccountName={input})(uid={input}))",
+			BaseDN:                     "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "cn=John,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john", "jdoe"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	_, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	assert.EqualError(t, err, "User john cannot have multiple value for attribute uid")
+}
+
+func TestShouldReturnAdditionalAttributesFromLDAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			AdditionalAttributes: []string{"department", "employeeNumber", "telephoneNumber"},
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(NewSearchRequestAttributesMatcher("displayname", "mail", "uid", "department", "employeeNumber", "telephoneNumber", "entryDN", "pwdChangedTime", "pwdPolicySubentry", "pwdReset")).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+						{
+							Name:   "department",
+							Values: []string{"Engineering"},
+						},
+						{
+							Name:   "employeenumber",
+							Values: []string{"1234"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"department":     {"Engineering"},
+		"employeeNumber": {"1234"},
+	}, details.Extra)
+}
+
+func TestShouldReturnPhotoFromLDAP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			PhotoAttribute:       "jpegPhoto",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	// The header of a JPEG file followed by bytes which are not valid UTF-8.
+	photo := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0xc3, 0x28}
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(NewSearchRequestAttributesMatcher("displayname", "mail", "uid", "jpegPhoto", "entryDN", "pwdChangedTime", "pwdPolicySubentry", "pwdReset")).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+						{
+							Name:       "jpegPhoto",
+							Values:     []string{string(photo)},
+							ByteValues: [][]byte{photo},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, photo, details.Photo)
+	assert.Equal(t, "image/jpeg", details.PhotoMIMEType)
+}
+
+func TestShouldDetectPhotoMIMEType(t *testing.T) {
+	assert.Equal(t, "", photoMIMEType(nil))
+	assert.Equal(t, "image/png", photoMIMEType([]byte("\x89PNG\x0D\x0A\x1A\x0A")))
+	assert.Equal(t, "image/jpeg", photoMIMEType([]byte{0xff, 0xd8, 0xff}))
+}
+
+func TestShouldFallbackWhenDisplayNameOrMailAttributesAreMissing(t *testing.T) {
+	testCases := []struct {
+		name                string
+		attributes          []*ldap.EntryAttribute
+		expectedDisplayName string
+		expectedEmails      []string
+	}{
+		{
+			name: "ShouldUseDisplayNameAttribute",
+			attributes: []*ldap.EntryAttribute{
+				{Name: "displayName", Values: []string{"John Doe"}},
+				{Name: "cn", Values: []string{"john.doe"}},
+				{Name: "mail", Values: []string{"john@example.com"}},
+			},
+			expectedDisplayName: "John Doe",
+			expectedEmails:      []string{"john@example.com"},
+		},
+		{
+			name: "ShouldFallbackToDisplayNameAttributeFallbacks",
+			attributes: []*ldap.EntryAttribute{
+				{Name: "cn", Values: []string{"john.doe"}},
+				{Name: "mail", Values: []string{"john@example.com"}},
+			},
+			expectedDisplayName: "john.doe",
+			expectedEmails:      []string{"john@example.com"},
+		},
+		{
+			name: "ShouldFallbackToUsername",
+			attributes: []*ldap.EntryAttribute{
+				{Name: "mail", Values: []string{"john@example.com"}},
+			},
+			expectedDisplayName: "john",
+			expectedEmails:      []string{"john@example.com"},
+		},
+		{
+			name: "ShouldNotFailWithoutMail",
+			attributes: []*ldap.EntryAttribute{
+				{Name: "displayName", Values: []string{"John Doe"}},
+			},
+			expectedDisplayName: "John Doe",
+		},
+		{
+			name: "ShouldIgnoreAttributesWithoutValues",
+			attributes: []*ldap.EntryAttribute{
+				{Name: "displayName", Values: []string{}},
+				{Name: "cn", Values: []string{}},
+				{Name: "mail", Values: []string{}},
+				{Name: "department", Values: []string{}},
+			},
+			expectedDisplayName: "john",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockFactory := NewMockLDAPConnectionFactory(ctrl)
+			mockConn := NewMockLDAPConnection(ctrl)
+
+			ldapClient := NewLDAPUserProviderWithFactory(
+				schema.LDAPAuthenticationBackendConfiguration{
+					URL:                           "ldap://127.0.0.1:389",
+					UsernameAttribute:             "uid",
+					MailAttribute:                 "mail",
+					DisplayNameAttribute:          "displayName",
+					DisplayNameAttributeFallbacks: []string{"cn"},
+					AdditionalAttributes:          []string{"department"},
+					UsersFilter:                   "(uid={input})",
+					BaseDN:                        "dc=example,dc=com",
+				},
+				nil,
+				mockFactory)
+
+			mockConn.EXPECT().
+				Search(NewSearchRequestAttributesMatcher("displayName", "cn", "mail", "uid", "department", "entryDN", "pwdChangedTime", "pwdPolicySubentry", "pwdReset")).
+				Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN:         "uid=john,dc=example,dc=com",
+							Attributes: append([]*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}, tc.attributes...),
+						},
+					},
+				}, nil)
+
+			profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedDisplayName, profile.DisplayName)
+			assert.Equal(t, tc.expectedEmails, profile.Emails)
+			assert.Empty(t, profile.Extra)
+		})
+	}
+}
+
+func TestShouldReturnCachedDetailsWhenCacheEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			AdditionalUsersDN: "ou=users",
+			BaseDN:            "dc=example,dc=com",
+			GroupsCacheTTL:    "5m",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchGroups := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithAttributeValues("group1"), nil)
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
+					DN: "uid=test,dc=example,dc=com",
+					Attributes: []*ldap.EntryAttribute{
+						{
+							Name:   "uid",
+							Values: []string{"john"},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	cached, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, details, cached)
+	assert.ElementsMatch(t, cached.Groups, []string{"group1"})
+}
+
+func TestShouldShareCachedDetailsWhenUsernamesAreCaseInsensitive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			Implementation:           schema.LDAPImplementationActiveDirectory,
+			URL:                      "ldap://127.0.0.1:389",
+			User:                     "cn=admin,dc=example,dc=com",
+			Password:                 "password",
+			UsernameAttribute:        "sAMAccountName",
+			UsersFilter:              "(sAMAccountName={input})",
+			GroupsFilter:             "(member={dn})",
+			GroupNameAttribute:       "cn",
+			BaseDN:                   "dc=example,dc=com",
+			GroupsCacheTTL:           "5m",
+			CaseInsensitiveUsernames: true,
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(&(sAMAccountName=JDoe)(objectCategory=person)(objectClass=user))")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "cn=John Doe,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "sAMAccountName",
+								Values: []string{"jdoe"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=cn=John Doe,dc=example,dc=com)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("JDoe")
+	require.NoError(t, err)
+
+	assert.Equal(t, "jdoe", details.Username)
+
+	cached, err := ldapClient.GetDetails("jdoe")
+	require.NoError(t, err)
+
+	assert.Equal(t, details, cached)
+}
+
+func TestShouldRetrievePasswordTimestampsFromPasswordPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "uid",
+			UsersFilter:        "(uid={input})",
+			GroupsFilter:       "(member={dn})",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "pwdChangedTime",
+								Values: []string{"20210101120000Z"},
+							},
+							{
+								Name:   "pwdPolicySubentry",
+								Values: []string{"cn=default,ou=policies,dc=example,dc=com"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("cn=default,ou=policies,dc=example,dc=com")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "cn=default,ou=policies,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "pwdMaxAge",
+								Values: []string{"86400"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC), details.PasswordLastSet)
+	assert.Equal(t, time.Date(2021, time.January, 2, 12, 0, 0, 0, time.UTC), details.PasswordExpires)
+	assert.True(t, details.AccountExpires.IsZero())
+	assert.False(t, details.MustChangePassword)
+}
+
+func TestShouldRetrievePasswordTimestampsFromActiveDirectory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			Implementation:     schema.LDAPImplementationActiveDirectory,
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "sAMAccountName",
+			UsersFilter:        "(sAMAccountName={input})",
+			GroupsFilter:       "(member={dn})",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "cn=John,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "sAMAccountName",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "pwdLastSet",
+								Values: []string{"132539328000000000"},
+							},
+							{
+								Name:   "msDS-UserPasswordExpiryTimeComputed",
+								Values: []string{"9223372036854775807"},
+							},
+							{
+								Name:   "accountExpires",
+								Values: []string{"132540192000000000"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), details.PasswordLastSet)
+	assert.True(t, details.PasswordExpires.IsZero())
+	assert.Equal(t, time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC), details.AccountExpires)
+	assert.False(t, details.MustChangePassword)
+}
+
+func TestShouldRetrieveDetailsByEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			MailAttribute:     "mail",
+			UsersFilter:       "(uid={input})",
+			GroupsFilter:      "(memberUid={input})",
+			AdditionalUsersDN: "ou=users",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(mail=john.doe@example.com)")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,ou=users,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "mail",
+								Values: []string{"john.doe@example.com"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(memberUid=john)")).
+			Return(createSearchResultWithAttributeValues("group1"), nil),
+	)
+
+	details, err := ldapClient.GetDetailsByEmail("john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+	assert.ElementsMatch(t, details.Emails, []string{"john.doe@example.com"})
+	assert.ElementsMatch(t, details.Groups, []string{"group1"})
+}
+
+func TestShouldFailToRetrieveDetailsByEmailWhenMultipleUsersMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			MailAttribute:     "mail",
+			UsersFilter:       "(uid={input})",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	mockConn.EXPECT().
+		Search(NewSearchRequestMatcher("(mail=shared@example.com)")).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{DN: "uid=john,dc=example,dc=com"},
+				{DN: "uid=harry,dc=example,dc=com"},
+			},
+		}, nil)
+
+	_, err := ldapClient.GetDetailsByEmail("shared@example.com")
+	assert.EqualError(t, err, "multiple users found with input shared@example.com")
+	assert.True(t, errors.Is(err, ErrMultipleUsersFound))
+}
+
+func TestShouldSelectUserAccordingToMultipleUsersPolicy(t *testing.T) {
+	entries := []*ldap.Entry{
+		{DN: "uid=john,ou=contractors,dc=example,dc=com", Attributes: []*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}},
+		{DN: "uid=john,ou=Employees,dc=example,dc=com", Attributes: []*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}},
+		{DN: "uid=john,ou=archive,dc=example,dc=com", Attributes: []*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}},
+	}
+
+	testCases := []struct {
+		name              string
+		policy            string
+		preferredUsersDN  string
+		expectedSizeLimit int
+		expectedDN        string
+		expectedErr       string
+	}{
+		{
+			name:              "ShouldFailWithError",
+			policy:            schema.LDAPMultipleUsersPolicyError,
+			expectedSizeLimit: 2,
+			expectedErr:       "multiple users found with input john",
+		},
+		{
+			name:              "ShouldSelectFirstSortedByDN",
+			policy:            schema.LDAPMultipleUsersPolicyFirst,
+			expectedSizeLimit: 0,
+			expectedDN:        "uid=john,ou=archive,dc=example,dc=com",
+		},
+		{
+			name:              "ShouldSelectUserInPreferredDN",
+			policy:            schema.LDAPMultipleUsersPolicyPreferredDN,
+			preferredUsersDN:  "ou=employees",
+			expectedSizeLimit: 0,
+			expectedDN:        "uid=john,ou=Employees,dc=example,dc=com",
+		},
+		{
+			name:              "ShouldFailWithoutUserInPreferredDN",
+			policy:            schema.LDAPMultipleUsersPolicyPreferredDN,
+			preferredUsersDN:  "ou=admins",
+			expectedSizeLimit: 0,
+			expectedErr:       "multiple users found with input john, 0 of them in the preferred users DN ou=admins,dc=example,dc=com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockFactory := NewMockLDAPConnectionFactory(ctrl)
+			mockConn := NewMockLDAPConnection(ctrl)
+
+			ldapClient := NewLDAPUserProviderWithFactory(
+				schema.LDAPAuthenticationBackendConfiguration{
+					URL:                 "ldap://127.0.0.1:389",
+					UsernameAttribute:   "uid",
+					UsersFilter:         "(uid={input})",
+					BaseDN:              "dc=example,dc=com",
+					MultipleUsersPolicy: tc.policy,
+					PreferredUsersDN:    tc.preferredUsersDN,
+				},
+				nil,
+				mockFactory)
+
+			mockConn.EXPECT().
+				Search(gomock.Any()).
+				DoAndReturn(func(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+					assert.Equal(t, tc.expectedSizeLimit, searchRequest.SizeLimit)
+
+					return &ldap.SearchResult{Entries: entries}, nil
+				})
+
+			profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				assert.True(t, errors.Is(err, ErrMultipleUsersFound))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDN, profile.DN)
+		})
+	}
+}
+
+func TestShouldDetectAmbiguousUsersWithSizeLimitOfTwo(t *testing.T) {
+	john := &ldap.Entry{DN: "uid=john,ou=people,dc=example,dc=com", Attributes: []*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}}
+	other := &ldap.Entry{DN: "uid=john,ou=contractors,dc=example,dc=com", Attributes: []*ldap.EntryAttribute{{Name: "uid", Values: []string{"john"}}}}
+	sizeLimitErr := ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+
+	testCases := []struct {
+		name       string
+		result     *ldap.SearchResult
+		err        error
+		expectedDN string
+	}{
+		{"OneMatch", &ldap.SearchResult{Entries: []*ldap.Entry{john}}, nil, "uid=john,ou=people,dc=example,dc=com"},
+		{"TwoMatches", &ldap.SearchResult{Entries: []*ldap.Entry{john, other}}, nil, ""},
+		{"SizeLimitExceededWithPartialEntries", &ldap.SearchResult{Entries: []*ldap.Entry{john}}, sizeLimitErr, ""},
+		{"SizeLimitExceededWithoutEntries", nil, sizeLimitErr, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+			mockConn.EXPECT().
+				Search(gomock.Any()).
+				DoAndReturn(func(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+					assert.Equal(t, 2, searchRequest.SizeLimit)
+
+					return tc.result, tc.err
+				})
+
+			profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+
+			if tc.expectedDN == "" {
+				assert.EqualError(t, err, "multiple users found with input john")
+				assert.True(t, errors.Is(err, ErrMultipleUsersFound))
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDN, profile.DN)
+		})
+	}
+}
+
+func TestShouldSearchGroupsWithPagingWhenPageSizeConfigured(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+			PageSize:             1000,
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	searchProfile := mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{
 					DN: "uid=test,dc=example,dc=com",
 					Attributes: []*ldap.EntryAttribute{
 						{
@@ -337,91 +2481,935 @@ func TestShouldNotCrashWhenEmailsAreNotRetrievedFromLDAP(t *testing.T) {
 						},
 					},
 				},
-			},
-		}, nil)
+			},
+		}, nil)
+	searchGroups := mockConn.EXPECT().
+		SearchWithPaging(gomock.Any(), gomock.Eq(uint32(1000))).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				{Attributes: []*ldap.EntryAttribute{{Name: "cn", Values: []string{"group1"}}}},
+				{Attributes: []*ldap.EntryAttribute{{Name: "cn", Values: []string{"group2"}}}},
+			},
+		}, nil)
+
+	gomock.InOrder(searchProfile, searchGroups)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, details.Groups, []string{"group1", "group2"})
+	assert.Equal(t, details.Username, "john")
+}
+
+func TestShouldReturnErrorWhenUserHasTooManyGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			GroupsFilter:       "(objectClass=group)",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+			MaxGroups:          2,
+		},
+		nil,
+		mockFactory)
+
+	profile := &ldapUserProfile{
+		DN:       "uid=john,dc=example,dc=com",
+		Username: "john",
+	}
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		DoAndReturn(func(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			assert.Equal(t, 2, searchRequest.SizeLimit)
+
+			return createSearchResultWithAttributeValues("group1", "group2", "group3"), nil
+		})
+
+	_, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", profile)
+
+	assert.True(t, errors.Is(err, ErrTooManyGroups))
+	assert.EqualError(t, err, "too many groups of user john, the maximum is 2")
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(nil, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")))
+
+	_, err = ldapClient.getUserDetails(context.Background(), mockConn, "john", profile)
+
+	assert.True(t, errors.Is(err, ErrTooManyGroups))
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				ldap.NewEntry("cn=group1,dc=example,dc=com", map[string][]string{"cn": {"group1"}}),
+				ldap.NewEntry("cn=group2,dc=example,dc=com", map[string][]string{"cn": {"group2"}}),
+			},
+		}, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")))
+
+	_, err = ldapClient.getUserDetails(context.Background(), mockConn, "john", profile)
+
+	assert.True(t, errors.Is(err, ErrTooManyGroups))
+}
+
+func TestShouldReturnPartialGroupsWhenServerEnforcesSmallerSizeLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			GroupsFilter:       "(member={dn})",
+			GroupNameAttribute: "cn",
+			BaseDN:             "dc=example,dc=com",
+			MaxGroups:          1000,
+		},
+		nil,
+		mockFactory)
+
+	profile := &ldapUserProfile{
+		DN:       "uid=john,dc=example,dc=com",
+		Username: "john",
+	}
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(&ldap.SearchResult{
+			Entries: []*ldap.Entry{
+				ldap.NewEntry("cn=admins,dc=example,dc=com", map[string][]string{"cn": {"admins"}}),
+				ldap.NewEntry("cn=dev,dc=example,dc=com", map[string][]string{"cn": {"dev"}}),
+			},
+		}, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded")))
+
+	details, err := ldapClient.getUserDetails(context.Background(), mockConn, "john", profile)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "dev"}, details.Groups)
+}
+
+func TestShouldPassHealthcheckWhenRootDSEAnswers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			BaseDN:   "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("")).
+			Return(&ldap.SearchResult{Entries: []*ldap.Entry{{DN: ""}}}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.NoError(t, ldapClient.Healthcheck())
+}
+
+func TestShouldFailHealthcheckWhenRootDSESearchFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			BaseDN:   "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, errors.New("LDAP Result Code 51 \"Busy\"")),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.EqualError(t, ldapClient.Healthcheck(), "Unable to search the root DSE. Cause: LDAP Result Code 51 \"Busy\"")
+}
+
+func TestShouldAppendPrimaryGroupWithActiveDirectory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			Implementation:       schema.LDAPImplementationActiveDirectory,
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "sAMAccountName",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayName",
+			GroupNameAttribute:   "cn",
+			UsersFilter:          "(sAMAccountName={input})",
+			GroupsFilter:         "(member={dn})",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
+	mockConn.EXPECT().
+		Close()
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestAttributesMatcher("displayName", "mail", "sAMAccountName", "objectGUID", "objectSid", "primaryGroupID",
+				"pwdLastSet", "accountExpires", "msDS-UserPasswordExpiryTimeComputed")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "CN=John,CN=Users,DC=example,DC=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "sAMAccountName",
+								Values: []string{"john"},
+							},
+							{
+								Name:       "objectSid",
+								Values:     []string{string(testUserSID)},
+								ByteValues: [][]byte{testUserSID},
+							},
+							{
+								Name:   "primaryGroupID",
+								Values: []string{"513"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=CN=John,CN=Users,DC=example,DC=com)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(objectSid=\\01\\05\\00\\00\\00\\00\\00\\05\\15\\00\\00\\00"+
+				"\\01\\00\\00\\00\\02\\00\\00\\00\\03\\00\\00\\00\\01\\02\\00\\00)")).
+			Return(createSearchResultWithAttributes(&ldap.EntryAttribute{
+				Name:   "cn",
+				Values: []string{"Domain Users"},
+			}), nil),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"admins", "Domain Users"}, details.Groups)
+}
+
+func TestShouldUpdateUserPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	modifyRequest := ldap.NewModifyRequest("uid=test,dc=example,dc=com", nil)
+	modifyRequest.Replace("userPassword", []string{"password"})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=test,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "displayname",
+								Values: []string{"John Doe"},
+							},
+							{
+								Name:   "mail",
+								Values: []string{"test@example.com"},
+							},
+							{
+								Name:   "uid",
+								Values: []string{"John"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Modify(modifyRequest).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	err := ldapClient.UpdatePassword("john", "password")
+
+	require.NoError(t, err)
+}
+
+func TestShouldUpdateUserPasswordWithPasswordModifyUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                    "ldap://127.0.0.1:389",
+			User:                   "cn=admin,dc=example,dc=com",
+			Password:               "password",
+			PasswordModifyUser:     "cn=password-admin,dc=example,dc=com",
+			PasswordModifyPassword: "modify-password",
+			UsernameAttribute:      "uid",
+			UsersFilter:            "uid={input}",
+			BaseDN:                 "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	modifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", nil)
+	modifyRequest.Replace("userPassword", []string{"new-password"})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=password-admin,dc=example,dc=com"), gomock.Eq("modify-password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Modify(modifyRequest).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	err := ldapClient.UpdatePassword("john", "new-password")
+
+	require.NoError(t, err)
+}
+
+func TestShouldUpdateUserPasswordWithPasswordModifyExtendedOperation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                             "ldap://127.0.0.1:389",
+			User:                            "cn=admin,dc=example,dc=com",
+			Password:                        "password",
+			PasswordModifyExtendedOperation: true,
+			UsernameAttribute:               "uid",
+			UsersFilter:                     "uid={input}",
+			BaseDN:                          "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			PasswordModify(ldap.NewPasswordModifyRequest("uid=john,dc=example,dc=com", "", "new-password")).
+			Return(&ldap.PasswordModifyResult{}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	err := ldapClient.UpdatePassword("john", "new-password")
+
+	require.NoError(t, err)
+}
+
+func TestShouldNotUpdateUserPasswordViolatingPasswordPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+			PasswordPolicy: schema.LDAPPasswordPolicyConfiguration{
+				MinLength: 8,
+			},
+		},
+		nil,
+		mockFactory)
+
+	err := ldapClient.UpdatePassword("john", "short")
+
+	assert.EqualError(t, err, "the password does not satisfy the password policy: the password must contain at least 8 characters")
+	assert.True(t, errors.Is(err, ErrPasswordPolicyViolation))
+}
+
+func TestShouldReturnPasswordPolicyViolationOnConstraintViolation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockConn.EXPECT().
+			Modify(gomock.Any()).
+			Return(ldap.NewError(ldap.LDAPResultConstraintViolation, errors.New("Password fails quality checking policy"))),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
 
-	gomock.InOrder(searchProfile, searchGroups)
+	err := ldapClient.UpdatePassword("john", "password")
 
-	details, err := ldapClient.GetDetails("john")
+	assert.EqualError(t, err, "the password does not satisfy the password policy of user john. Cause: LDAP Result Code 19 \"Constraint Violation\": Password fails quality checking policy")
+	assert.True(t, errors.Is(err, ErrPasswordPolicyViolation))
+}
+
+// passwordPolicyTestConfiguration is the configuration of the provider of the password policy tests.
+var passwordPolicyTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	URL:               "ldap://127.0.0.1:389",
+	User:              "cn=admin,dc=example,dc=com",
+	Password:          "password",
+	UsernameAttribute: "uid",
+	UsersFilter:       "uid={input}",
+	BaseDN:            "dc=example,dc=com",
+	PPolicyControl:    true,
+}
+
+func expectPasswordPolicyUserBind(mockFactory *MockLDAPConnectionFactory, mockConn *MockLDAPConnection, policy *ldap.ControlBeheraPasswordPolicy, err error) {
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			SimpleBind(gomock.Any()).
+			Return(&ldap.SimpleBindResult{}, nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			SimpleBind(gomock.Any()).
+			DoAndReturn(func(req *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+				if req.Username != "uid=john,dc=example,dc=com" || req.Password != "password" {
+					return nil, errors.New("unexpected bind request")
+				}
+
+				if len(req.Controls) != 1 || req.Controls[0].GetControlType() != ldap.ControlTypeBeheraPasswordPolicy {
+					return nil, errors.New("missing password policy control")
+				}
+
+				return &ldap.SimpleBindResult{Controls: []ldap.Control{policy}}, err
+			}),
+	)
+}
+
+func TestShouldReturnPasswordPolicyWarnings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, passwordPolicyTestConfiguration)
+
+	policy := ldap.NewControlBeheraPasswordPolicy()
+	policy.Expire = 3600
+
+	expectPasswordPolicyUserBind(mockFactory, mockConn, policy, nil)
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	warnings, err := ldapClient.CheckUserPasswordWithPasswordPolicy(context.Background(), "john", "password")
 	require.NoError(t, err)
+	require.NotNil(t, warnings)
 
-	assert.ElementsMatch(t, details.Groups, []string{"group1", "group2"})
-	assert.ElementsMatch(t, details.Emails, []string{})
-	assert.Equal(t, details.Username, "john")
+	assert.Equal(t, time.Hour, warnings.ExpiresIn)
+	assert.Equal(t, -1, warnings.GraceLoginsRemaining)
+	assert.False(t, warnings.MustChangePassword)
 }
 
-func TestShouldReturnUsernameFromLDAP(t *testing.T) {
+func TestShouldReturnPasswordPolicyMustChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, passwordPolicyTestConfiguration)
+
+	policy := ldap.NewControlBeheraPasswordPolicy()
+	policy.Error = ldap.BeheraChangeAfterReset
+
+	expectPasswordPolicyUserBind(mockFactory, mockConn, policy, nil)
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	warnings, err := ldapClient.CheckUserPasswordWithPasswordPolicy(context.Background(), "john", "password")
+	require.NoError(t, err)
+	require.NotNil(t, warnings)
+
+	assert.True(t, warnings.MustChangePassword)
+}
+
+func TestShouldNotReturnPasswordPolicyWarningsWithoutControl(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, passwordPolicyTestConfiguration)
+
+	expectPasswordPolicyUserBind(mockFactory, mockConn, ldap.NewControlBeheraPasswordPolicy(), nil)
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	warnings, err := ldapClient.CheckUserPasswordWithPasswordPolicy(context.Background(), "john", "password")
+	require.NoError(t, err)
+
+	assert.Nil(t, warnings)
+}
+
+func TestShouldReturnPasswordPolicyErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     int8
+		expected error
+	}{
+		{"expired", ldap.BeheraPasswordExpired, ErrPasswordExpired},
+		{"locked", ldap.BeheraAccountLocked, ErrAccountLocked},
+		{"other", ldap.BeheraInsufficientPasswordQuality, ErrInvalidCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, passwordPolicyTestConfiguration)
+
+			policy := ldap.NewControlBeheraPasswordPolicy()
+			policy.Error = tc.code
+
+			expectPasswordPolicyUserBind(mockFactory, mockConn, policy,
+				ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("")))
+
+			mockConn.EXPECT().Unbind().Return(nil)
+			mockConn.EXPECT().Close().Times(2)
+
+			valid, err := ldapClient.CheckUserPassword("john", "password")
+
+			assert.False(t, valid)
+			assert.True(t, errors.Is(err, tc.expected))
+		})
+	}
+}
+
+func TestShouldSearchGlobalCatalogAndBindDomainController(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockGlobalCatalogConn := NewMockLDAPConnection(ctrl)
 	mockConn := NewMockLDAPConnection(ctrl)
 
 	ldapClient := NewLDAPUserProviderWithFactory(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:                  "ldap://127.0.0.1:389",
-			User:                 "cn=admin,dc=example,dc=com",
-			Password:             "password",
-			UsernameAttribute:    "uid",
-			MailAttribute:        "mail",
-			DisplayNameAttribute: "displayname",
-			UsersFilter:          "uid={input}",
-			AdditionalUsersDN:    "ou=users",
-			BaseDN:               "dc=example,dc=com",
+			Implementation:    schema.LDAPImplementationActiveDirectory,
+			URL:               "ldaps://dc.example.com:636",
+			GlobalCatalogURL:  "ldaps://gc.example.com:3269",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "sAMAccountName",
+			UsersFilter:       "(sAMAccountName={input})",
+			BaseDN:            "dc=example,dc=com",
 		},
 		nil,
 		mockFactory)
 
-	mockFactory.EXPECT().
-		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
-		Return(mockConn, nil)
-
-	mockConn.EXPECT().
-		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
-		Return(nil)
-
-	mockConn.EXPECT().
-		Close()
+	assert.Equal(t, "gc.example.com", ldapClient.globalCatalogTLSConfig.ServerName)
 
-	searchGroups := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(createSearchResultWithAttributeValues("group1", "group2"), nil)
-	searchProfile := mockConn.EXPECT().
-		Search(gomock.Any()).
-		Return(&ldap.SearchResult{
-			Entries: []*ldap.Entry{
-				{
-					DN: "uid=test,dc=example,dc=com",
-					Attributes: []*ldap.EntryAttribute{
-						{
-							Name:   "displayname",
-							Values: []string{"John Doe"},
-						},
-						{
-							Name:   "mail",
-							Values: []string{"test@example.com"},
-						},
-						{
-							Name:   "uid",
-							Values: []string{"John"},
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldaps://gc.example.com:3269"), gomock.Any()).
+			Return(mockGlobalCatalogConn, nil),
+		mockGlobalCatalogConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockGlobalCatalogConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "cn=john,ou=users,dc=child,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "sAMAccountName",
+								Values: []string{"john"},
+							},
 						},
 					},
 				},
-			},
-		}, nil)
+			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldaps://dc.example.com:636"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=john,ou=users,dc=child,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+		mockGlobalCatalogConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockGlobalCatalogConn.EXPECT().
+			Close(),
+	)
+
+	valid, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+
+	assert.True(t, valid)
+}
+
+func TestShouldPassStartupCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                "ldap://127.0.0.1:389",
+			User:               "cn=admin,dc=example,dc=com",
+			Password:           "password",
+			UsernameAttribute:  "uid",
+			UsersFilter:        "(uid={input})",
+			AdditionalUsersDN:  "ou=users",
+			AdditionalGroupsDN: "ou=groups",
+			BaseDN:             "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("ou=users,dc=example,dc=com")).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("ou=groups,dc=example,dc=com")).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.NoError(t, ldapClient.StartupCheck())
+}
+
+func TestShouldFailStartupCheckWhenBaseDNDoesNotExist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(uid={input})",
+			AdditionalUsersDN: "ou=users",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestBaseDNMatcher("ou=users,dc=example,dc=com")).
+			Return(nil, errors.New("LDAP Result Code 32 \"No Such Object\"")),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.EqualError(t, ldapClient.StartupCheck(), "Unable to find the base DN ou=users,dc=example,dc=com. Cause: LDAP Result Code 32 \"No Such Object\"")
+}
+
+func TestShouldUnbindBeforeClosingConnection(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{"ShouldCloseAfterUnbind", nil},
+		{"ShouldCloseWhenUnbindFails", ldap.NewError(ldap.ErrorNetwork, errors.New("ldap: connection closed"))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+			gomock.InOrder(
+				mockFactory.EXPECT().
+					DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+					Return(mockConn, nil),
+				mockConn.EXPECT().
+					Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+					Return(nil),
+				mockConn.EXPECT().
+					Search(gomock.Any()).
+					Return(userCheckTestSearchResult(), nil),
+				mockConn.EXPECT().
+					Unbind().
+					Return(tc.err),
+				mockConn.EXPECT().
+					Close(),
+			)
+
+			exists, err := ldapClient.UserExists("john")
+			require.NoError(t, err)
+			assert.True(t, exists)
+		})
+	}
+}
+
+func TestShouldFailStartupCheckWhenBindFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:         "ldap://127.0.0.1:389",
+			User:        "cn=admin,dc=example,dc=com",
+			Password:    "password",
+			UsersFilter: "(uid={input})",
+			BaseDN:      "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(errors.New("LDAP Result Code 49 \"Invalid Credentials\"")),
+		mockConn.EXPECT().
+			Close(),
+	)
 
-	gomock.InOrder(searchProfile, searchGroups)
+	assert.EqualError(t, ldapClient.StartupCheck(), "Unable to connect to the LDAP server with user cn=admin,dc=example,dc=com. Cause: LDAP Result Code 49 \"Invalid Credentials\"")
+}
 
-	details, err := ldapClient.GetDetails("john")
-	require.NoError(t, err)
+func TestShouldFailStartupCheckWhenUsersFilterHasNoInputPlaceholder(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:         "ldap://127.0.0.1:389",
+			UsersFilter: "(uid=john)",
+			BaseDN:      "dc=example,dc=com",
+		},
+		nil)
 
-	assert.ElementsMatch(t, details.Groups, []string{"group1", "group2"})
-	assert.ElementsMatch(t, details.Emails, []string{"test@example.com"})
-	assert.Equal(t, details.DisplayName, "John Doe")
-	assert.Equal(t, details.Username, "John")
+	assert.EqualError(t, ldapClient.StartupCheck(), "The users filter (uid=john) does not contain the {input} placeholder")
 }
 
-func TestShouldUpdateUserPassword(t *testing.T) {
+func TestShouldCheckValidUserPassword(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -443,9 +3431,6 @@ func TestShouldUpdateUserPassword(t *testing.T) {
 		nil,
 		mockFactory)
 
-	modifyRequest := ldap.NewModifyRequest("uid=test,dc=example,dc=com", nil)
-	modifyRequest.Replace("userPassword", []string{"password"})
-
 	gomock.InOrder(
 		mockFactory.EXPECT().
 			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
@@ -476,19 +3461,135 @@ func TestShouldUpdateUserPassword(t *testing.T) {
 					},
 				},
 			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
 		mockConn.EXPECT().
-			Modify(modifyRequest).
+			Bind(gomock.Eq("uid=test,dc=example,dc=com"), gomock.Eq("password")).
 			Return(nil),
 		mockConn.EXPECT().
-			Close(),
+			Close().Times(2),
 	)
 
-	err := ldapClient.UpdatePassword("john", "password")
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
 
+	valid, err := ldapClient.CheckUserPassword("john", "password")
+
+	assert.True(t, valid)
 	require.NoError(t, err)
 }
 
-func TestShouldCheckValidUserPassword(t *testing.T) {
+func TestShouldCheckUserPasswordAndGetDetailsWithSingleAdminConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockAdminConn := NewMockLDAPConnection(ctrl)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "(uid={input})",
+			GroupsFilter:         "(member={dn})",
+			BaseDN:               "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockAdminConn, nil),
+		mockAdminConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Return(&ldap.SearchResult{
+				Entries: []*ldap.Entry{
+					{
+						DN: "uid=john,dc=example,dc=com",
+						Attributes: []*ldap.EntryAttribute{
+							{
+								Name:   "uid",
+								Values: []string{"john"},
+							},
+							{
+								Name:   "mail",
+								Values: []string{"john@example.com"},
+							},
+						},
+					},
+				},
+			}, nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockUserConn, nil),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("secret")).
+			Return(nil),
+		mockUserConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockUserConn.EXPECT().
+			Close(),
+		mockAdminConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Close(),
+	)
+
+	valid, details, err := ldapClient.CheckUserPasswordAndGetDetailsWithContext(context.Background(), "john", "secret")
+	require.NoError(t, err)
+
+	assert.True(t, valid)
+	assert.Equal(t, "john", details.Username)
+	assert.Equal(t, []string{"john@example.com"}, details.Emails)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+}
+
+func TestShouldRejectEmptyPasswordWithoutBindingUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	// The user must never be bound with an empty password which many servers accept as an unauthenticated bind.
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	valid, err := ldapClient.CheckUserPassword("john", "")
+
+	assert.False(t, valid)
+	assert.EqualError(t, err, "invalid credentials for user john. Cause: the password is empty")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestShouldCheckInvalidUserPassword(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -545,18 +3646,167 @@ func TestShouldCheckValidUserPassword(t *testing.T) {
 			Return(mockConn, nil),
 		mockConn.EXPECT().
 			Bind(gomock.Eq("uid=test,dc=example,dc=com"), gomock.Eq("password")).
-			Return(nil),
+			Return(errors.New("Invalid username or password")),
 		mockConn.EXPECT().
-			Close().Times(2),
+			Close().
+			Times(2),
 	)
 
+	mockConn.EXPECT().Unbind().Return(nil)
+
 	valid, err := ldapClient.CheckUserPassword("john", "password")
 
-	assert.True(t, valid)
+	assert.False(t, valid)
+	require.EqualError(t, err, "bind failed for user john. Cause: Invalid username or password")
+	assert.True(t, errors.Is(err, ErrBindFailed))
+}
+
+func TestShouldNotReportUnavailableServerAsFailedUserBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockAdminConn, nil),
+		mockAdminConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockUserConn, nil),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(ldap.NewError(ldap.LDAPResultUnavailable, errors.New("server is unavailable"))),
+		mockUserConn.EXPECT().
+			Close(),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Close(),
+	)
+
+	valid, err := ldapClient.CheckUserPassword("john", "password")
+
+	assert.False(t, valid)
+	require.EqualError(t, err, "Unable to bind user john. Cause: LDAP Result Code 52 \"Unavailable\": server is unavailable")
+	assert.False(t, errors.Is(err, ErrBindFailed))
+	assert.False(t, errors.Is(err, ErrInvalidCredentials))
+
+	// The login failing because the LDAP server is unavailable is not regulated.
+	assert.True(t, IsBackendUnavailableError(err))
+}
+
+func TestShouldReturnConnectionFailedErrorWhenServerUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused"))
+
+	valid, err := ldapClient.CheckUserPassword("john", "password")
+
+	assert.False(t, valid)
+	require.EqualError(t, err, "unable to connect to the authentication backend ldap://127.0.0.1:389. Cause: connection refused")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+	assert.False(t, errors.Is(err, ErrBindFailed))
+}
+
+func TestShouldConnectToNextDiscoveredServerWhenUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "srv://example.com",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	require.NotNil(t, ldapClient.discovery)
+
+	ldapClient.discovery.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", []*net.SRV{
+			{Target: "dc1.example.com.", Port: 389},
+			{Target: "dc2.example.com.", Port: 389},
+		}, nil
+	}
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://dc1.example.com:389"), gomock.Any()).
+			Return(nil, errors.New("connection refused")),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://dc2.example.com:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	conn, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
 	require.NoError(t, err)
+	assert.Equal(t, mockConn, conn.(*ldapTrackedConnection).LDAPConnection)
 }
 
-func TestShouldCheckInvalidUserPassword(t *testing.T) {
+func TestShouldNotDialWhenContextIsCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	valid, err := ldapClient.CheckUserPasswordWithContext(ctx, "john", "password")
+
+	assert.False(t, valid)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestShouldAbortSearchWhenContextDeadlineExceeded(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -565,63 +3815,145 @@ func TestShouldCheckInvalidUserPassword(t *testing.T) {
 
 	ldapClient := NewLDAPUserProviderWithFactory(
 		schema.LDAPAuthenticationBackendConfiguration{
-			URL:                  "ldap://127.0.0.1:389",
-			User:                 "cn=admin,dc=example,dc=com",
-			Password:             "password",
-			UsernameAttribute:    "uid",
-			MailAttribute:        "mail",
-			DisplayNameAttribute: "displayname",
-			UsersFilter:          "uid={input}",
-			AdditionalUsersDN:    "ou=users",
-			BaseDN:               "dc=example,dc=com",
+			URL:               "ldap://127.0.0.1:389",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
 		},
 		nil,
 		mockFactory)
 
+	closed := make(chan struct{})
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			DoAndReturn(func(_ *ldap.SearchRequest) (*ldap.SearchResult, error) {
+				// The search only returns once the connection is closed like a hanging LDAP server.
+				<-closed
+				return nil, errors.New("ldap: connection closed")
+			}),
+	)
+
+	mockConn.EXPECT().
+		Close().
+		Do(func() { close(closed) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := ldapClient.GetDetailsWithContext(ctx, "john")
+	assert.EqualError(t, err, "Cannot find user DN of user john. Cause: context deadline exceeded")
+}
+
+func TestShouldAbortUserBindWhenUserBindTimeoutExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient.configuration.UserBindTimeout = "2s"
+	ldapClient.parseDynamicConfiguration()
+
+	assert.Equal(t, 2*time.Second, ldapClient.userBindTimeout)
+
+	ldapClient.userBindTimeout = 10 * time.Millisecond
+	ldapClient.circuitBreaker = newLDAPCircuitBreaker(1, time.Minute, &testClock{now: time.Unix(1600000000, 0)})
+
+	closed := make(chan struct{})
+
 	gomock.InOrder(
 		mockFactory.EXPECT().
 			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
-			Return(mockConn, nil),
-		mockConn.EXPECT().
+			Return(mockAdminConn, nil),
+		mockAdminConn.EXPECT().
 			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
 			Return(nil),
-		mockConn.EXPECT().
+		mockAdminConn.EXPECT().
 			Search(gomock.Any()).
-			Return(&ldap.SearchResult{
-				Entries: []*ldap.Entry{
-					{
-						DN: "uid=test,dc=example,dc=com",
-						Attributes: []*ldap.EntryAttribute{
-							{
-								Name:   "displayname",
-								Values: []string{"John Doe"},
-							},
-							{
-								Name:   "mail",
-								Values: []string{"test@example.com"},
-							},
-							{
-								Name:   "uid",
-								Values: []string{"John"},
-							},
-						},
-					},
-				},
-			}, nil),
+			Return(userCheckTestSearchResult(), nil),
 		mockFactory.EXPECT().
 			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
-			Return(mockConn, nil),
-		mockConn.EXPECT().
-			Bind(gomock.Eq("uid=test,dc=example,dc=com"), gomock.Eq("password")).
-			Return(errors.New("Invalid username or password")),
-		mockConn.EXPECT().
+			Return(mockUserConn, nil),
+		mockUserConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			DoAndReturn(func(_, _ string) error {
+				// The bind only returns once the connection is closed like a slow domain controller.
+				<-closed
+				return errors.New("ldap: connection closed")
+			}),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
 			Close(),
 	)
 
+	var once sync.Once
+
+	mockUserConn.EXPECT().
+		Close().
+		Do(func() { once.Do(func() { close(closed) }) }).
+		MinTimes(1)
+
 	valid, err := ldapClient.CheckUserPassword("john", "password")
 
 	assert.False(t, valid)
-	require.EqualError(t, err, "Authentication of user john failed. Cause: Invalid username or password")
+	assert.EqualError(t, err, "the bind timed out against the authentication backend ldap://127.0.0.1:389. Cause: context deadline exceeded")
+	assert.True(t, errors.Is(err, ErrBindTimeout))
+	assert.False(t, errors.Is(err, ErrConnectionFailed))
+	assert.False(t, IsBackendUnavailableError(err))
+
+	// The LDAP server is reachable so the timed out bind is not counted as a connection failure.
+	assert.NoError(t, ldapClient.circuitBreaker.Allow("ldap://127.0.0.1:389"))
+}
+
+func TestShouldKeepUserConnectionOnceBoundWithinUserBindTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockAdminConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	mockUserConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient.configuration.PasswordChangeAsUser = true
+	ldapClient.userBindTimeout = 10 * time.Millisecond
+
+	modifyRequest := ldap.NewModifyRequest("uid=john,dc=example,dc=com", nil)
+	modifyRequest.Replace("userPassword", []string{"new-password"})
+
+	calls := expectPasswordChangeBinds(mockFactory, mockAdminConn, mockUserConn, nil)
+	calls = append(calls,
+		mockUserConn.EXPECT().
+			Modify(modifyRequest).
+			DoAndReturn(func(_ *ldap.ModifyRequest) error {
+				// The operations after the bind are not bounded by the user bind timeout.
+				time.Sleep(30 * time.Millisecond)
+				return nil
+			}),
+		mockUserConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockUserConn.EXPECT().
+			Close(),
+		mockAdminConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockAdminConn.EXPECT().
+			Close(),
+	)
+
+	gomock.InOrder(calls...)
+
+	require.NoError(t, ldapClient.ChangePassword("john", "old-password", "new-password"))
 }
 
 func TestShouldCallStartTLSWhenEnabled(t *testing.T) {
@@ -658,6 +3990,9 @@ func TestShouldCallStartTLSWhenEnabled(t *testing.T) {
 	mockConn.EXPECT().
 		StartTLS(ldapClient.tlsConfig)
 
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
 	mockConn.EXPECT().
 		Close()
 
@@ -729,6 +4064,102 @@ func TestShouldParseDynamicConfiguration(t *testing.T) {
 	assert.Equal(t, "ou=groups,dc=example,dc=com", ldapClient.groupsDN)
 }
 
+func TestShouldReplaceAttributePlaceholders(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			UsernameAttribute: "uid",
+			UsersFilter:       "(&(|({username_attribute}={input})({employee_id}={input}))(objectClass=person))",
+			ListUsersFilter:   "(&({employee_id}=*)(objectClass=person))",
+			AttributePlaceholders: []schema.LDAPAttributePlaceholderConfiguration{
+				{Name: "employee_id", Attribute: "employeeID"},
+			},
+			BaseDN: "dc=example,dc=com",
+		},
+		nil)
+
+	assert.Equal(t, "(&(|(uid={input})(employeeID={input}))(objectClass=person))", ldapClient.configuration.UsersFilter)
+	assert.Equal(t, "(&(employeeID=*)(objectClass=person))", ldapClient.listUsersFilter)
+	assert.Equal(t, "(&(|(uid=E\\2a1234)(employeeID=E\\2a1234))(objectClass=person))", ldapClient.resolveUsersFilter(ldapClient.configuration.UsersFilter, "E*1234"))
+}
+
+func TestShouldParseSearchScopes(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "ldap://127.0.0.1:389",
+			UsersSearchScope:  schema.LDAPSearchScopeOne,
+			GroupsSearchScope: schema.LDAPSearchScopeBase,
+		},
+		nil)
+
+	assert.Equal(t, ldap.ScopeSingleLevel, ldapClient.usersScope)
+	assert.Equal(t, ldap.ScopeBaseObject, ldapClient.groupsScope)
+
+	ldapClient = NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL: "ldap://127.0.0.1:389",
+		},
+		nil)
+
+	assert.Equal(t, ldap.ScopeWholeSubtree, ldapClient.usersScope)
+	assert.Equal(t, ldap.ScopeWholeSubtree, ldapClient.groupsScope)
+}
+
+func TestShouldParseDerefAliases(t *testing.T) {
+	testCases := []struct {
+		derefAliases string
+		expected     int
+	}{
+		{"", ldap.NeverDerefAliases},
+		{schema.LDAPDerefAliasesNever, ldap.NeverDerefAliases},
+		{schema.LDAPDerefAliasesSearching, ldap.DerefInSearching},
+		{schema.LDAPDerefAliasesFinding, ldap.DerefFindingBaseObj},
+		{schema.LDAPDerefAliasesAlways, ldap.DerefAlways},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.derefAliases, func(t *testing.T) {
+			ldapClient := NewLDAPUserProvider(
+				schema.LDAPAuthenticationBackendConfiguration{
+					URL:          "ldap://127.0.0.1:389",
+					DerefAliases: tc.derefAliases,
+				},
+				nil)
+
+			assert.Equal(t, tc.expected, ldapClient.derefAliases)
+		})
+	}
+}
+
+func TestShouldDereferenceAliasesWhenSearchingUsersAndGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.derefAliases = ldap.DerefAlways
+
+	derefAlways := func(searchRequest *ldap.SearchRequest) {
+		assert.Equal(t, ldap.DerefAlways, searchRequest.DerefAliases)
+	}
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Do(derefAlways).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(member=uid=john,dc=example,dc=com)")).
+			Do(derefAlways).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+	)
+
+	profile, err := ldapClient.getUserProfile(context.Background(), mockConn, "john")
+	require.NoError(t, err)
+
+	_, err = ldapClient.getUserDetails(context.Background(), mockConn, "john", profile)
+	require.NoError(t, err)
+}
+
 func TestShouldCallStartTLSWithInsecureSkipVerifyWhenSkipVerifyTrue(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -766,6 +4197,9 @@ func TestShouldCallStartTLSWithInsecureSkipVerifyWhenSkipVerifyTrue(t *testing.T
 	mockConn.EXPECT().
 		StartTLS(ldapClient.tlsConfig)
 
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil)
 	mockConn.EXPECT().
 		Close()
 
@@ -807,6 +4241,132 @@ func TestShouldCallStartTLSWithInsecureSkipVerifyWhenSkipVerifyTrue(t *testing.T
 	assert.Equal(t, details.Username, "john")
 }
 
+func TestShouldCallStartTLSWithStartTLSConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			BaseDN:   "dc=example,dc=com",
+			StartTLS: true,
+			TLS:      &schema.TLSConfig{},
+			StartTLSConfig: &schema.TLSConfig{
+				SkipVerify: true,
+			},
+		},
+		nil,
+		mockFactory)
+
+	assert.False(t, ldapClient.tlsConfig.InsecureSkipVerify)
+	assert.True(t, ldapClient.startTLSConfig.InsecureSkipVerify)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			StartTLS(ldapClient.startTLSConfig).
+			Return(nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	_, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
+	require.NoError(t, err)
+}
+
+func TestShouldUseTLSConfigForStartTLSWithoutStartTLSConfig(t *testing.T) {
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldap://127.0.0.1:389",
+			StartTLS: true,
+			TLS: &schema.TLSConfig{
+				SkipVerify: true,
+			},
+		},
+		nil)
+
+	assert.Same(t, ldapClient.tlsConfig, ldapClient.startTLSConfig)
+}
+
+func TestShouldChainCustomVerifyConnection(t *testing.T) {
+	errPinning := errors.New("the certificate doesn't match the pinned one")
+
+	ldapClient := NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL: "ldaps://127.0.0.1:636",
+			TLS: &schema.TLSConfig{RequireOCSPStapling: true},
+		},
+		nil)
+
+	var called int
+
+	ldapClient.SetVerifyConnection(func(state tls.ConnectionState) error {
+		called++
+
+		return errPinning
+	})
+
+	// The custom verification is skipped when the stapled OCSP response can't be verified.
+	assert.EqualError(t, ldapClient.tlsConfig.VerifyConnection(tls.ConnectionState{}), "the peer presented no certificate")
+	assert.Equal(t, 0, called)
+
+	ldapClient = NewLDAPUserProvider(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL: "ldaps://127.0.0.1:636",
+		},
+		nil)
+
+	ldapClient.SetVerifyConnection(func(state tls.ConnectionState) error {
+		called++
+
+		return errPinning
+	})
+
+	assert.Equal(t, errPinning, ldapClient.tlsConfig.VerifyConnection(tls.ConnectionState{}))
+	assert.Equal(t, errPinning, ldapClient.startTLSConfig.VerifyConnection(tls.ConnectionState{}))
+	assert.Equal(t, 2, called)
+}
+
+func TestShouldNotUseTLSOverUnixDomainSocket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:      "ldapi:///var/run/slapd/ldapi",
+			User:     "cn=admin,dc=example,dc=com",
+			Password: "password",
+			StartTLS: true,
+			BaseDN:   "dc=example,dc=com",
+		},
+		nil,
+		mockFactory)
+
+	// Neither the TLS dial option nor StartTLS are used with the Unix domain socket.
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldapi:///var/run/slapd/ldapi"), gomock.Nil()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+	)
+
+	_, err := ldapClient.connectAdmin(context.Background(), "cn=admin,dc=example,dc=com", "password")
+	require.NoError(t, err)
+}
+
 func TestShouldReturnLDAPSAlreadySecuredWhenStartTLSAttempted(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -841,6 +4401,144 @@ func TestShouldReturnLDAPSAlreadySecuredWhenStartTLSAttempted(t *testing.T) {
 		StartTLS(ldapClient.tlsConfig).
 		Return(errors.New("LDAP Result Code 200 \"Network Error\": ldap: already encrypted"))
 
+	mockConn.EXPECT().
+		Close()
+
 	_, err := ldapClient.GetDetails("john")
-	assert.EqualError(t, err, "LDAP Result Code 200 \"Network Error\": ldap: already encrypted")
+	assert.EqualError(t, err, "unable to connect to the authentication backend ldaps://127.0.0.1:389 with StartTLS. Cause: LDAP Result Code 200 \"Network Error\": ldap: already encrypted")
+}
+
+// loginAttributeTestConfiguration is the configuration of the provider of the login attribute tests.
+var loginAttributeTestConfiguration = schema.LDAPAuthenticationBackendConfiguration{
+	URL:                  "ldap://127.0.0.1:389",
+	User:                 "cn=admin,dc=example,dc=com",
+	Password:             "password",
+	LoginAttribute:       "mail",
+	UsernameAttribute:    "uid",
+	MailAttribute:        "mail",
+	DisplayNameAttribute: "displayname",
+	UsersFilter:          "(&({login_attribute}={input})(objectClass=person))",
+	GroupsFilter:         "(memberUid={username})",
+	GroupNameAttribute:   "cn",
+	BaseDN:               "dc=example,dc=com",
+}
+
+func TestShouldSearchByLoginAttributeAndReturnUsername(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, loginAttributeTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(&(mail=john@example.com)(objectClass=person))")).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(memberUid=john)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+	assert.Equal(t, []string{"admins"}, details.Groups)
+}
+
+func TestShouldSearchByUsernameWhenNoUserMatchesLoginAttribute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, loginAttributeTestConfiguration)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(&(mail=john)(objectClass=person))")).
+			Return(&ldap.SearchResult{}, nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(&(uid=john)(objectClass=person))")).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(memberUid=john)")).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "john", details.Username)
+}
+
+func TestShouldCheckUserExistsWithoutPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil).
+		Times(2)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil).
+		Times(2)
+
+	mockConn.EXPECT().
+		Unbind().
+		Return(nil).
+		Times(2)
+
+	mockConn.EXPECT().
+		Close().
+		Times(2)
+
+	gomock.InOrder(
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=john)")).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(NewSearchRequestMatcher("(uid=nobody)")).
+			Return(&ldap.SearchResult{}, nil),
+	)
+
+	exists, err := ldapClient.UserExists("john")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = ldapClient.UserExists("nobody")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The LDAP server being unreachable is not mistaken for a missing user.
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(nil, errors.New("connection refused"))
+
+	exists, err = ldapClient.UserExists("john")
+	assert.True(t, errors.Is(err, ErrConnectionFailed))
+	assert.False(t, exists)
 }
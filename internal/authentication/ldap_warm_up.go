@@ -0,0 +1,100 @@
+package authentication
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ldapWarmUpServer is an LDAP server connected to at startup along with the TLS configurations of its connections.
+type ldapWarmUpServer struct {
+	address        string
+	tlsConfig      *tls.Config
+	startTLSConfig *tls.Config
+}
+
+// enableTLSSessionResumption shares a TLS session cache between the TLS configurations of the connections to the LDAP
+// servers so the connections resume the sessions of the previous ones with an abbreviated handshake. The TLS
+// configurations of the discovered servers are clones sharing the same cache.
+func (p *LDAPUserProvider) enableTLSSessionResumption() {
+	cache := tls.NewLRUClientSessionCache(ldapTLSSessionCacheSize)
+
+	for _, tlsConfig := range []*tls.Config{p.tlsConfig, p.startTLSConfig, p.globalCatalogTLSConfig, p.globalCatalogStartTLSConfig} {
+		if tlsConfig != nil {
+			tlsConfig.ClientSessionCache = cache
+		}
+	}
+}
+
+// warmUpServers returns the LDAP servers to warm up in addition to the server of the URL, which the startup check has
+// already connected to: every discovered server and the global catalog.
+func (p *LDAPUserProvider) warmUpServers() ([]ldapWarmUpServer, error) {
+	var servers []ldapWarmUpServer
+
+	if p.discovery != nil {
+		urls, err := p.discovery.URLs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, address := range urls {
+			tlsConfig, startTLSConfig := p.discoveredServerTLSConfigs(address)
+
+			servers = append(servers, ldapWarmUpServer{address: address, tlsConfig: tlsConfig, startTLSConfig: startTLSConfig})
+		}
+	}
+
+	if p.configuration.GlobalCatalogURL != "" {
+		servers = append(servers, ldapWarmUpServer{
+			address:        p.configuration.GlobalCatalogURL,
+			tlsConfig:      p.globalCatalogTLSConfig,
+			startTLSConfig: p.globalCatalogStartTLSConfig,
+		})
+	}
+
+	return servers, nil
+}
+
+// warmUp connects and binds with the admin user to the LDAP servers the logins may connect to so their TLS sessions
+// are resumed by the first logins and the unreachable servers are known before serving traffic. The connections are
+// closed once bound since every operation opens its own connection. The servers which can't be warmed up are logged
+// and only fail the startup when the warm up is required.
+func (p *LDAPUserProvider) warmUp(ctx context.Context) error {
+	servers, err := p.warmUpServers()
+	if err != nil {
+		operationLogger(ctx).Warnf("Unable to warm up the connections to the LDAP servers. Cause: %s", err)
+
+		if p.configuration.WarmUp.Required {
+			return fmt.Errorf("Unable to warm up the connections to the LDAP servers. Cause: %w", err)
+		}
+
+		return nil
+	}
+
+	var failed []string
+
+	for _, server := range servers {
+		start := time.Now()
+
+		conn, _, err := p.connectURL(ctx, server.address, server.tlsConfig, server.startTLSConfig, p.configuration.User, p.configuration.Password)
+		if err != nil {
+			operationLogger(ctx).Warnf("Unable to warm up the connection to the LDAP server %s. Cause: %s", server.address, err)
+
+			failed = append(failed, server.address)
+
+			continue
+		}
+
+		unbindAndClose(conn)
+
+		operationLogger(ctx).Debugf("Warmed up the connection to the LDAP server %s in %s", server.address, time.Since(start))
+	}
+
+	if len(failed) != 0 && p.configuration.WarmUp.Required {
+		return fmt.Errorf("Unable to warm up the connections to the LDAP servers %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
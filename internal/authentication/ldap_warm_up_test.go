@@ -0,0 +1,131 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldShareTLSSessionCacheWhenWarmUpIsEnabled(t *testing.T) {
+	configuration := schema.LDAPAuthenticationBackendConfiguration{
+		URL:              "ldaps://dc.example.com:636",
+		GlobalCatalogURL: "ldaps://gc.example.com:3269",
+		StartTLSConfig:   &schema.TLSConfig{},
+	}
+
+	ldapClient := NewLDAPUserProvider(configuration, nil)
+
+	assert.Nil(t, ldapClient.tlsConfig.ClientSessionCache)
+
+	configuration.WarmUp.Enabled = true
+
+	ldapClient = NewLDAPUserProvider(configuration, nil)
+
+	require.NotNil(t, ldapClient.tlsConfig.ClientSessionCache)
+	assert.Equal(t, ldapClient.tlsConfig.ClientSessionCache, ldapClient.startTLSConfig.ClientSessionCache)
+	assert.Equal(t, ldapClient.tlsConfig.ClientSessionCache, ldapClient.globalCatalogTLSConfig.ClientSessionCache)
+	assert.Equal(t, ldapClient.tlsConfig.ClientSessionCache, ldapClient.globalCatalogStartTLSConfig.ClientSessionCache)
+}
+
+func TestShouldWarmUpDiscoveredServersAndGlobalCatalog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:               "srv://example.com",
+			GlobalCatalogURL:  "ldap://gc.example.com:3268",
+			User:              "cn=admin,dc=example,dc=com",
+			Password:          "password",
+			UsernameAttribute: "uid",
+			UsersFilter:       "uid={input}",
+			BaseDN:            "dc=example,dc=com",
+			WarmUp:            schema.LDAPWarmUpConfiguration{Enabled: true},
+		},
+		nil,
+		mockFactory)
+
+	ldapClient.discovery.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", []*net.SRV{
+			{Target: "dc1.example.com.", Port: 389},
+			{Target: "dc2.example.com.", Port: 389},
+		}, nil
+	}
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://dc1.example.com:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://dc2.example.com:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://gc.example.com:3268"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil),
+		mockConn.EXPECT().
+			Close(),
+	)
+
+	assert.NoError(t, ldapClient.warmUp(context.Background()))
+}
+
+func TestShouldFailStartupOnlyWhenWarmUpIsRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	ldapClient, mockFactory, _ := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.GlobalCatalogURL = "ldap://gc.example.com:3268"
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://gc.example.com:3268"), gomock.Any()).
+		Return(nil, errors.New("connection refused")).
+		Times(2)
+
+	require.NoError(t, ldapClient.warmUp(context.Background()))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "Unable to warm up the connection to the LDAP server ldap://gc.example.com:3268. Cause: unable to connect to the authentication backend ldap://gc.example.com:3268. Cause: connection refused", entry.Message)
+
+	ldapClient.configuration.WarmUp.Required = true
+
+	assert.EqualError(t, ldapClient.warmUp(context.Background()), "Unable to warm up the connections to the LDAP servers ldap://gc.example.com:3268")
+}
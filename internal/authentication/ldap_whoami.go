@@ -0,0 +1,70 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// whoAmI returns the authorization identity the LDAP server associates with the connection with the Who Am I?
+// extended operation of RFC 4532, like dn:uid=john,dc=example,dc=com or u:john, and empty for an anonymous bind.
+func (p *LDAPUserProvider) whoAmI(ctx context.Context, conn LDAPConnection) (string, error) {
+	start := time.Now()
+
+	result, err := conn.WhoAmI(nil)
+	logOperationStep(ctx, ldapStepWhoAmI, start, nil, err)
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to perform the Who Am I? extended operation. Cause: %s", err)
+	}
+
+	operationLogger(ctx).Debugf("The LDAP server associates the connection with the authorization identity '%s'", result.AuthzID)
+
+	return result.AuthzID, nil
+}
+
+// whoAmIEnabled returns true when the authorization identity of the binds of the users is requested, which is only the
+// case when the Who Am I? extended operation is enabled and the log level is debug or trace to keep it off the logins.
+func (p *LDAPUserProvider) whoAmIEnabled(ctx context.Context) bool {
+	return p.configuration.WhoAmI && operationLogger(ctx).Logger.IsLevelEnabled(logrus.DebugLevel)
+}
+
+// checkWhoAmI confirms the bind of the admin user maps to the expected identity. A bind with a DN mapped to the
+// authorization identity of another entry is logged as a warning, the identity of the other binds is logged at the
+// info level. It never fails since the LDAP servers are not required to support the extended operation.
+func (p *LDAPUserProvider) checkWhoAmI(ctx context.Context, conn LDAPConnection, bindUser string) {
+	bind := fmt.Sprintf("the bind of user %s", bindUser)
+
+	switch {
+	case bindUser == "" && p.configuration.AuthMethod == schema.LDAPAuthMethodExternal:
+		bind = "the SASL EXTERNAL bind"
+	case bindUser == "" && p.configuration.AuthMethod == schema.LDAPAuthMethodGSSAPI:
+		bind = "the SASL GSSAPI bind"
+	case bindUser == "":
+		bind = "the anonymous bind"
+	}
+
+	authzID, err := p.whoAmI(ctx, conn)
+	if err != nil {
+		operationLogger(ctx).Warnf("Unable to confirm the identity the LDAP server associates with %s. Cause: %s", bind, err)
+		return
+	}
+
+	// The users which are not DNs are UPNs or down-level logon names of Active Directory which can't be compared.
+	bindDN, err := ldap.ParseDN(bindUser)
+	if bindUser == "" || err != nil || !strings.HasPrefix(authzID, "dn:") {
+		operationLogger(ctx).Infof("The LDAP server associates %s with the authorization identity '%s'", bind, authzID)
+		return
+	}
+
+	authzDN, err := ldap.ParseDN(strings.TrimPrefix(authzID, "dn:"))
+	if err != nil || !bindDN.Equal(authzDN) {
+		operationLogger(ctx).Warnf("The LDAP server associates %s with the authorization identity '%s' of another entry", bind, authzID)
+	}
+}
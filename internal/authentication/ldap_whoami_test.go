@@ -0,0 +1,159 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldCheckIdentityOfBindUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	ldapClient, _, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+
+	testCases := []struct {
+		name    string
+		result  *ldap.WhoAmIResult
+		err     error
+		level   logrus.Level
+		message string
+	}{
+		{"SameDN", &ldap.WhoAmIResult{AuthzID: "dn:CN=admin,DC=example,DC=com"}, nil, 0, ""},
+		{"OtherDN", &ldap.WhoAmIResult{AuthzID: "dn:cn=proxy,dc=example,dc=com"}, nil, logrus.WarnLevel,
+			"The LDAP server associates the bind of user cn=admin,dc=example,dc=com with the authorization identity 'dn:cn=proxy,dc=example,dc=com' of another entry"},
+		{"UserID", &ldap.WhoAmIResult{AuthzID: "u:admin"}, nil, logrus.InfoLevel,
+			"The LDAP server associates the bind of user cn=admin,dc=example,dc=com with the authorization identity 'u:admin'"},
+		{"Unsupported", nil, ldap.NewError(ldap.LDAPResultProtocolError, errors.New("unsupported extended operation")), logrus.WarnLevel,
+			"Unable to confirm the identity the LDAP server associates with the bind of user cn=admin,dc=example,dc=com. Cause: Unable to perform the Who Am I? extended operation. Cause: LDAP Result Code 2 \"Protocol Error\": unsupported extended operation"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hook.Reset()
+
+			mockConn.EXPECT().
+				WhoAmI(gomock.Nil()).
+				Return(tc.result, tc.err)
+
+			ldapClient.checkWhoAmI(newOperationContext(context.Background(), "startup_check", ""), mockConn, "cn=admin,dc=example,dc=com")
+
+			entry := hook.LastEntry()
+
+			if tc.message == "" {
+				assert.Nil(t, entry)
+				return
+			}
+
+			require.NotNil(t, entry)
+			assert.Equal(t, tc.level, entry.Level)
+			assert.Equal(t, tc.message, entry.Message)
+		})
+	}
+}
+
+func TestShouldRequestIdentityOfUserBindOnlyWithDebugLevel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	level := logrus.GetLevel()
+	defer logrus.SetLevel(level)
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.WhoAmI = true
+
+	expectUserBind := func() {
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil).
+			Times(2)
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil)
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil)
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil)
+		mockConn.EXPECT().
+			Unbind().
+			Return(nil).
+			Times(2)
+		mockConn.EXPECT().
+			Close().
+			Times(2)
+	}
+
+	// The mock fails on the unexpected call of WhoAmI.
+	logrus.SetLevel(logrus.InfoLevel)
+	expectUserBind()
+
+	ok, err := ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	logrus.SetLevel(logrus.DebugLevel)
+	expectUserBind()
+
+	mockConn.EXPECT().
+		WhoAmI(gomock.Nil()).
+		Return(&ldap.WhoAmIResult{AuthzID: "dn:uid=john,dc=example,dc=com"}, nil)
+
+	ok, err = ldapClient.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestShouldReportIdentityOfTestedUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ldapClient, mockFactory, mockConn := newTestLDAPUserProvider(ctrl, userCheckTestConfiguration)
+	ldapClient.configuration.WhoAmI = true
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(userCheckTestSearchResult(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("admins"), nil),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("uid=john,dc=example,dc=com"), gomock.Eq("secret")).
+			Return(nil),
+		mockConn.EXPECT().
+			WhoAmI(gomock.Nil()).
+			Return(&ldap.WhoAmIResult{AuthzID: "dn:uid=john,dc=example,dc=com"}, nil),
+	)
+
+	mockConn.EXPECT().Unbind().Return(nil).Times(2)
+	mockConn.EXPECT().Close().Times(2)
+
+	report, err := ldapClient.TestUser("john", "secret")
+	require.NoError(t, err)
+
+	assert.True(t, report.Authenticated)
+	assert.Equal(t, "dn:uid=john,dc=example,dc=com", report.AuthzID)
+}
@@ -0,0 +1,58 @@
+package authentication
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// checkPasswordPolicy checks the new password of a user satisfies the password policy before it's sent to the
+// authentication backend.
+func checkPasswordPolicy(policy schema.LDAPPasswordPolicyConfiguration, username, password string) error {
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("%w: the password must contain at least %d characters", ErrPasswordPolicyViolation, policy.MinLength)
+	}
+
+	var hasUppercase, hasLowercase, hasNumber, hasSpecial bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUppercase = true
+		case unicode.IsLower(r):
+			hasLowercase = true
+		case unicode.IsDigit(r):
+			hasNumber = true
+		default:
+			hasSpecial = true
+		}
+	}
+
+	switch {
+	case policy.RequireUppercase && !hasUppercase:
+		return fmt.Errorf("%w: the password must contain an uppercase character", ErrPasswordPolicyViolation)
+	case policy.RequireLowercase && !hasLowercase:
+		return fmt.Errorf("%w: the password must contain a lowercase character", ErrPasswordPolicyViolation)
+	case policy.RequireNumber && !hasNumber:
+		return fmt.Errorf("%w: the password must contain a number", ErrPasswordPolicyViolation)
+	case policy.RequireSpecial && !hasSpecial:
+		return fmt.Errorf("%w: the password must contain a special character", ErrPasswordPolicyViolation)
+	}
+
+	lowerPassword := strings.ToLower(password)
+
+	if policy.ForbidUsername && username != "" && strings.Contains(lowerPassword, strings.ToLower(username)) {
+		return fmt.Errorf("%w: the password must not contain the username", ErrPasswordPolicyViolation)
+	}
+
+	for _, substring := range policy.ForbiddenSubstrings {
+		if substring != "" && strings.Contains(lowerPassword, strings.ToLower(substring)) {
+			return fmt.Errorf("%w: the password must not contain %s", ErrPasswordPolicyViolation, substring)
+		}
+	}
+
+	return nil
+}
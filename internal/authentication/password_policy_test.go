@@ -0,0 +1,50 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldAcceptAnyPasswordWithoutPolicy(t *testing.T) {
+	assert.NoError(t, checkPasswordPolicy(schema.LDAPPasswordPolicyConfiguration{}, "john", "john"))
+}
+
+func TestShouldCheckPasswordPolicy(t *testing.T) {
+	policy := schema.LDAPPasswordPolicyConfiguration{
+		MinLength:           8,
+		RequireUppercase:    true,
+		RequireLowercase:    true,
+		RequireNumber:       true,
+		RequireSpecial:      true,
+		ForbidUsername:      true,
+		ForbiddenSubstrings: []string{"authelia"},
+	}
+
+	testCases := []struct {
+		password string
+		expected string
+	}{
+		{"Ab1!", "the password does not satisfy the password policy: the password must contain at least 8 characters"},
+		{"abcdef1!", "the password does not satisfy the password policy: the password must contain an uppercase character"},
+		{"ABCDEF1!", "the password does not satisfy the password policy: the password must contain a lowercase character"},
+		{"Abcdefg!", "the password does not satisfy the password policy: the password must contain a number"},
+		{"Abcdefg1", "the password does not satisfy the password policy: the password must contain a special character"},
+		{"MyJohn12!", "the password does not satisfy the password policy: the password must not contain the username"},
+		{"Authelia12!", "the password does not satisfy the password policy: the password must not contain authelia"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.password, func(t *testing.T) {
+			err := checkPasswordPolicy(policy, "john", tc.password)
+
+			assert.EqualError(t, err, tc.expected)
+			assert.True(t, errors.Is(err, ErrPasswordPolicyViolation))
+		})
+	}
+
+	assert.NoError(t, checkPasswordPolicy(policy, "john", "Secure-Passw0rd"))
+}
@@ -1,9 +1,69 @@
 package authentication
 
+import "time"
+
 // UserDetails represent the details retrieved for a given user.
 type UserDetails struct {
 	Username    string
 	DisplayName string
 	Emails      []string
 	Groups      []string
+
+	// StableID is the immutable identifier of the user in the backend, such as the objectGUID of Active Directory or the
+	// entryUUID of OpenLDAP, which is kept when the user is renamed. It is empty unless the backend is configured with
+	// a stable identifier attribute.
+	StableID string
+
+	// GroupDisplayNames maps the names of the groups to their display name, nil unless the backend is configured with a
+	// group display name attribute. The groups without a display name are not mapped.
+	GroupDisplayNames map[string]string
+
+	// Extra contains the values of the additional attributes retrieved from the backend, keyed by attribute name.
+	Extra map[string][]string
+
+	// OperationalTimestamps contains the times of the operational attributes retrieved from the backend, such as the
+	// createTimestamp and the modifyTimestamp, keyed by attribute name.
+	OperationalTimestamps map[string]time.Time
+
+	// PasswordLastSet is the time the password was last changed, zero when unknown.
+	PasswordLastSet time.Time
+
+	// PasswordExpires is the time the password expires, zero when unknown or when the password never expires.
+	PasswordExpires time.Time
+
+	// AccountExpires is the time the account expires, zero when unknown or when the account never expires.
+	AccountExpires time.Time
+
+	// Photo is the raw picture of the user, nil when the backend has none.
+	Photo []byte
+
+	// PhotoMIMEType is the MIME type of the picture of the user detected from its content, empty without a picture.
+	PhotoMIMEType string
+
+	// MustChangePassword indicates the password has been reset by an administrator and must be changed by the user at
+	// the next login.
+	MustChangePassword bool
+}
+
+// PasswordPolicyWarnings represent the warnings returned by the password policy of the backend when the password of the
+// user matches.
+type PasswordPolicyWarnings struct {
+	// ExpiresIn is the time left before the password expires, zero when the password is not about to expire.
+	ExpiresIn time.Duration
+
+	// GraceLoginsRemaining is the number of logins left with the expired password, -1 when the password has not
+	// expired.
+	GraceLoginsRemaining int
+
+	// MustChangePassword indicates the password has been reset by an administrator and must be changed by the user.
+	MustChangePassword bool
+}
+
+// TransportSecurity represents the transport security of the connection the credentials of the user were sent over.
+type TransportSecurity struct {
+	// Transport is one of TransportLDAPS, TransportStartTLS, TransportLDAPI or TransportPlaintext.
+	Transport string
+
+	// TLSVersion is the negotiated TLS version like TLS 1.3, empty without TLS.
+	TLSVersion string
 }
@@ -0,0 +1,78 @@
+package authentication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// userDetailsCache is a concurrency safe cache of user details which expire after a fixed duration.
+type userDetailsCache struct {
+	ttl     time.Duration
+	clock   utils.Clock
+	entries map[string]userDetailsCacheEntry
+	lock    sync.RWMutex
+}
+
+type userDetailsCacheEntry struct {
+	details *UserDetails
+	expires time.Time
+}
+
+func newUserDetailsCache(ttl time.Duration, clock utils.Clock) *userDetailsCache {
+	return &userDetailsCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]userDetailsCacheEntry),
+	}
+}
+
+// Get returns the cached details of the user if they have not expired yet.
+func (c *userDetailsCache) Get(username string) (details *UserDetails, ok bool) {
+	c.lock.RLock()
+	entry, ok := c.entries[username]
+	c.lock.RUnlock()
+
+	if !ok || !c.clock.Now().Before(entry.expires) {
+		return nil, false
+	}
+
+	return entry.details, true
+}
+
+// Set caches the details of the user until the cache duration elapses.
+func (c *userDetailsCache) Set(username string, details *UserDetails) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[username] = userDetailsCacheEntry{
+		details: details,
+		expires: c.clock.Now().Add(c.ttl),
+	}
+
+	c.purgeExpired()
+}
+
+// Delete removes every entry cached for the username either as the input or as the username of the details.
+func (c *userDetailsCache) Delete(username string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, entry := range c.entries {
+		if key == username || entry.details.Username == username {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// purgeExpired removes the expired entries, it must be called with the lock held.
+func (c *userDetailsCache) purgeExpired() {
+	now := c.clock.Now()
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
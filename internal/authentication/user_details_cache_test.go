@@ -0,0 +1,76 @@
+package authentication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func TestShouldReturnCachedUserDetailsUntilExpired(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	cache := newUserDetailsCache(time.Minute, clock)
+
+	details := &UserDetails{Username: "john", Groups: []string{"admins"}}
+	cache.Set("john", details)
+
+	cached, ok := cache.Get("john")
+	require.True(t, ok)
+	assert.Equal(t, details, cached)
+
+	clock.now = clock.now.Add(59 * time.Second)
+
+	_, ok = cache.Get("john")
+	assert.True(t, ok)
+
+	clock.now = clock.now.Add(time.Second)
+
+	_, ok = cache.Get("john")
+	assert.False(t, ok)
+}
+
+func TestShouldDeleteCachedUserDetailsByInputOrUsername(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	cache := newUserDetailsCache(time.Minute, clock)
+
+	cache.Set("john", &UserDetails{Username: "john"})
+	cache.Set("john@example.com", &UserDetails{Username: "john"})
+	cache.Set("harry", &UserDetails{Username: "harry"})
+
+	cache.Delete("john")
+
+	_, ok := cache.Get("john")
+	assert.False(t, ok)
+
+	_, ok = cache.Get("john@example.com")
+	assert.False(t, ok)
+
+	_, ok = cache.Get("harry")
+	assert.True(t, ok)
+}
+
+func TestShouldPurgeExpiredUserDetails(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	cache := newUserDetailsCache(time.Minute, clock)
+
+	cache.Set("john", &UserDetails{Username: "john"})
+
+	clock.now = clock.now.Add(time.Minute)
+
+	cache.Set("harry", &UserDetails{Username: "harry"})
+
+	assert.Len(t, cache.entries, 1)
+}
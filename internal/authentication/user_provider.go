@@ -1,9 +1,41 @@
 package authentication
 
+import (
+	"context"
+	"errors"
+)
+
 // UserProvider is the interface for checking user password and
 // gathering user details.
+//
+// UserExists tells whether a user exists without checking any password, it is meant for the internal callers such as
+// the OpenID Connect flows and the administration only. It must never back a response of the login endpoint since its
+// result and its response time would let anyone enumerate the users, the login endpoint relies on CheckUserPassword.
 type UserProvider interface {
 	CheckUserPassword(username string, password string) (bool, error)
+	CheckUserPasswordAndGetDetails(username string, password string) (bool, *UserDetails, error)
 	GetDetails(username string) (*UserDetails, error)
+	GetDetailsByEmail(email string) (*UserDetails, error)
+	UserExists(username string) (bool, error)
 	UpdatePassword(username string, newPassword string) error
+	ListUsers() ([]string, error)
+	StartupCheck() error
+	Healthcheck() error
+}
+
+// ContextUserProvider is implemented by the user providers whose operations accept a context, which carries the ID set
+// with ContextWithOperationID correlating their logs with the request which triggered them.
+type ContextUserProvider interface {
+	CheckUserPasswordWithContext(ctx context.Context, username string, password string) (bool, error)
+	CheckUserPasswordAndGetDetailsWithContext(ctx context.Context, username string, password string) (bool, *UserDetails, error)
+	GetDetailsWithContext(ctx context.Context, username string) (*UserDetails, error)
+	UpdatePasswordWithContext(ctx context.Context, username string, newPassword string) error
+}
+
+// IsBackendUnavailableError returns true when the error is the result of the authentication backend being unreachable,
+// busy or unavailable rather than of the credentials of the user. The failed logins resulting from such an error must
+// not be regulated, otherwise an outage of the authentication backend bans every user trying to login.
+func IsBackendUnavailableError(err error) bool {
+	return errors.Is(err, ErrConnectionFailed) || errors.Is(err, ErrProviderClosed) || errors.Is(err, context.DeadlineExceeded) ||
+		isTransientLDAPError(err)
 }
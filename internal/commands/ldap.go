@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// LDAPTestUserCmd resolves a user with the LDAP authentication backend of the configuration and reports the computed
+// filters, the DN, the groups and the result of the bind with the password of the user.
+var LDAPTestUserCmd = &cobra.Command{
+	Use:   "ldap-test-user [yaml] [username]",
+	Short: "Check a user resolves and authenticates against the LDAP authentication backend of a configuration. The password is read from the standard input.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		config, errs := configuration.Read(args[0])
+		if len(errs) != 0 {
+			for _, err := range errs {
+				log.Printf("Error occurred parsing configuration: %s\n", err)
+			}
+
+			os.Exit(1)
+		}
+
+		if config.AuthenticationBackend.Ldap == nil {
+			log.Fatalf("The configuration doesn't define an LDAP authentication backend\n")
+		}
+
+		certPool, errs, _ := utils.NewX509CertPool(config.CertificatesDirectory, config)
+		if len(errs) != 0 {
+			log.Fatalf("Error occurred loading the certificates: %s\n", errs[0])
+		}
+
+		fmt.Fprint(os.Stderr, "Password (leave empty to skip the bind): ")
+
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Fatalf("Error occurred reading the password: %s\n", err)
+		}
+
+		provider := authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap, certPool)
+
+		report, err := provider.TestUser(args[1], strings.TrimRight(password, "\r\n"))
+
+		fmt.Printf("Users filter: %s\n", report.UsersFilter)
+
+		if err != nil {
+			log.Fatalf("Error occurred resolving the user: %s\n", err)
+		}
+
+		fmt.Printf("DN: %s\n", report.DN)
+		fmt.Printf("Username: %s\n", report.Username)
+		fmt.Printf("Groups filter: %s\n", report.GroupsFilter)
+		fmt.Printf("Groups: %s\n", strings.Join(report.Groups, ", "))
+
+		switch {
+		case report.Authenticated:
+			fmt.Printf("Bind: succeeded over %s", report.Transport.Transport)
+
+			if report.Transport.TLSVersion != "" {
+				fmt.Printf(" (%s)", report.Transport.TLSVersion)
+			}
+
+			fmt.Println()
+
+			if report.AuthzID != "" {
+				fmt.Printf("Authorization identity: %s\n", report.AuthzID)
+			}
+		case report.BindError != nil:
+			fmt.Printf("Bind: failed. Cause: %s\n", report.BindError)
+			os.Exit(1)
+		default:
+			fmt.Println("Bind: skipped")
+		}
+	},
+	Args: cobra.ExactArgs(2),
+}
@@ -26,6 +26,7 @@ func resetEnv() {
 	_ = os.Unsetenv("AUTHELIA_SESSION_SECRET_FILE")
 	_ = os.Unsetenv("AUTHELIA_SESSION_SECRET_FILE")
 	_ = os.Unsetenv("AUTHELIA_AUTHENTICATION_BACKEND_LDAP_PASSWORD_FILE")
+	_ = os.Unsetenv("AUTHELIA_AUTHENTICATION_BACKEND_LDAP_PASSWORD_MODIFY_PASSWORD_FILE")
 	_ = os.Unsetenv("AUTHELIA_NOTIFIER_SMTP_PASSWORD_FILE")
 	_ = os.Unsetenv("AUTHELIA_SESSION_REDIS_PASSWORD_FILE")
 	_ = os.Unsetenv("AUTHELIA_STORAGE_MYSQL_PASSWORD_FILE")
@@ -48,6 +49,8 @@ func setupEnv(t *testing.T) string {
 	createTestingTempFile(t, dir, "duo", "duo_secret_from_env")
 	createTestingTempFile(t, dir, "session", "session_secret_from_env")
 	createTestingTempFile(t, dir, "authentication", "ldap_secret_from_env")
+	createTestingTempFile(t, dir, "password_modify", "ldap_password_modify_secret_from_env")
+	createTestingTempFile(t, dir, "empty", "\n")
 	createTestingTempFile(t, dir, "notifier", "smtp_secret_from_env")
 	createTestingTempFile(t, dir, "redis", "redis_secret_from_env")
 	createTestingTempFile(t, dir, "mysql", "mysql_secret_from_env")
@@ -225,3 +228,40 @@ func TestShouldOnlyAllowEnvOrConfig(t *testing.T) {
 	require.Len(t, errors, 1)
 	require.EqualError(t, errors[0], "error loading secret (jwt_secret): it's already defined in the config file")
 }
+
+func TestShouldLoadLDAPPasswordModifyPasswordFromSecret(t *testing.T) {
+	dir := setupEnv(t)
+
+	require.NoError(t, os.Setenv("AUTHELIA_JWT_SECRET_FILE", dir+"jwt"))
+	require.NoError(t, os.Setenv("AUTHELIA_DUO_API_SECRET_KEY_FILE", dir+"duo"))
+	require.NoError(t, os.Setenv("AUTHELIA_SESSION_SECRET_FILE", dir+"session"))
+	require.NoError(t, os.Setenv("AUTHELIA_AUTHENTICATION_BACKEND_LDAP_PASSWORD_FILE", dir+"authentication"))
+	require.NoError(t, os.Setenv("AUTHELIA_AUTHENTICATION_BACKEND_LDAP_PASSWORD_MODIFY_PASSWORD_FILE", dir+"password_modify"))
+	require.NoError(t, os.Setenv("AUTHELIA_NOTIFIER_SMTP_PASSWORD_FILE", dir+"notifier"))
+	require.NoError(t, os.Setenv("AUTHELIA_SESSION_REDIS_PASSWORD_FILE", dir+"redis"))
+	require.NoError(t, os.Setenv("AUTHELIA_STORAGE_MYSQL_PASSWORD_FILE", dir+"mysql"))
+
+	config, errors := Read("./test_resources/config.yml")
+	require.Len(t, errors, 0)
+
+	assert.Equal(t, "ldap_secret_from_env", config.AuthenticationBackend.Ldap.Password)
+	assert.Equal(t, "ldap_password_modify_secret_from_env", config.AuthenticationBackend.Ldap.PasswordModifyPassword)
+}
+
+func TestShouldErrorOnEmptySecretFile(t *testing.T) {
+	dir := setupEnv(t)
+
+	require.NoError(t, os.Setenv("AUTHELIA_JWT_SECRET_FILE", dir+"jwt"))
+	require.NoError(t, os.Setenv("AUTHELIA_DUO_API_SECRET_KEY_FILE", dir+"duo"))
+	require.NoError(t, os.Setenv("AUTHELIA_SESSION_SECRET_FILE", dir+"session"))
+	require.NoError(t, os.Setenv("AUTHELIA_AUTHENTICATION_BACKEND_LDAP_PASSWORD_FILE", dir+"empty"))
+	require.NoError(t, os.Setenv("AUTHELIA_NOTIFIER_SMTP_PASSWORD_FILE", dir+"notifier"))
+	require.NoError(t, os.Setenv("AUTHELIA_SESSION_REDIS_PASSWORD_FILE", dir+"redis"))
+	require.NoError(t, os.Setenv("AUTHELIA_STORAGE_MYSQL_PASSWORD_FILE", dir+"mysql"))
+
+	_, errors := Read("./test_resources/config.yml")
+	require.Len(t, errors, 2)
+
+	assert.EqualError(t, errors[0], "error loading secret file (authentication_backend.ldap.password): the file "+dir+"empty is empty")
+	assert.EqualError(t, errors[1], "Please provide a password to connect to the LDAP server")
+}
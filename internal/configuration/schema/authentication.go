@@ -1,24 +1,152 @@
 package schema
 
+import "strings"
+
 // LDAPAuthenticationBackendConfiguration represents the configuration related to LDAP server.
 type LDAPAuthenticationBackendConfiguration struct {
-	Implementation       string     `mapstructure:"implementation"`
-	URL                  string     `mapstructure:"url"`
-	BaseDN               string     `mapstructure:"base_dn"`
-	AdditionalUsersDN    string     `mapstructure:"additional_users_dn"`
-	UsersFilter          string     `mapstructure:"users_filter"`
-	AdditionalGroupsDN   string     `mapstructure:"additional_groups_dn"`
-	GroupsFilter         string     `mapstructure:"groups_filter"`
-	GroupNameAttribute   string     `mapstructure:"group_name_attribute"`
-	UsernameAttribute    string     `mapstructure:"username_attribute"`
-	MailAttribute        string     `mapstructure:"mail_attribute"`
-	DisplayNameAttribute string     `mapstructure:"display_name_attribute"`
-	User                 string     `mapstructure:"user"`
-	Password             string     `mapstructure:"password"`
-	StartTLS             bool       `mapstructure:"start_tls"`
-	TLS                  *TLSConfig `mapstructure:"tls"`
-	SkipVerify           *bool      `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
-	MinimumTLSVersion    string     `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+	Implementation                  string                                  `mapstructure:"implementation"`
+	URL                             string                                  `mapstructure:"url"`
+	GlobalCatalogURL                string                                  `mapstructure:"global_catalog_url"`
+	ProxyURL                        string                                  `mapstructure:"proxy_url"`
+	BaseDN                          string                                  `mapstructure:"base_dn"`
+	AdditionalUsersDN               string                                  `mapstructure:"additional_users_dn"`
+	ExtraUsersDNs                   []string                                `mapstructure:"extra_users_dns"`
+	UsersFilter                     string                                  `mapstructure:"users_filter"`
+	DisableUsersFilterConstraint    bool                                    `mapstructure:"disable_users_filter_constraint"`
+	ExcludeDisabledUsers            bool                                    `mapstructure:"exclude_disabled_users"`
+	UsersSearchScope                string                                  `mapstructure:"users_search_scope"`
+	ListUsersFilter                 string                                  `mapstructure:"list_users_filter"`
+	MaxUsers                        int                                     `mapstructure:"max_users"`
+	MultipleUsersPolicy             string                                  `mapstructure:"multiple_users_policy"`
+	UsernameNormalization           string                                  `mapstructure:"username_normalization"`
+	PreferredUsersDN                string                                  `mapstructure:"preferred_users_dn"`
+	AdditionalGroupsDN              string                                  `mapstructure:"additional_groups_dn"`
+	ExtraGroupsDNs                  []string                                `mapstructure:"extra_groups_dns"`
+	GroupsFilter                    string                                  `mapstructure:"groups_filter"`
+	GroupsSearchMode                string                                  `mapstructure:"groups_search_mode"`
+	MemberOfAttribute               string                                  `mapstructure:"member_of_attribute"`
+	GroupsSearchScope               string                                  `mapstructure:"groups_search_scope"`
+	DerefAliases                    string                                  `mapstructure:"deref_aliases"`
+	GroupNameAttribute              string                                  `mapstructure:"group_name_attribute"`
+	GroupNameAttributeFallbacks     []string                                `mapstructure:"group_name_attribute_fallbacks"`
+	GroupDisplayNameAttribute       string                                  `mapstructure:"group_display_name_attribute"`
+	GroupNamePatterns               []string                                `mapstructure:"group_name_patterns"`
+	GroupNameNormalization          string                                  `mapstructure:"group_name_normalization"`
+	GroupsAllowlist                 []string                                `mapstructure:"groups_allowlist"`
+	GroupsDenylist                  []string                                `mapstructure:"groups_denylist"`
+	PageSize                        int                                     `mapstructure:"page_size"`
+	ConcurrentSearches              bool                                    `mapstructure:"concurrent_searches"`
+	MaxAttempts                     int                                     `mapstructure:"max_attempts"`
+	MaxGroups                       int                                     `mapstructure:"max_groups"`
+	MaxAttributeValueSize           int                                     `mapstructure:"max_attribute_value_size"`
+	MaxSearchResultSize             int                                     `mapstructure:"max_search_result_size"`
+	GroupsCacheTTL                  string                                  `mapstructure:"groups_cache_ttl"`
+	UserBindTimeout                 string                                  `mapstructure:"user_bind_timeout"`
+	CircuitBreakerThreshold         int                                     `mapstructure:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown          string                                  `mapstructure:"circuit_breaker_cooldown"`
+	UsernameAttribute               string                                  `mapstructure:"username_attribute"`
+	UsernameAttributeFallbacks      []string                                `mapstructure:"username_attribute_fallbacks"`
+	LoginAttribute                  string                                  `mapstructure:"login_attribute"`
+	AttributePlaceholders           []LDAPAttributePlaceholderConfiguration `mapstructure:"attribute_placeholders"`
+	CaseInsensitiveUsernames        bool                                    `mapstructure:"case_insensitive_usernames"`
+	MailAttribute                   string                                  `mapstructure:"mail_attribute"`
+	MailAttributeFallbacks          []string                                `mapstructure:"mail_attribute_fallbacks"`
+	PrimaryMailAttribute            string                                  `mapstructure:"primary_mail_attribute"`
+	DisplayNameAttribute            string                                  `mapstructure:"display_name_attribute"`
+	DisplayNameAttributeFallbacks   []string                                `mapstructure:"display_name_attribute_fallbacks"`
+	PhotoAttribute                  string                                  `mapstructure:"photo_attribute"`
+	StableIDAttribute               string                                  `mapstructure:"stable_id_attribute"`
+	DNAttribute                     string                                  `mapstructure:"dn_attribute"`
+	AdditionalAttributes            []string                                `mapstructure:"additional_attributes"`
+	OperationalAttributes           []string                                `mapstructure:"operational_attributes"`
+	EscapedCharacters               string                                  `mapstructure:"escaped_characters"`
+	AuthMethod                      string                                  `mapstructure:"auth_method"`
+	BindDNTemplate                  string                                  `mapstructure:"bind_dn_template"`
+	KerberosPrincipal               string                                  `mapstructure:"kerberos_principal"`
+	KerberosKeytab                  string                                  `mapstructure:"kerberos_keytab"`
+	KerberosConfig                  string                                  `mapstructure:"kerberos_config"`
+	KerberosServicePrincipal        string                                  `mapstructure:"kerberos_service_principal"`
+	User                            string                                  `mapstructure:"user"`
+	Password                        string                                  `mapstructure:"password"`
+	StartTLS                        bool                                    `mapstructure:"start_tls"`
+	FollowReferrals                 bool                                    `mapstructure:"follow_referrals"`
+	ReferralHosts                   []string                                `mapstructure:"referral_hosts"`
+	PasswordModifyUser              string                                  `mapstructure:"password_modify_user"`
+	PasswordModifyPassword          string                                  `mapstructure:"password_modify_password"`
+	PasswordModifyExtendedOperation bool                                    `mapstructure:"password_modify_extended_operation"`
+	PasswordChangeAsUser            bool                                    `mapstructure:"password_change_as_user"`
+	PasswordModifyAssertionFilter   string                                  `mapstructure:"password_modify_assertion_filter"`
+	PasswordModifyPreReadAttributes []string                                `mapstructure:"password_modify_pre_read_attributes"`
+	ResetTokenAttribute             string                                  `mapstructure:"reset_token_attribute"`
+	ResetTokenExpiryAttribute       string                                  `mapstructure:"reset_token_expiry_attribute"`
+	PPolicyControl                  bool                                    `mapstructure:"ppolicy_control"`
+	WhoAmI                          bool                                    `mapstructure:"whoami"`
+	PasswordPolicy                  LDAPPasswordPolicyConfiguration         `mapstructure:"password_policy"`
+	EmergencyFallback               LDAPEmergencyFallbackConfiguration      `mapstructure:"emergency_fallback"`
+	WarmUp                          LDAPWarmUpConfiguration                 `mapstructure:"warm_up"`
+	TLS                             *TLSConfig                              `mapstructure:"tls"`
+	StartTLSConfig                  *TLSConfig                              `mapstructure:"start_tls_config"`
+	SkipVerify                      *bool                                   `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
+	MinimumTLSVersion               string                                  `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+}
+
+// ExcludeDisabledUsersFilter returns the users filter combined with the filter excluding the disabled users of the
+// implementation when `exclude_disabled_users` is enabled, the disabled accounts of Active Directory and the accounts
+// locked by the password policy overlay of the other directories such as OpenLDAP. The filter is returned as is when it
+// already excludes them, for instance the default users filter of Active Directory which omits the parenthesis of the
+// negated filter.
+func (c *LDAPAuthenticationBackendConfiguration) ExcludeDisabledUsersFilter(filter string) string {
+	if !c.ExcludeDisabledUsers {
+		return filter
+	}
+
+	disabled := LDAPFilterPasswordPolicyUnlockedUsers
+
+	if c.Implementation == LDAPImplementationActiveDirectory {
+		disabled = LDAPFilterActiveDirectoryEnabledUsers
+	}
+
+	unparenthesized := "(!" + strings.TrimSuffix(strings.TrimPrefix(disabled, "(!("), "))") + ")"
+
+	if strings.Contains(filter, disabled) || strings.Contains(filter, unparenthesized) {
+		return filter
+	}
+
+	return "(&" + filter + disabled + ")"
+}
+
+// LDAPPasswordPolicyConfiguration represents the policy the passwords must satisfy before being updated in the LDAP
+// server.
+type LDAPPasswordPolicyConfiguration struct {
+	MinLength           int      `mapstructure:"min_length"`
+	RequireUppercase    bool     `mapstructure:"require_uppercase"`
+	RequireLowercase    bool     `mapstructure:"require_lowercase"`
+	RequireNumber       bool     `mapstructure:"require_number"`
+	RequireSpecial      bool     `mapstructure:"require_special"`
+	ForbidUsername      bool     `mapstructure:"forbid_username"`
+	ForbiddenSubstrings []string `mapstructure:"forbidden_substrings"`
+}
+
+// LDAPAttributePlaceholderConfiguration represents a custom placeholder of the users filters replaced by the name of an
+// attribute, like the {username_attribute} placeholder is replaced by the username attribute.
+type LDAPAttributePlaceholderConfiguration struct {
+	Name      string `mapstructure:"name"`
+	Attribute string `mapstructure:"attribute"`
+}
+
+// LDAPEmergencyFallbackConfiguration represents the configuration of the file-based backend the named emergency
+// users fall back to when the LDAP server is unavailable.
+type LDAPEmergencyFallbackConfiguration struct {
+	Enabled   bool     `mapstructure:"enabled"`
+	Path      string   `mapstructure:"path"`
+	Usernames []string `mapstructure:"usernames"`
+}
+
+// LDAPWarmUpConfiguration represents the configuration of the connections to the LDAP servers opened and bound at
+// startup so the first logins don't pay the full cost of the TLS handshakes.
+type LDAPWarmUpConfiguration struct {
+	Enabled  bool `mapstructure:"enabled"`
+	Required bool `mapstructure:"required"`
 }
 
 // FileAuthenticationBackendConfiguration represents the configuration related to file-based backend.
@@ -74,11 +202,30 @@ var DefaultPasswordSHA512Configuration = PasswordConfiguration{
 
 // DefaultLDAPAuthenticationBackendConfiguration represents the default LDAP config.
 var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendConfiguration{
-	Implementation:       LDAPImplementationCustom,
-	UsernameAttribute:    "uid",
-	MailAttribute:        "mail",
-	DisplayNameAttribute: "displayname",
-	GroupNameAttribute:   "cn",
+	Implementation:          LDAPImplementationCustom,
+	UsernameAttribute:       "uid",
+	MailAttribute:           "mail",
+	DisplayNameAttribute:    "displayname",
+	GroupNameAttribute:      "cn",
+	DNAttribute:             "entryDN",
+	UsersSearchScope:        LDAPSearchScopeSub,
+	MultipleUsersPolicy:     LDAPMultipleUsersPolicyError,
+	UsernameNormalization:   LDAPUsernameNormalizationNFC,
+	GroupNameNormalization:  LDAPGroupNameNormalizationNone,
+	AuthMethod:              LDAPAuthMethodSimple,
+	KerberosConfig:          "/etc/krb5.conf",
+	GroupsSearchScope:       LDAPSearchScopeSub,
+	GroupsSearchMode:        LDAPGroupsSearchModeFilter,
+	MemberOfAttribute:       "memberOf",
+	DerefAliases:            LDAPDerefAliasesNever,
+	PageSize:                1000,
+	MaxAttempts:             2,
+	CircuitBreakerThreshold: 5,
+	CircuitBreakerCooldown:  "10s",
+	MaxGroups:               1000,
+	MaxUsers:                10000,
+	MaxAttributeValueSize:   1048576,
+	MaxSearchResultSize:     16777216,
 	TLS: &TLSConfig{
 		MinimumVersion: "TLS1.2",
 	},
@@ -86,10 +233,12 @@ var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendCon
 
 // DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration represents the default LDAP config for the MSAD Implementation.
 var DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration = LDAPAuthenticationBackendConfiguration{
-	UsersFilter:          "(&(|({username_attribute}={input})({mail_attribute}={input}))(objectCategory=person)(objectClass=user)(!userAccountControl:1.2.840.113556.1.4.803:=2)(!pwdLastSet=0))",
+	UsersFilter:          "(&(|({username_attribute}={input})({mail_attribute}={input})(userPrincipalName={input}))(objectCategory=person)(objectClass=user)(!userAccountControl:1.2.840.113556.1.4.803:=2))",
 	UsernameAttribute:    "sAMAccountName",
 	MailAttribute:        "mail",
 	DisplayNameAttribute: "displayName",
+	StableIDAttribute:    "objectGUID",
 	GroupsFilter:         "(&(member={dn})(objectClass=group))",
 	GroupNameAttribute:   "cn",
+	MemberOfAttribute:    "memberOf",
 }
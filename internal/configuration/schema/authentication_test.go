@@ -0,0 +1,58 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldExcludeDisabledUsers(t *testing.T) {
+	testCases := []struct {
+		name           string
+		implementation string
+		filter         string
+		expected       string
+	}{
+		{
+			"ActiveDirectory",
+			schema.LDAPImplementationActiveDirectory,
+			"(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))",
+			"(&(&(sAMAccountName={input})(objectCategory=person)(objectClass=user))(!(userAccountControl:1.2.840.113556.1.4.803:=2)))",
+		},
+		{
+			"ActiveDirectoryDefault",
+			schema.LDAPImplementationActiveDirectory,
+			schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+			schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter,
+		},
+		{
+			"Custom",
+			schema.LDAPImplementationCustom,
+			"(&(uid={input})(objectClass=person))",
+			"(&(&(uid={input})(objectClass=person))(!(pwdAccountLockedTime=*)))",
+		},
+		{
+			"CustomAlreadyExcluded",
+			schema.LDAPImplementationCustom,
+			"(&(uid={input})(objectClass=person)(!(pwdAccountLockedTime=*)))",
+			"(&(uid={input})(objectClass=person)(!(pwdAccountLockedTime=*)))",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configuration := schema.LDAPAuthenticationBackendConfiguration{
+				Implementation:       tc.implementation,
+				ExcludeDisabledUsers: true,
+			}
+
+			assert.Equal(t, tc.expected, configuration.ExcludeDisabledUsersFilter(tc.filter))
+
+			configuration.ExcludeDisabledUsers = false
+
+			assert.Equal(t, tc.filter, configuration.ExcludeDisabledUsersFilter(tc.filter))
+		})
+	}
+}
@@ -23,3 +23,84 @@ const LDAPImplementationCustom = "custom"
 
 // LDAPImplementationActiveDirectory is the string for the Active Directory LDAP implementation.
 const LDAPImplementationActiveDirectory = "activedirectory"
+
+// LDAPSearchScopeBase is the string for the LDAP base object search scope.
+const LDAPSearchScopeBase = "base"
+
+// LDAPSearchScopeOne is the string for the LDAP single level search scope.
+const LDAPSearchScopeOne = "one"
+
+// LDAPSearchScopeSub is the string for the LDAP whole subtree search scope.
+const LDAPSearchScopeSub = "sub"
+
+// LDAPMultipleUsersPolicyError is the string for the LDAP multiple users policy failing when several users match.
+const LDAPMultipleUsersPolicyError = "error"
+
+// LDAPMultipleUsersPolicyFirst is the string for the LDAP multiple users policy selecting the first user by DN.
+const LDAPMultipleUsersPolicyFirst = "first"
+
+// LDAPMultipleUsersPolicyPreferredDN is the string for the LDAP multiple users policy selecting the user in the
+// preferred users DN.
+const LDAPMultipleUsersPolicyPreferredDN = "preferred_dn"
+
+// LDAPUsernameNormalizationNFC is the string for the normalization of the usernames to the NFC Unicode form.
+const LDAPUsernameNormalizationNFC = "nfc"
+
+// LDAPUsernameNormalizationNFD is the string for the normalization of the usernames to the NFD Unicode form.
+const LDAPUsernameNormalizationNFD = "nfd"
+
+// LDAPUsernameNormalizationNFKC is the string for the normalization of the usernames to the NFKC Unicode form.
+const LDAPUsernameNormalizationNFKC = "nfkc"
+
+// LDAPUsernameNormalizationNFKD is the string for the normalization of the usernames to the NFKD Unicode form.
+const LDAPUsernameNormalizationNFKD = "nfkd"
+
+// LDAPUsernameNormalizationNone is the string for the usernames searched as entered.
+const LDAPUsernameNormalizationNone = "none"
+
+// LDAPGroupNameNormalizationNone is the string for the group names kept as returned by the LDAP server.
+const LDAPGroupNameNormalizationNone = "none"
+
+// LDAPGroupNameNormalizationTrim is the string for the group names stripped of their leading and trailing whitespaces.
+const LDAPGroupNameNormalizationTrim = "trim"
+
+// LDAPGroupNameNormalizationLowercase is the string for the group names stripped of their leading and trailing
+// whitespaces and converted to lowercase.
+const LDAPGroupNameNormalizationLowercase = "lowercase"
+
+// LDAPGroupsSearchModeFilter is the string for the groups searched with the groups filter.
+const LDAPGroupsSearchModeFilter = "filter"
+
+// LDAPGroupsSearchModeMemberOf is the string for the groups read from the DNs listed by the member of attribute of the
+// users.
+const LDAPGroupsSearchModeMemberOf = "memberof"
+
+// LDAPFilterActiveDirectoryEnabledUsers is the filter excluding the disabled accounts of Active Directory, which have
+// the ACCOUNTDISABLE flag of their userAccountControl attribute set.
+const LDAPFilterActiveDirectoryEnabledUsers = "(!(userAccountControl:1.2.840.113556.1.4.803:=2))"
+
+// LDAPFilterPasswordPolicyUnlockedUsers is the filter excluding the accounts locked by the password policy overlay of
+// OpenLDAP, which sets their pwdAccountLockedTime attribute.
+const LDAPFilterPasswordPolicyUnlockedUsers = "(!(pwdAccountLockedTime=*))"
+
+// LDAPDerefAliasesNever is the string for the aliases never dereferenced by the LDAP searches.
+const LDAPDerefAliasesNever = "never"
+
+// LDAPDerefAliasesSearching is the string for the aliases dereferenced among the entries below the base DN of the
+// LDAP searches.
+const LDAPDerefAliasesSearching = "searching"
+
+// LDAPDerefAliasesFinding is the string for the aliases dereferenced when locating the base DN of the LDAP searches.
+const LDAPDerefAliasesFinding = "finding"
+
+// LDAPDerefAliasesAlways is the string for the aliases always dereferenced by the LDAP searches.
+const LDAPDerefAliasesAlways = "always"
+
+// LDAPAuthMethodSimple is the string for the LDAP simple bind with the user and the password.
+const LDAPAuthMethodSimple = "simple"
+
+// LDAPAuthMethodExternal is the string for the LDAP SASL EXTERNAL bind with the client certificate.
+const LDAPAuthMethodExternal = "external"
+
+// LDAPAuthMethodGSSAPI is the string for the LDAP SASL GSSAPI bind with the Kerberos principal of a keytab.
+const LDAPAuthMethodGSSAPI = "gssapi"
@@ -5,6 +5,7 @@ type ServerConfiguration struct {
 	Path            string `mapstructure:"path"`
 	ReadBufferSize  int    `mapstructure:"read_buffer_size"`
 	WriteBufferSize int    `mapstructure:"write_buffer_size"`
+	EnableMetrics   bool   `mapstructure:"enable_metrics"`
 }
 
 // DefaultServerConfiguration represents the default values of the ServerConfiguration.
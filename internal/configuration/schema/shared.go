@@ -5,4 +5,12 @@ type TLSConfig struct {
 	MinimumVersion string `mapstructure:"minimum_version"`
 	SkipVerify     bool   `mapstructure:"skip_verify"`
 	ServerName     string `mapstructure:"server_name"`
+	Certificate    string `mapstructure:"certificate"`
+	Key            string `mapstructure:"key"`
+
+	Renegotiation       string `mapstructure:"renegotiation"`
+	RequireOCSPStapling bool   `mapstructure:"require_ocsp_stapling"`
+
+	PinnedCertificates []string `mapstructure:"pinned_certificates"`
+	PinnedPublicKeys   []string `mapstructure:"pinned_public_keys"`
 }
@@ -87,3 +87,17 @@ func ValidateRules(configuration schema.AccessControlConfiguration, validator *s
 		}
 	}
 }
+
+// NormalizeRulesGroups normalizes the groups of the subjects of the rules like the groups returned by the LDAP
+// authentication backend, so a rule written with another case or surrounded by whitespaces still matches the group.
+func NormalizeRulesGroups(configuration *schema.AccessControlConfiguration, normalization string) {
+	for _, rule := range configuration.Rules {
+		for _, subjectRule := range rule.Subjects {
+			for i, subject := range subjectRule {
+				if strings.HasPrefix(subject, "group:") {
+					subjectRule[i] = "group:" + utils.NormalizeGroupName(strings.TrimPrefix(subject, "group:"), normalization)
+				}
+			}
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package validator
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"regexp"
 	"strings"
 
+	"github.com/go-ldap/ldap/v3"
+
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/utils"
 )
@@ -72,6 +77,12 @@ func validateFileAuthenticationBackend(configuration *schema.FileAuthenticationB
 	}
 }
 
+// isLdapTLSURL returns true when the connections to the LDAP servers of the URL are encrypted with TLS from the start,
+// either an ldaps URL or a srvs URL whose discovered servers are connected to with the ldaps scheme.
+func isLdapTLSURL(ldapURL string) bool {
+	return strings.HasPrefix(ldapURL, schemeLDAPS+"://") || strings.HasPrefix(ldapURL, schemeSRVS+"://")
+}
+
 // Wrapper for test purposes to exclude the hostname from the return.
 func validateLdapURLSimple(ldapURL string, validator *schema.StructValidator) (finalURL string) {
 	finalURL, _ = validateLdapURL(ldapURL, validator)
@@ -87,8 +98,22 @@ func validateLdapURL(ldapURL string, validator *schema.StructValidator) (finalUR
 		return "", ""
 	}
 
+	if parsedURL.Scheme == schemeSRV || parsedURL.Scheme == schemeSRVS {
+		if parsedURL.Hostname() == "" || parsedURL.Port() != "" || (parsedURL.Path != "" && parsedURL.Path != "/") {
+			validator.Push(fmt.Errorf("The LDAP URL %s must only contain the domain of the SRV records, it should be something like %s://example.com", parsedURL.String(), parsedURL.Scheme))
+		}
+
+		// The server name is the name of the discovered server which is only known when connecting.
+		return parsedURL.String(), ""
+	}
+
+	// The URLs of the Unix domain sockets have no host, the socket is the path of the URL.
+	if parsedURL.Scheme == schemeLDAPI {
+		return parsedURL.String(), ""
+	}
+
 	if !(parsedURL.Scheme == schemeLDAP || parsedURL.Scheme == schemeLDAPS) {
-		validator.Push(errors.New("Unknown scheme for ldap url, should be ldap:// or ldaps://"))
+		validator.Push(errors.New("Unknown scheme for ldap url, should be ldap://, ldaps://, ldapi://, srv:// or srvs://"))
 		return "", ""
 	}
 
@@ -99,15 +124,376 @@ func validateLdapURL(ldapURL string, validator *schema.StructValidator) (finalUR
 	return parsedURL.String(), parsedURL.Hostname()
 }
 
+// ldapFilterPlaceholdersReplacer replaces the placeholders of the filters with dummy values so the filters can be
+// compiled.
+var ldapFilterPlaceholdersReplacer = strings.NewReplacer(
+	"{login_attribute}", "mail",
+	"{username_attribute}", "uid",
+	"{mail_attribute}", "mail",
+	"{display_name_attribute}", "displayName",
+	"{input}", "input",
+	"{username}", "username",
+	"{dn}", "dn",
+	"{0}", "input",
+	"{1}", "username",
+)
+
+// validateLdapLoginAttribute checks the {login_attribute} placeholder of the users filter is only used along with a
+// login attribute.
+func validateLdapLoginAttribute(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.LoginAttribute == "" && strings.Contains(configuration.UsersFilter, "{login_attribute}") {
+		validator.Push(errors.New("The LDAP `users_filter` contains the {login_attribute} placeholder but no `login_attribute` is configured"))
+	}
+}
+
+// ldapUsersFilterPlaceholders are the built-in placeholders of the users filters, the custom placeholders must not
+// shadow them.
+var ldapUsersFilterPlaceholders = []string{"login_attribute", "username_attribute", "mail_attribute", "display_name_attribute", "input", "0"}
+
+var ldapPlaceholderRegexp = regexp.MustCompile(`{([a-z0-9_]+)}`)
+
+var ldapAttributePlaceholderNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validateLdapAttributePlaceholders checks the custom placeholders of the users filters are named like the built-in
+// placeholders without shadowing them and name an attribute.
+func validateLdapAttributePlaceholders(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	seen := make(map[string]bool)
+
+	for _, placeholder := range configuration.AttributePlaceholders {
+		switch {
+		case !ldapAttributePlaceholderNameRegexp.MatchString(placeholder.Name):
+			validator.Push(fmt.Errorf("The LDAP `attribute_placeholders` name '%s' is invalid, it must start with a lowercase letter followed by lowercase letters, digits or underscores", placeholder.Name))
+		case utils.IsStringInSlice(placeholder.Name, ldapUsersFilterPlaceholders):
+			validator.Push(fmt.Errorf("The LDAP `attribute_placeholders` name '%s' is a built-in placeholder", placeholder.Name))
+		case seen[placeholder.Name]:
+			validator.Push(fmt.Errorf("The LDAP `attribute_placeholders` name '%s' is configured more than once", placeholder.Name))
+		}
+
+		seen[placeholder.Name] = true
+
+		if placeholder.Attribute == "" {
+			validator.Push(fmt.Errorf("The LDAP `attribute_placeholders` placeholder '%s' must name an `attribute`", placeholder.Name))
+		}
+	}
+}
+
+// validateLdapUsersFilterPlaceholders checks every placeholder of the users filter is either a built-in placeholder or
+// a configured attribute placeholder, an unknown placeholder would be searched as is and never match.
+func validateLdapUsersFilterPlaceholders(name string, filter string, configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	for _, match := range ldapPlaceholderRegexp.FindAllStringSubmatch(filter, -1) {
+		if utils.IsStringInSlice(match[1], ldapUsersFilterPlaceholders) {
+			continue
+		}
+
+		configured := false
+
+		for _, placeholder := range configuration.AttributePlaceholders {
+			if placeholder.Name == match[1] {
+				configured = true
+				break
+			}
+		}
+
+		if !configured {
+			validator.Push(fmt.Errorf("The LDAP `%s` contains the %s placeholder which is neither a built-in placeholder nor configured in `attribute_placeholders`", name, match[0]))
+		}
+	}
+}
+
+// validateLdapFilter checks the filter compiles once its placeholders are replaced, so a malformed filter is reported
+// on startup instead of failing every search.
+func validateLdapFilter(name string, filter string, validator *schema.StructValidator) {
+	if _, err := ldap.CompileFilter(ldapFilterPlaceholdersReplacer.Replace(filter)); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `%s` '%s' is not a valid filter. Cause: %s", name, filter, err))
+	}
+}
+
+// validateLdapBindDNTemplate validates the template of the DN the users are bound with, which must contain the {input}
+// placeholder and be a valid DN once the placeholder is replaced.
+func validateLdapBindDNTemplate(template string, validator *schema.StructValidator) {
+	if template == "" {
+		return
+	}
+
+	if !strings.Contains(template, "{input}") {
+		validator.Push(fmt.Errorf("The LDAP `bind_dn_template` must contain the {input} placeholder, you configured '%s'", template))
+		return
+	}
+
+	if _, err := ldap.ParseDN(strings.ReplaceAll(template, "{input}", "input")); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `bind_dn_template` '%s' is not a valid DN. Cause: %s", template, err))
+	}
+}
+
+// validateLdapBindUser warns about the bind users the server is unlikely to accept. Active Directory accepts a DN, a
+// UPN like user@example.com or a down-level logon name like EXAMPLE\user whereas the other servers expect a DN.
+func validateLdapBindUser(name string, user string, implementation string, validator *schema.StructValidator) {
+	if user == "" {
+		return
+	}
+
+	isDN := strings.Contains(user, "=")
+	isADLogon := !isDN && (strings.Contains(user, "@") || strings.Contains(user, "\\"))
+
+	switch {
+	case implementation == schema.LDAPImplementationActiveDirectory && !isDN && !isADLogon:
+		validator.PushWarning(fmt.Errorf("The LDAP `%s` '%s' is neither a DN, a UPN like user@example.com nor a down-level logon name like EXAMPLE\\user, Active Directory will likely reject the bind", name, user))
+	case implementation != schema.LDAPImplementationActiveDirectory && !isDN:
+		validator.PushWarning(fmt.Errorf("The LDAP `%s` '%s' is not a DN, UPN and down-level logon names are only accepted by Active Directory", name, user))
+	}
+}
+
+func validateLdapGlobalCatalogURL(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.Implementation != schema.LDAPImplementationActiveDirectory {
+		validator.Push(errors.New("The LDAP `global_catalog_url` is only supported by the activedirectory implementation"))
+		return
+	}
+
+	if strings.HasPrefix(configuration.GlobalCatalogURL, schemeSRV+"://") || strings.HasPrefix(configuration.GlobalCatalogURL, schemeSRVS+"://") {
+		validator.Push(errors.New("The LDAP `global_catalog_url` must be an ldap:// or ldaps:// URL"))
+		return
+	}
+
+	configuration.GlobalCatalogURL = validateLdapURLSimple(configuration.GlobalCatalogURL, validator)
+}
+
+// validateLdapDNs trims the whitespaces and the stray commas around the DNs and checks they are well formed, so a typo
+// is reported on startup instead of failing every search with the DNs joined with the base DN.
+func validateLdapDNs(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	configuration.BaseDN = utils.TrimDN(configuration.BaseDN)
+	configuration.AdditionalUsersDN = utils.TrimDN(configuration.AdditionalUsersDN)
+	configuration.AdditionalGroupsDN = utils.TrimDN(configuration.AdditionalGroupsDN)
+	configuration.PreferredUsersDN = utils.TrimDN(configuration.PreferredUsersDN)
+
+	dns := []struct {
+		name, dn string
+	}{
+		{"base_dn", configuration.BaseDN},
+		{"additional_users_dn", configuration.AdditionalUsersDN},
+		{"additional_groups_dn", configuration.AdditionalGroupsDN},
+		{"preferred_users_dn", configuration.PreferredUsersDN},
+	}
+
+	for i := range configuration.ExtraUsersDNs {
+		if configuration.ExtraUsersDNs[i] = utils.TrimDN(configuration.ExtraUsersDNs[i]); configuration.ExtraUsersDNs[i] == "" {
+			validator.Push(errors.New("The LDAP `extra_users_dns` must not contain an empty DN"))
+		}
+
+		dns = append(dns, struct{ name, dn string }{"extra_users_dns", configuration.ExtraUsersDNs[i]})
+	}
+
+	for i := range configuration.ExtraGroupsDNs {
+		if configuration.ExtraGroupsDNs[i] = utils.TrimDN(configuration.ExtraGroupsDNs[i]); configuration.ExtraGroupsDNs[i] == "" {
+			validator.Push(errors.New("The LDAP `extra_groups_dns` must not contain an empty DN"))
+		}
+
+		dns = append(dns, struct{ name, dn string }{"extra_groups_dns", configuration.ExtraGroupsDNs[i]})
+	}
+
+	for _, dn := range dns {
+		if dn.dn == "" {
+			continue
+		}
+
+		if _, err := ldap.ParseDN(dn.dn); err != nil {
+			validator.Push(fmt.Errorf("The LDAP `%s` '%s' is not a valid DN. Cause: %s", dn.name, dn.dn, err))
+		}
+	}
+}
+
+// validateLdapProxyURL checks the proxy is a SOCKS5 proxy, the only kind of proxy able to tunnel the LDAP connections.
+// The password of the proxy is redacted from the errors.
+func validateLdapProxyURL(proxyURL string, validator *schema.StructValidator) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		validator.Push(fmt.Errorf("Unable to parse the LDAP `proxy_url`. Cause: %s", err))
+		return
+	}
+
+	if u.Scheme != schemeSOCKS5 && u.Scheme != schemeSOCKS5H {
+		validator.Push(fmt.Errorf("The LDAP `proxy_url` %s must be a socks5:// or socks5h:// URL", u.Redacted()))
+		return
+	}
+
+	if u.Host == "" {
+		validator.Push(errors.New("The LDAP `proxy_url` must specify the host of the proxy"))
+	}
+}
+
+// validateLdapStartTLSConfig defaults the TLS options of StartTLS to the ones of the tls section and warns when the
+// certificate of the LDAP server is not verified, so skipping the verification is not silently left on.
+func validateLdapStartTLSConfig(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.StartTLSConfig != nil {
+		if !configuration.StartTLS {
+			validator.PushWarning(errors.New("The LDAP `start_tls_config` is configured but it is only used when `start_tls` is enabled"))
+		}
+
+		if configuration.StartTLSConfig.MinimumVersion == "" {
+			configuration.StartTLSConfig.MinimumVersion = configuration.TLS.MinimumVersion
+		}
+
+		if configuration.StartTLSConfig.ServerName == "" {
+			configuration.StartTLSConfig.ServerName = configuration.TLS.ServerName
+		}
+
+		if configuration.StartTLSConfig.Certificate == "" && configuration.StartTLSConfig.Key == "" {
+			configuration.StartTLSConfig.Certificate = configuration.TLS.Certificate
+			configuration.StartTLSConfig.Key = configuration.TLS.Key
+		}
+
+		if version, err := utils.TLSStringToTLSConfigVersion(configuration.StartTLSConfig.MinimumVersion); err != nil {
+			validator.Push(fmt.Errorf("error occurred validating the LDAP start_tls_config minimum_version key with value %s: %v", configuration.StartTLSConfig.MinimumVersion, err))
+		} else if version < tls.VersionTLS12 {
+			validator.PushWarning(fmt.Errorf("The LDAP `start_tls_config.minimum_version` %s is insecure and must only be used temporarily with an LDAP server which doesn't support TLS1.2 or newer", configuration.StartTLSConfig.MinimumVersion))
+		}
+
+		validateLdapTLSRenegotiation("start_tls_config", configuration.StartTLSConfig, validator)
+		validateLdapTLSPins("start_tls_config", configuration.StartTLSConfig, validator)
+	}
+
+	validateLdapTLSRenegotiation("tls", configuration.TLS, validator)
+	validateLdapTLSPins("tls", configuration.TLS, validator)
+
+	if isLdapTLSURL(configuration.URL) && configuration.TLS.SkipVerify {
+		validator.PushWarning(errors.New("The LDAP `tls.skip_verify` is enabled, the certificate of the LDAP server is not verified which must not be used in production"))
+	}
+
+	if configuration.StartTLS {
+		if configuration.StartTLSConfig != nil && configuration.StartTLSConfig.SkipVerify {
+			validator.PushWarning(errors.New("The LDAP `start_tls_config.skip_verify` is enabled, the certificate of the LDAP server is not verified on StartTLS which must not be used in production"))
+		} else if configuration.StartTLSConfig == nil && configuration.TLS.SkipVerify {
+			validator.PushWarning(errors.New("The LDAP `tls.skip_verify` is enabled, the certificate of the LDAP server is not verified on StartTLS which must not be used in production"))
+		}
+	}
+}
+
+// validateLdapTLSRenegotiation validates the renegotiation support of the TLS options.
+func validateLdapTLSRenegotiation(name string, config *schema.TLSConfig, validator *schema.StructValidator) {
+	if _, err := utils.TLSStringToTLSRenegotiationSupport(config.Renegotiation); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `%s.renegotiation` must be one of the following values `%s`, `%s`, `%s`, you configured '%s'",
+			name, utils.TLSRenegotiationNever, utils.TLSRenegotiationOnce, utils.TLSRenegotiationFreely, config.Renegotiation))
+	}
+}
+
+// validateLdapTLSPins validates the pins of the certificates and of the public keys of the TLS options.
+func validateLdapTLSPins(name string, config *schema.TLSConfig, validator *schema.StructValidator) {
+	for _, pin := range config.PinnedCertificates {
+		if _, err := utils.ParseCertificatePin(pin); err != nil {
+			validator.Push(fmt.Errorf("The LDAP `%s.pinned_certificates` must only contain hexadecimal SHA-256 fingerprints, you configured '%s'", name, pin))
+		}
+	}
+
+	for _, pin := range config.PinnedPublicKeys {
+		if _, err := utils.ParsePublicKeyPin(pin); err != nil {
+			validator.Push(fmt.Errorf("The LDAP `%s.pinned_public_keys` must only contain base64 SHA-256 digests, you configured '%s'", name, pin))
+		}
+	}
+}
+
+// validateLdapUnixSocket ensures the options which require a network connection are not used with an ldapi URL.
+func validateLdapUnixSocket(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if !strings.HasPrefix(configuration.URL, schemeLDAPI+"://") {
+		return
+	}
+
+	if configuration.StartTLS {
+		validator.Push(errors.New("The LDAP `start_tls` must not be enabled with an ldapi URL, the Unix domain socket is never encrypted"))
+	}
+
+	if configuration.ProxyURL != "" {
+		validator.Push(errors.New("The LDAP `proxy_url` must not be configured with an ldapi URL, the Unix domain socket is only reachable locally"))
+	}
+}
+
+// validateLdapAuthMethod validates the client certificates and ensures the external auth method is only used over a
+// TLS connection presenting a client certificate.
+func validateLdapAuthMethod(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.AuthMethod == "" {
+		configuration.AuthMethod = schema.DefaultLDAPAuthenticationBackendConfiguration.AuthMethod
+	}
+
+	if _, err := utils.NewTLSCertificates(configuration.TLS); err != nil {
+		validator.Push(fmt.Errorf("error occurred validating the LDAP tls certificate: %v", err))
+	}
+
+	if configuration.StartTLSConfig != nil {
+		if _, err := utils.NewTLSCertificates(configuration.StartTLSConfig); err != nil {
+			validator.Push(fmt.Errorf("error occurred validating the LDAP start_tls_config certificate: %v", err))
+		}
+	}
+
+	switch configuration.AuthMethod {
+	case schema.LDAPAuthMethodExternal:
+	case schema.LDAPAuthMethodGSSAPI:
+		validateLdapKerberos(configuration, validator)
+		return
+	case schema.LDAPAuthMethodSimple:
+		return
+	default:
+		validator.Push(fmt.Errorf("The LDAP `auth_method` must be one of `%s`, `%s`, `%s` but it is `%s`",
+			schema.LDAPAuthMethodSimple, schema.LDAPAuthMethodExternal, schema.LDAPAuthMethodGSSAPI, configuration.AuthMethod))
+
+		return
+	}
+
+	// The certificate presented by the connection is the one of the start_tls_config when StartTLS is used.
+	tlsConfig := configuration.TLS
+	if configuration.StartTLS && configuration.StartTLSConfig != nil {
+		tlsConfig = configuration.StartTLSConfig
+	}
+
+	switch {
+	case !configuration.StartTLS && !isLdapTLSURL(configuration.URL):
+		validator.Push(errors.New("The LDAP `auth_method` external requires either an ldaps URL or `start_tls` to present the client certificate"))
+	case tlsConfig.Certificate == "":
+		validator.Push(errors.New("The LDAP `auth_method` external requires a client certificate configured with the `certificate` and `key` of the TLS options"))
+	}
+
+	if configuration.User != "" || configuration.Password != "" {
+		validator.PushWarning(errors.New("The LDAP `user` and `password` are not used with the `auth_method` external, the identity of the client certificate is used instead"))
+	}
+}
+
+// validateLdapKerberos validates the Kerberos principal, keytab and configuration of the gssapi auth method. The
+// keytab and the configuration are read when binding, their absence is reported at startup rather than at the first
+// search.
+func validateLdapKerberos(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if configuration.KerberosConfig == "" {
+		configuration.KerberosConfig = schema.DefaultLDAPAuthenticationBackendConfiguration.KerberosConfig
+	}
+
+	if configuration.KerberosPrincipal == "" {
+		validator.Push(errors.New("The LDAP `auth_method` gssapi requires the `kerberos_principal` to bind with, for instance authelia@EXAMPLE.COM"))
+	} else if strings.HasPrefix(configuration.KerberosPrincipal, "@") || strings.HasSuffix(configuration.KerberosPrincipal, "@") {
+		validator.Push(fmt.Errorf("The LDAP `kerberos_principal` '%s' must be a name optionally followed by @ and the realm", configuration.KerberosPrincipal))
+	}
+
+	if configuration.KerberosKeytab == "" {
+		validator.Push(errors.New("The LDAP `auth_method` gssapi requires the `kerberos_keytab` holding the keys of the `kerberos_principal`"))
+	} else if _, err := os.Stat(configuration.KerberosKeytab); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `kerberos_keytab` '%s' is not readable: %v", configuration.KerberosKeytab, err))
+	}
+
+	if _, err := os.Stat(configuration.KerberosConfig); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `kerberos_config` '%s' is not readable: %v", configuration.KerberosConfig, err))
+	}
+
+	if configuration.User != "" || configuration.Password != "" {
+		validator.PushWarning(errors.New("The LDAP `user` and `password` are not used with the `auth_method` gssapi, the `kerberos_principal` is used instead"))
+	}
+}
+
 //nolint:gocyclo // TODO: Consider refactoring/simplifying, time permitting.
-func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationBackendConfiguration, disableResetPassword bool, validator *schema.StructValidator) {
 	if configuration.Implementation == "" {
 		configuration.Implementation = schema.DefaultLDAPAuthenticationBackendConfiguration.Implementation
 	}
 
+	// The default TLS options are copied since they are updated below.
 	nilTLS := configuration.TLS == nil
 	if nilTLS {
-		configuration.TLS = schema.DefaultLDAPAuthenticationBackendConfiguration.TLS
+		tlsConfig := *schema.DefaultLDAPAuthenticationBackendConfiguration.TLS
+		configuration.TLS = &tlsConfig
 	}
 
 	// Deprecated. Maps deprecated values to the new ones. TODO: Remove in 4.28 (if block).
@@ -132,8 +518,10 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		configuration.TLS.MinimumVersion = schema.DefaultLDAPAuthenticationBackendConfiguration.TLS.MinimumVersion
 	}
 
-	if _, err := utils.TLSStringToTLSConfigVersion(configuration.TLS.MinimumVersion); err != nil {
+	if version, err := utils.TLSStringToTLSConfigVersion(configuration.TLS.MinimumVersion); err != nil {
 		validator.Push(fmt.Errorf("error occurred validating the LDAP minimum_tls_version key with value %s: %v", configuration.TLS.MinimumVersion, err))
+	} else if version < tls.VersionTLS12 {
+		validator.PushWarning(fmt.Errorf("The LDAP `tls.minimum_version` %s is insecure and must only be used temporarily with an LDAP server which doesn't support TLS1.2 or newer", configuration.TLS.MinimumVersion))
 	}
 
 	switch configuration.Implementation {
@@ -157,28 +545,78 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		}
 	}
 
-	// TODO: see if it's possible to disable this check if disable_reset_password is set and when anonymous/user binding is supported (#101 and #387)
-	if configuration.User == "" {
-		validator.Push(errors.New("Please provide a user name to connect to the LDAP server"))
-	}
+	validateLdapStartTLSConfig(configuration, validator)
+	validateLdapAuthMethod(configuration, validator)
+	validateLdapUnixSocket(configuration, validator)
 
-	// TODO: see if it's possible to disable this check if disable_reset_password is set and when anonymous/user binding is supported (#101 and #387)
-	if configuration.Password == "" {
+	// An empty user results in an anonymous bind which is unable to update passwords unless a dedicated account is
+	// used to update them.
+	switch {
+	case configuration.AuthMethod == schema.LDAPAuthMethodExternal, configuration.AuthMethod == schema.LDAPAuthMethodGSSAPI:
+		// The identity of the client certificate or of the Kerberos principal is used instead of the user.
+	case configuration.User == "":
+		if !disableResetPassword && configuration.PasswordModifyUser == "" {
+			validator.Push(errors.New("Please provide a user name to connect to the LDAP server, an anonymous bind is only possible when `disable_reset_password` is enabled"))
+		}
+	case configuration.Password == "":
 		validator.Push(errors.New("Please provide a password to connect to the LDAP server"))
 	}
 
+	if configuration.GlobalCatalogURL != "" {
+		validateLdapGlobalCatalogURL(configuration, validator)
+	}
+
+	if configuration.ProxyURL != "" {
+		validateLdapProxyURL(configuration.ProxyURL, validator)
+	}
+
+	if configuration.PasswordModifyExtendedOperation && configuration.Implementation == schema.LDAPImplementationActiveDirectory {
+		validator.Push(errors.New("The LDAP `password_modify_extended_operation` is not supported by the activedirectory implementation"))
+	}
+
+	// The Password Modify extended operation can't carry the Assertion control.
+	if configuration.PasswordModifyAssertionFilter != "" {
+		validateLdapFilter("password_modify_assertion_filter", configuration.PasswordModifyAssertionFilter, validator)
+
+		if configuration.PasswordModifyExtendedOperation {
+			validator.Push(errors.New("The LDAP `password_modify_assertion_filter` is not supported with `password_modify_extended_operation`"))
+		}
+	}
+
+	if configuration.ResetTokenExpiryAttribute != "" && configuration.ResetTokenAttribute == "" {
+		validator.Push(errors.New("The LDAP `reset_token_expiry_attribute` requires the `reset_token_attribute` to be configured"))
+	}
+
+	validateLdapBindUser("user", configuration.User, configuration.Implementation, validator)
+	validateLdapBindUser("password_modify_user", configuration.PasswordModifyUser, configuration.Implementation, validator)
+
+	if configuration.PasswordModifyUser != "" && configuration.PasswordModifyPassword == "" {
+		validator.Push(errors.New("Please provide a password with `password_modify_password` for the user updating the passwords"))
+	}
+
+	validateLdapReferralHosts(configuration, validator)
+
 	if configuration.BaseDN == "" {
 		validator.Push(errors.New("Please provide a base DN to connect to the LDAP server"))
+	} else {
+		validateLdapDNs(configuration, validator)
 	}
 
+	validateLdapAttributePlaceholders(configuration, validator)
+
 	if configuration.UsersFilter == "" {
 		validator.Push(errors.New("Please provide a users filter with `users_filter` attribute"))
 	} else {
 		if !strings.HasPrefix(configuration.UsersFilter, "(") || !strings.HasSuffix(configuration.UsersFilter, ")") {
 			validator.Push(errors.New("The users filter should contain enclosing parenthesis. For instance {username_attribute}={input} should be ({username_attribute}={input})"))
+		} else {
+			validateLdapFilter("users_filter", configuration.ExcludeDisabledUsersFilter(configuration.UsersFilter), validator)
 		}
 
-		if !strings.Contains(configuration.UsersFilter, "{username_attribute}") {
+		validateLdapLoginAttribute(configuration, validator)
+		validateLdapUsersFilterPlaceholders("users_filter", configuration.UsersFilter, configuration, validator)
+
+		if !strings.Contains(configuration.UsersFilter, "{username_attribute}") && !strings.Contains(configuration.UsersFilter, "{login_attribute}") {
 			validator.Push(errors.New("Unable to detect {username_attribute} placeholder in users_filter, your configuration is broken. " +
 				"Please review configuration options listed at https://docs.authelia.com/configuration/authentication/ldap.html"))
 		}
@@ -190,15 +628,251 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		}
 	}
 
+	validateLdapGroupsSearchMode(configuration, validator)
+	validateLdapBindDNTemplate(configuration.BindDNTemplate, validator)
+
 	if configuration.GroupsFilter == "" {
-		validator.Push(errors.New("Please provide a groups filter with `groups_filter` attribute"))
+		// The groups filter is not used when the groups are read from the member of attribute of the users.
+		if configuration.GroupsSearchMode != schema.LDAPGroupsSearchModeMemberOf {
+			validator.Push(errors.New("Please provide a groups filter with `groups_filter` attribute"))
+		}
 	} else if !strings.HasPrefix(configuration.GroupsFilter, "(") || !strings.HasSuffix(configuration.GroupsFilter, ")") {
 		validator.Push(errors.New("The groups filter should contain enclosing parenthesis. For instance cn={input} should be (cn={input})"))
+	} else {
+		validateLdapFilter("groups_filter", configuration.GroupsFilter, validator)
 	}
 
+	for _, pattern := range configuration.GroupNamePatterns {
+		if strings.Trim(pattern, "*") == "" {
+			validator.Push(fmt.Errorf("The LDAP `group_name_patterns` must not contain empty patterns or patterns matching any group, you configured '%s'", pattern))
+		}
+	}
+
+	validateLdapGroupsList("groups_allowlist", configuration.GroupsAllowlist, validator)
+	validateLdapGroupsList("groups_denylist", configuration.GroupsDenylist, validator)
+
 	if configuration.UsernameAttribute == "" {
 		validator.Push(errors.New("Please provide a username attribute with `username_attribute`"))
 	}
+
+	configuration.UsersSearchScope = validateLdapSearchScope("users_search_scope", configuration.UsersSearchScope, validator)
+	configuration.GroupsSearchScope = validateLdapSearchScope("groups_search_scope", configuration.GroupsSearchScope, validator)
+
+	validateLdapMultipleUsersPolicy(configuration, validator)
+	validateLdapUsernameNormalization(configuration, validator)
+	validateLdapGroupNameNormalization(configuration, validator)
+	validateLdapDerefAliases(configuration, validator)
+	validateLdapOperationalAttributes(configuration, validator)
+
+	if configuration.PageSize == 0 {
+		configuration.PageSize = schema.DefaultLDAPAuthenticationBackendConfiguration.PageSize
+	} else if configuration.PageSize < 0 {
+		validator.Push(fmt.Errorf("The LDAP `page_size` specified is invalid, must be 1 or more, you configured %d", configuration.PageSize))
+	}
+
+	if configuration.MaxAttempts == 0 {
+		configuration.MaxAttempts = schema.DefaultLDAPAuthenticationBackendConfiguration.MaxAttempts
+	} else if configuration.MaxAttempts < 0 {
+		validator.Push(fmt.Errorf("The LDAP `max_attempts` specified is invalid, must be 1 or more, you configured %d", configuration.MaxAttempts))
+	}
+
+	if configuration.MaxGroups == 0 {
+		configuration.MaxGroups = schema.DefaultLDAPAuthenticationBackendConfiguration.MaxGroups
+	} else if configuration.MaxGroups < 0 {
+		validator.Push(fmt.Errorf("The LDAP `max_groups` specified is invalid, must be 1 or more, you configured %d", configuration.MaxGroups))
+	}
+
+	if configuration.MaxUsers == 0 {
+		configuration.MaxUsers = schema.DefaultLDAPAuthenticationBackendConfiguration.MaxUsers
+	} else if configuration.MaxUsers < 0 {
+		validator.Push(fmt.Errorf("The LDAP `max_users` specified is invalid, must be 1 or more, you configured %d", configuration.MaxUsers))
+	}
+
+	if configuration.MaxAttributeValueSize == 0 {
+		configuration.MaxAttributeValueSize = schema.DefaultLDAPAuthenticationBackendConfiguration.MaxAttributeValueSize
+	} else if configuration.MaxAttributeValueSize < 0 {
+		validator.Push(fmt.Errorf("The LDAP `max_attribute_value_size` specified is invalid, must be 1 or more, you configured %d", configuration.MaxAttributeValueSize))
+	}
+
+	if configuration.MaxSearchResultSize == 0 {
+		configuration.MaxSearchResultSize = schema.DefaultLDAPAuthenticationBackendConfiguration.MaxSearchResultSize
+	} else if configuration.MaxSearchResultSize < 0 {
+		validator.Push(fmt.Errorf("The LDAP `max_search_result_size` specified is invalid, must be 1 or more, you configured %d", configuration.MaxSearchResultSize))
+	}
+
+	if configuration.ListUsersFilter != "" {
+		validateLdapFilter("list_users_filter", configuration.ExcludeDisabledUsersFilter(configuration.ListUsersFilter), validator)
+		validateLdapUsersFilterPlaceholders("list_users_filter", configuration.ListUsersFilter, configuration, validator)
+
+		if strings.Contains(configuration.ListUsersFilter, "{input}") {
+			validator.Push(errors.New("The LDAP `list_users_filter` must not contain the {input} placeholder as there is no input when listing the users"))
+		}
+	}
+
+	if _, err := utils.ParseDurationString(configuration.GroupsCacheTTL); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `groups_cache_ttl` is configured to '%s' but it must be a duration notation. Error from parser: %s", configuration.GroupsCacheTTL, err))
+	}
+
+	if _, err := utils.ParseDurationString(configuration.UserBindTimeout); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `user_bind_timeout` is configured to '%s' but it must be a duration notation. Error from parser: %s", configuration.UserBindTimeout, err))
+	}
+
+	if configuration.CircuitBreakerThreshold == 0 {
+		configuration.CircuitBreakerThreshold = schema.DefaultLDAPAuthenticationBackendConfiguration.CircuitBreakerThreshold
+	} else if configuration.CircuitBreakerThreshold < 0 {
+		validator.Push(fmt.Errorf("The LDAP `circuit_breaker_threshold` specified is invalid, must be 1 or more, you configured %d", configuration.CircuitBreakerThreshold))
+	}
+
+	if configuration.CircuitBreakerCooldown == "" {
+		configuration.CircuitBreakerCooldown = schema.DefaultLDAPAuthenticationBackendConfiguration.CircuitBreakerCooldown
+	} else if _, err := utils.ParseDurationString(configuration.CircuitBreakerCooldown); err != nil {
+		validator.Push(fmt.Errorf("The LDAP `circuit_breaker_cooldown` is configured to '%s' but it must be a duration notation. Error from parser: %s", configuration.CircuitBreakerCooldown, err))
+	}
+
+	if configuration.PasswordPolicy.MinLength < 0 {
+		validator.Push(fmt.Errorf("The LDAP `password_policy.min_length` specified is invalid, must be 0 or more, you configured %d", configuration.PasswordPolicy.MinLength))
+	}
+
+	validateLdapEmergencyFallback(configuration, validator)
+
+	if configuration.WarmUp.Required && !configuration.WarmUp.Enabled {
+		validator.Push(errors.New("The LDAP `warm_up.required` option requires the warm up to be enabled with `warm_up.enabled`"))
+	}
+
+	// These characters are always escaped by the filters escaping.
+	if strings.ContainsAny(configuration.EscapedCharacters, "\\()*\x00") {
+		validator.Push(fmt.Errorf("The LDAP `escaped_characters` must not contain the characters which are always escaped `\\`, `(`, `)`, `*` and NUL, you configured '%s'", configuration.EscapedCharacters))
+	}
+}
+
+func validateLdapSearchScope(key, scope string, validator *schema.StructValidator) string {
+	switch scope {
+	case "":
+		return schema.LDAPSearchScopeSub
+	case schema.LDAPSearchScopeBase, schema.LDAPSearchScopeOne, schema.LDAPSearchScopeSub:
+		return scope
+	default:
+		validator.Push(fmt.Errorf("The LDAP `%s` must be one of the following values `%s`, `%s`, `%s`, you configured '%s'",
+			key, schema.LDAPSearchScopeBase, schema.LDAPSearchScopeOne, schema.LDAPSearchScopeSub, scope))
+
+		return scope
+	}
+}
+
+func validateLdapMultipleUsersPolicy(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	switch configuration.MultipleUsersPolicy {
+	case "":
+		configuration.MultipleUsersPolicy = schema.DefaultLDAPAuthenticationBackendConfiguration.MultipleUsersPolicy
+	case schema.LDAPMultipleUsersPolicyError, schema.LDAPMultipleUsersPolicyFirst:
+	case schema.LDAPMultipleUsersPolicyPreferredDN:
+		if configuration.PreferredUsersDN == "" {
+			validator.Push(fmt.Errorf("The LDAP `preferred_users_dn` must be configured when the `multiple_users_policy` is '%s'", schema.LDAPMultipleUsersPolicyPreferredDN))
+		}
+	default:
+		validator.Push(fmt.Errorf("The LDAP `multiple_users_policy` must be one of the following values `%s`, `%s`, `%s`, you configured '%s'",
+			schema.LDAPMultipleUsersPolicyError, schema.LDAPMultipleUsersPolicyFirst, schema.LDAPMultipleUsersPolicyPreferredDN, configuration.MultipleUsersPolicy))
+	}
+}
+
+func validateLdapUsernameNormalization(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	switch configuration.UsernameNormalization {
+	case "":
+		configuration.UsernameNormalization = schema.DefaultLDAPAuthenticationBackendConfiguration.UsernameNormalization
+	case schema.LDAPUsernameNormalizationNFC, schema.LDAPUsernameNormalizationNFD, schema.LDAPUsernameNormalizationNFKC,
+		schema.LDAPUsernameNormalizationNFKD, schema.LDAPUsernameNormalizationNone:
+	default:
+		validator.Push(fmt.Errorf("The LDAP `username_normalization` must be one of the following values `%s`, `%s`, `%s`, `%s`, `%s`, you configured '%s'",
+			schema.LDAPUsernameNormalizationNFC, schema.LDAPUsernameNormalizationNFD, schema.LDAPUsernameNormalizationNFKC,
+			schema.LDAPUsernameNormalizationNFKD, schema.LDAPUsernameNormalizationNone, configuration.UsernameNormalization))
+	}
+}
+
+// validateLdapGroupsList validates the regular expressions of the groups allowlist or denylist.
+func validateLdapGroupsList(name string, patterns []string, validator *schema.StructValidator) {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			validator.Push(fmt.Errorf("The LDAP `%s` pattern '%s' is not a valid regular expression: %s", name, pattern, err))
+		}
+	}
+}
+
+func validateLdapGroupsSearchMode(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	switch configuration.GroupsSearchMode {
+	case "":
+		configuration.GroupsSearchMode = schema.DefaultLDAPAuthenticationBackendConfiguration.GroupsSearchMode
+	case schema.LDAPGroupsSearchModeFilter, schema.LDAPGroupsSearchModeMemberOf:
+	default:
+		validator.Push(fmt.Errorf("The LDAP `groups_search_mode` must be one of the following values `%s`, `%s`, you configured '%s'",
+			schema.LDAPGroupsSearchModeFilter, schema.LDAPGroupsSearchModeMemberOf, configuration.GroupsSearchMode))
+	}
+
+	if configuration.MemberOfAttribute == "" {
+		configuration.MemberOfAttribute = schema.DefaultLDAPAuthenticationBackendConfiguration.MemberOfAttribute
+	}
+}
+
+func validateLdapGroupNameNormalization(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	switch configuration.GroupNameNormalization {
+	case "":
+		configuration.GroupNameNormalization = schema.DefaultLDAPAuthenticationBackendConfiguration.GroupNameNormalization
+	case schema.LDAPGroupNameNormalizationNone, schema.LDAPGroupNameNormalizationTrim, schema.LDAPGroupNameNormalizationLowercase:
+	default:
+		validator.Push(fmt.Errorf("The LDAP `group_name_normalization` must be one of the following values `%s`, `%s`, `%s`, you configured '%s'",
+			schema.LDAPGroupNameNormalizationNone, schema.LDAPGroupNameNormalizationTrim, schema.LDAPGroupNameNormalizationLowercase,
+			configuration.GroupNameNormalization))
+	}
+}
+
+func validateLdapDerefAliases(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	switch configuration.DerefAliases {
+	case "":
+		configuration.DerefAliases = schema.DefaultLDAPAuthenticationBackendConfiguration.DerefAliases
+	case schema.LDAPDerefAliasesNever, schema.LDAPDerefAliasesSearching, schema.LDAPDerefAliasesFinding, schema.LDAPDerefAliasesAlways:
+	default:
+		validator.Push(fmt.Errorf("The LDAP `deref_aliases` must be one of the following values `%s`, `%s`, `%s`, `%s`, you configured '%s'",
+			schema.LDAPDerefAliasesNever, schema.LDAPDerefAliasesSearching, schema.LDAPDerefAliasesFinding, schema.LDAPDerefAliasesAlways,
+			configuration.DerefAliases))
+	}
+}
+
+func validateLdapEmergencyFallback(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	if !configuration.EmergencyFallback.Enabled {
+		return
+	}
+
+	if configuration.EmergencyFallback.Path == "" {
+		validator.Push(errors.New("Please provide a `path` for the users database of the LDAP `emergency_fallback`"))
+	}
+
+	if len(configuration.EmergencyFallback.Usernames) == 0 {
+		validator.Push(errors.New("The LDAP `emergency_fallback` must name the emergency users allowed to fall back in `usernames`"))
+	}
+
+	for _, username := range configuration.EmergencyFallback.Usernames {
+		if strings.TrimSpace(username) == "" {
+			validator.Push(errors.New("The LDAP `emergency_fallback.usernames` must not contain an empty username"))
+		}
+	}
+}
+
+func validateLdapOperationalAttributes(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	for _, attribute := range configuration.OperationalAttributes {
+		if attribute == "" || attribute == "*" {
+			validator.Push(fmt.Errorf("The LDAP `operational_attributes` must only contain the names of operational attributes or '+', you configured '%s'", attribute))
+		}
+	}
+}
+
+func validateLdapReferralHosts(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	for _, host := range configuration.ReferralHosts {
+		if host == "" || strings.ContainsAny(host, "/:@") {
+			validator.Push(fmt.Errorf("The LDAP `referral_hosts` must only contain host names or domains, you configured '%s'", host))
+		}
+	}
+
+	if len(configuration.ReferralHosts) != 0 && !configuration.FollowReferrals {
+		validator.PushWarning(errors.New("The LDAP `referral_hosts` are not used unless `follow_referrals` is enabled"))
+	}
 }
 
 func setDefaultImplementationActiveDirectoryLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationBackendConfiguration) {
@@ -218,6 +892,10 @@ func setDefaultImplementationActiveDirectoryLdapAuthenticationBackend(configurat
 		configuration.MailAttribute = schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.MailAttribute
 	}
 
+	if configuration.StableIDAttribute == "" {
+		configuration.StableIDAttribute = schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.StableIDAttribute
+	}
+
 	if configuration.GroupsFilter == "" {
 		configuration.GroupsFilter = schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.GroupsFilter
 	}
@@ -243,6 +921,10 @@ func setDefaultImplementationCustomLdapAuthenticationBackend(configuration *sche
 	if configuration.DisplayNameAttribute == "" {
 		configuration.DisplayNameAttribute = schema.DefaultLDAPAuthenticationBackendConfiguration.DisplayNameAttribute
 	}
+
+	if configuration.DNAttribute == "" {
+		configuration.DNAttribute = schema.DefaultLDAPAuthenticationBackendConfiguration.DNAttribute
+	}
 }
 
 // ValidateAuthenticationBackend validates and update authentication backend configuration.
@@ -258,7 +940,7 @@ func ValidateAuthenticationBackend(configuration *schema.AuthenticationBackendCo
 	if configuration.File != nil {
 		validateFileAuthenticationBackend(configuration.File, validator)
 	} else if configuration.Ldap != nil {
-		validateLdapAuthenticationBackend(configuration.Ldap, validator)
+		validateLdapAuthenticationBackend(configuration.Ldap, configuration.DisableResetPassword, validator)
 	}
 
 	if configuration.RefreshInterval == "" {
@@ -70,6 +70,11 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 		ValidateRules(configuration.AccessControl, validator)
 	}
 
+	// The groups of the rules are compared with the groups returned by the LDAP server once normalized.
+	if configuration.AuthenticationBackend.Ldap != nil {
+		NormalizeRulesGroups(&configuration.AccessControl, configuration.AuthenticationBackend.Ldap.GroupNameNormalization)
+	}
+
 	ValidateSession(&configuration.Session, validator)
 
 	if configuration.Regulation == nil {
@@ -189,3 +189,30 @@ func TestShouldNotRaiseErrorOnValidCertificatesDirectory(t *testing.T) {
 
 	require.Len(t, validator.Errors(), 0)
 }
+
+func TestShouldNormalizeRulesGroupsLikeLDAPGroups(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultConfig()
+	config.AuthenticationBackend.File = nil
+	config.AuthenticationBackend.Ldap = &schema.LDAPAuthenticationBackendConfiguration{
+		URL:                    "ldap://ldap",
+		User:                   "cn=admin,dc=example,dc=com",
+		Password:               "password",
+		BaseDN:                 "dc=example,dc=com",
+		UsersFilter:            "({username_attribute}={input})",
+		GroupsFilter:           "(member={dn})",
+		GroupNameNormalization: schema.LDAPGroupNameNormalizationLowercase,
+	}
+	config.AccessControl.Rules = []schema.ACLRule{
+		{
+			Domains:  []string{"public.example.com"},
+			Policy:   "one_factor",
+			Subjects: [][]string{{"group:Admins ", "user:John"}, {"group:dev"}},
+		},
+	}
+
+	ValidateConfiguration(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, [][]string{{"group:admins", "user:John"}, {"group:dev"}}, config.AccessControl.Rules[0].Subjects)
+}
@@ -17,6 +17,7 @@ var validKeys = []string{
 	"server.read_buffer_size",
 	"server.write_buffer_size",
 	"server.path",
+	"server.enable_metrics",
 
 	// TOTP Keys.
 	"totp.issuer",
@@ -98,21 +99,111 @@ var validKeys = []string{
 	// LDAP Authentication Backend Keys.
 	"authentication_backend.ldap.implementation",
 	"authentication_backend.ldap.url",
+	"authentication_backend.ldap.global_catalog_url",
+	"authentication_backend.ldap.proxy_url",
 	"authentication_backend.ldap.base_dn",
 	"authentication_backend.ldap.username_attribute",
+	"authentication_backend.ldap.username_attribute_fallbacks",
+	"authentication_backend.ldap.login_attribute",
+	"authentication_backend.ldap.attribute_placeholders",
+	"authentication_backend.ldap.case_insensitive_usernames",
 	"authentication_backend.ldap.additional_users_dn",
+	"authentication_backend.ldap.extra_users_dns",
 	"authentication_backend.ldap.users_filter",
+	"authentication_backend.ldap.disable_users_filter_constraint",
+	"authentication_backend.ldap.exclude_disabled_users",
+	"authentication_backend.ldap.users_search_scope",
+	"authentication_backend.ldap.list_users_filter",
+	"authentication_backend.ldap.max_users",
+	"authentication_backend.ldap.multiple_users_policy",
+	"authentication_backend.ldap.username_normalization",
+	"authentication_backend.ldap.preferred_users_dn",
 	"authentication_backend.ldap.additional_groups_dn",
+	"authentication_backend.ldap.extra_groups_dns",
 	"authentication_backend.ldap.groups_filter",
+	"authentication_backend.ldap.groups_search_mode",
+	"authentication_backend.ldap.member_of_attribute",
+	"authentication_backend.ldap.groups_search_scope",
+	"authentication_backend.ldap.deref_aliases",
 	"authentication_backend.ldap.group_name_attribute",
+	"authentication_backend.ldap.group_name_attribute_fallbacks",
+	"authentication_backend.ldap.group_display_name_attribute",
+	"authentication_backend.ldap.group_name_patterns",
+	"authentication_backend.ldap.groups_allowlist",
+	"authentication_backend.ldap.groups_denylist",
+	"authentication_backend.ldap.group_name_normalization",
+	"authentication_backend.ldap.page_size",
+	"authentication_backend.ldap.concurrent_searches",
+	"authentication_backend.ldap.max_attempts",
+	"authentication_backend.ldap.max_groups",
+	"authentication_backend.ldap.max_attribute_value_size",
+	"authentication_backend.ldap.max_search_result_size",
+	"authentication_backend.ldap.groups_cache_ttl",
+	"authentication_backend.ldap.user_bind_timeout",
+	"authentication_backend.ldap.circuit_breaker_threshold",
+	"authentication_backend.ldap.circuit_breaker_cooldown",
 	"authentication_backend.ldap.mail_attribute",
+	"authentication_backend.ldap.mail_attribute_fallbacks",
+	"authentication_backend.ldap.primary_mail_attribute",
 	"authentication_backend.ldap.display_name_attribute",
+	"authentication_backend.ldap.display_name_attribute_fallbacks",
+	"authentication_backend.ldap.photo_attribute",
+	"authentication_backend.ldap.stable_id_attribute",
+	"authentication_backend.ldap.dn_attribute",
+	"authentication_backend.ldap.additional_attributes",
+	"authentication_backend.ldap.operational_attributes",
+	"authentication_backend.ldap.escaped_characters",
+	"authentication_backend.ldap.auth_method",
+	"authentication_backend.ldap.kerberos_principal",
+	"authentication_backend.ldap.kerberos_keytab",
+	"authentication_backend.ldap.kerberos_config",
+	"authentication_backend.ldap.kerberos_service_principal",
+	"authentication_backend.ldap.bind_dn_template",
 	"authentication_backend.ldap.user",
 	"authentication_backend.ldap.password",
+	"authentication_backend.ldap.password_modify_user",
+	"authentication_backend.ldap.password_modify_password",
+	"authentication_backend.ldap.password_modify_extended_operation",
+	"authentication_backend.ldap.password_change_as_user",
+	"authentication_backend.ldap.password_modify_assertion_filter",
+	"authentication_backend.ldap.password_modify_pre_read_attributes",
+	"authentication_backend.ldap.reset_token_attribute",
+	"authentication_backend.ldap.reset_token_expiry_attribute",
 	"authentication_backend.ldap.start_tls",
+	"authentication_backend.ldap.follow_referrals",
+	"authentication_backend.ldap.referral_hosts",
+	"authentication_backend.ldap.ppolicy_control",
+	"authentication_backend.ldap.whoami",
+	"authentication_backend.ldap.password_policy.min_length",
+	"authentication_backend.ldap.password_policy.require_uppercase",
+	"authentication_backend.ldap.password_policy.require_lowercase",
+	"authentication_backend.ldap.password_policy.require_number",
+	"authentication_backend.ldap.password_policy.require_special",
+	"authentication_backend.ldap.password_policy.forbid_username",
+	"authentication_backend.ldap.password_policy.forbidden_substrings",
+	"authentication_backend.ldap.emergency_fallback.enabled",
+	"authentication_backend.ldap.emergency_fallback.path",
+	"authentication_backend.ldap.emergency_fallback.usernames",
+	"authentication_backend.ldap.warm_up.enabled",
+	"authentication_backend.ldap.warm_up.required",
 	"authentication_backend.ldap.tls.minimum_version",
 	"authentication_backend.ldap.tls.skip_verify",
 	"authentication_backend.ldap.tls.server_name",
+	"authentication_backend.ldap.tls.certificate",
+	"authentication_backend.ldap.tls.key",
+	"authentication_backend.ldap.tls.renegotiation",
+	"authentication_backend.ldap.tls.require_ocsp_stapling",
+	"authentication_backend.ldap.tls.pinned_certificates",
+	"authentication_backend.ldap.tls.pinned_public_keys",
+	"authentication_backend.ldap.start_tls_config.minimum_version",
+	"authentication_backend.ldap.start_tls_config.skip_verify",
+	"authentication_backend.ldap.start_tls_config.server_name",
+	"authentication_backend.ldap.start_tls_config.certificate",
+	"authentication_backend.ldap.start_tls_config.key",
+	"authentication_backend.ldap.start_tls_config.renegotiation",
+	"authentication_backend.ldap.start_tls_config.require_ocsp_stapling",
+	"authentication_backend.ldap.start_tls_config.pinned_certificates",
+	"authentication_backend.ldap.start_tls_config.pinned_public_keys",
 	"authentication_backend.ldap.skip_verify",         // TODO: Deprecated: Remove in 4.28.
 	"authentication_backend.ldap.minimum_tls_version", // TODO: Deprecated: Remove in 4.28.
 
@@ -138,6 +229,7 @@ var validKeys = []string{
 	"authelia.duo_api.secret_key.file",
 	"authelia.session.secret.file",
 	"authelia.authentication_backend.ldap.password.file",
+	"authelia.authentication_backend.ldap.password_modify_password.file",
 	"authelia.notifier.smtp.password.file",
 	"authelia.session.redis.password.file",
 	"authelia.storage.mysql.password.file",
@@ -175,13 +267,18 @@ const sha512 = "sha512"
 
 const schemeLDAP = "ldap"
 const schemeLDAPS = "ldaps"
+const schemeLDAPI = "ldapi"
+const schemeSRV = "srv"
+const schemeSRVS = "srvs"
+const schemeSOCKS5 = "socks5"
+const schemeSOCKS5H = "socks5h"
 
 const testBadTimer = "-1"
 const testJWTSecret = "a_secret"
-const testLDAPBaseDN = "base_dn"
+const testLDAPBaseDN = "dc=example,dc=com"
 const testLDAPPassword = "password"
 const testLDAPURL = "ldap://ldap"
-const testLDAPUser = "user"
+const testLDAPUser = "cn=admin,dc=example,dc=com"
 const testModeDisabled = "disable"
 const testTLSCert = "/tmp/cert.pem"
 const testTLSKey = "/tmp/key.pem"
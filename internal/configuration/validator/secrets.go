@@ -25,6 +25,7 @@ func ValidateSecrets(configuration *schema.Configuration, validator *schema.Stru
 
 	if configuration.AuthenticationBackend.Ldap != nil {
 		configuration.AuthenticationBackend.Ldap.Password = getSecretValue("authentication_backend.ldap.password", validator, viper)
+		configuration.AuthenticationBackend.Ldap.PasswordModifyPassword = getSecretValue("authentication_backend.ldap.password_modify_password", validator, viper)
 	}
 
 	if configuration.Notifier != nil && configuration.Notifier.SMTP != nil {
@@ -52,10 +53,14 @@ func getSecretValue(name string, validator *schema.StructValidator, viper *viper
 	// Derive Secret.
 	if fileEnvValue != "" {
 		content, err := ioutil.ReadFile(fileEnvValue)
-		if err != nil {
+
+		switch secret := strings.ReplaceAll(string(content), "\n", ""); {
+		case err != nil:
 			validator.Push(fmt.Errorf("error loading secret file (%s): %s", name, err))
-		} else {
-			return strings.ReplaceAll(string(content), "\n", "")
+		case secret == "":
+			validator.Push(fmt.Errorf("error loading secret file (%s): the file %s is empty", name, fileEnvValue))
+		default:
+			return secret
 		}
 	}
 
@@ -40,6 +40,7 @@ const unableToRegisterOneTimePasswordMessage = "Unable to set up one-time passwo
 const unableToRegisterSecurityKeyMessage = "Unable to register your security key."
 const unableToResetPasswordMessage = "Unable to reset your password."
 const mfaValidationFailedMessage = "Authentication failed, please retry later."
+const authenticationBackendUnavailableMessage = "Authentication backend is unavailable."
 
 const ldapPasswordComplexityCode = "0000052D."
 
@@ -91,9 +91,18 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			return
 		}
 
-		userPasswordOk, err := ctx.Providers.UserProvider.CheckUserPassword(bodyJSON.Username, bodyJSON.Password)
+		// The details are retrieved along with the password check which saves the authentication backend a bind.
+		userPasswordOk, userDetails, err := checkUserPasswordAndGetDetails(ctx, bodyJSON.Username, bodyJSON.Password)
 
-		if err != nil {
+		// The attempts failing because the authentication backend is unavailable are not marked, an outage would
+		// otherwise ban every user.
+		if err != nil && authentication.IsBackendUnavailableError(err) {
+			handleAuthenticationBackendUnavailable(ctx, fmt.Errorf("Unable to check password for user %s as the authentication backend is unavailable: %s", bodyJSON.Username, err.Error()))
+
+			return
+		}
+
+		if err != nil && !userPasswordOk {
 			ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 
 			if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, false); err != nil {
@@ -118,13 +127,18 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 		}
 
 		ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
-		err = ctx.Providers.Regulator.Mark(bodyJSON.Username, true)
 
-		if err != nil {
+		if err := ctx.Providers.Regulator.Mark(bodyJSON.Username, true); err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err.Error()), authenticationFailedMessage)
 			return
 		}
 
+		// The password matched but the details of the user could not be retrieved.
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error while retrieving details from user %s: %s", bodyJSON.Username, err.Error()), authenticationFailedMessage)
+			return
+		}
+
 		ctx.Logger.Debugf("Credentials validation of user %s is ok", bodyJSON.Username)
 
 		// Reset all values from previous session before regenerating the cookie.
@@ -154,14 +168,6 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 			}
 		}
 
-		// Get the details of the given user from the user provider.
-		userDetails, err := ctx.Providers.UserProvider.GetDetails(bodyJSON.Username)
-
-		if err != nil {
-			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error while retrieving details from user %s: %s", bodyJSON.Username, err.Error()), authenticationFailedMessage)
-			return
-		}
-
 		ctx.Logger.Tracef("Details for user %s => groups: %s, emails %s", bodyJSON.Username, userDetails.Groups, userDetails.Emails)
 
 		// And set those information in the new session.
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-ldap/ldap/v3"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -17,6 +20,34 @@ import (
 	"github.com/authelia/authelia/internal/models"
 )
 
+// contextUserProvider is a user provider accepting a context which records the operation ID of the context it is
+// called with.
+type contextUserProvider struct {
+	*mocks.MockUserProvider
+
+	operationID string
+}
+
+func (p *contextUserProvider) CheckUserPasswordWithContext(ctx context.Context, username string, password string) (bool, error) {
+	p.operationID, _ = authentication.OperationIDFromContext(ctx)
+	return p.CheckUserPassword(username, password)
+}
+
+func (p *contextUserProvider) CheckUserPasswordAndGetDetailsWithContext(ctx context.Context, username string, password string) (bool, *authentication.UserDetails, error) {
+	p.operationID, _ = authentication.OperationIDFromContext(ctx)
+	return p.CheckUserPasswordAndGetDetails(username, password)
+}
+
+func (p *contextUserProvider) GetDetailsWithContext(ctx context.Context, username string) (*authentication.UserDetails, error) {
+	p.operationID, _ = authentication.OperationIDFromContext(ctx)
+	return p.GetDetails(username)
+}
+
+func (p *contextUserProvider) UpdatePasswordWithContext(ctx context.Context, username string, newPassword string) error {
+	p.operationID, _ = authentication.OperationIDFromContext(ctx)
+	return p.UpdatePassword(username, newPassword)
+}
+
 type FirstFactorSuite struct {
 	suite.Suite
 
@@ -53,8 +84,8 @@ func (s *FirstFactorSuite) TestShouldFailIfBodyIsInBadFormat() {
 func (s *FirstFactorSuite) TestShouldFailIfUserProviderCheckPasswordFail() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(false, fmt.Errorf("Failed"))
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil, fmt.Errorf("Failed"))
 
 	s.mock.StorageProviderMock.
 		EXPECT().
@@ -78,8 +109,8 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderCheckPasswordFail() {
 func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCredentials() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(false, fmt.Errorf("Invalid credentials"))
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil, fmt.Errorf("Invalid credentials"))
 
 	s.mock.StorageProviderMock.
 		EXPECT().
@@ -98,21 +129,126 @@ func (s *FirstFactorSuite) TestShouldCheckAuthenticationIsMarkedWhenInvalidCrede
 	FirstFactorPost(0, false)(s.mock.Ctx)
 }
 
-func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
+func (s *FirstFactorSuite) TestShouldNotMarkAuthenticationWhenBackendIsUnavailable() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil, fmt.Errorf("%w ldap://127.0.0.1:389. Cause: connection refused", authentication.ErrConnectionFailed))
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": true
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), "Unable to check password for user test as the authentication backend is unavailable: "+
+		"unable to connect to the authentication backend ldap://127.0.0.1:389. Cause: connection refused", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), 503, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "{\"status\":\"KO\",\"message\":\"Authentication backend is unavailable.\"}", string(s.mock.Ctx.Response.Body()))
+}
+
+func (s *FirstFactorSuite) TestShouldNotMarkAuthenticationWhenBackendIsBusyOrTimesOut() {
+	testCases := []error{
+		fmt.Errorf("Unable to search the user test. Cause: %w", context.DeadlineExceeded),
+		ldap.NewError(ldap.LDAPResultBusy, errors.New("server is busy")),
+		ldap.NewError(ldap.LDAPResultUnavailable, errors.New("server is unavailable")),
+	}
 
+	for _, err := range testCases {
+		s.Run(err.Error(), func() {
+			s.SetupTest()
+			defer s.TearDownTest()
+
+			// The storage mock fails the test on any attempt appended to the authentication logs by the regulator.
+			s.mock.UserProviderMock.
+				EXPECT().
+				CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+				Return(false, nil, err)
+
+			s.mock.Ctx.Request.SetBodyString(`{
+				"username": "test",
+				"password": "hello",
+				"keepMeLoggedIn": true
+			}`)
+
+			FirstFactorPost(0, false)(s.mock.Ctx)
+
+			assert.Equal(s.T(), 503, s.mock.Ctx.Response.StatusCode())
+			assert.Equal(s.T(), "{\"status\":\"KO\",\"message\":\"Authentication backend is unavailable.\"}", string(s.mock.Ctx.Response.Body()))
+		})
+	}
+}
+
+func (s *FirstFactorSuite) TestShouldMarkAuthenticationWhenUserBindTimesOut() {
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil, fmt.Errorf("%w ldap://127.0.0.1:389. Cause: context deadline exceeded", authentication.ErrBindTimeout))
+
+	// The LDAP server is reachable so the bind timing out is regulated like a rejected bind.
 	s.mock.StorageProviderMock.
 		EXPECT().
-		AppendAuthenticationLog(gomock.Any()).
-		Return(nil)
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   "test",
+			Successful: false,
+			Time:       s.mock.Clock.Now(),
+		}))
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": true
+	}`)
 
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	assert.Equal(s.T(), "Error while checking password for user test: the bind timed out against the authentication "+
+		"backend ldap://127.0.0.1:389. Cause: context deadline exceeded", s.mock.Hook.LastEntry().Message)
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+}
+
+func (s *FirstFactorSuite) TestShouldPassRequestIDToUserProvider() {
+	provider := &contextUserProvider{MockUserProvider: s.mock.UserProviderMock}
+	s.mock.Ctx.Providers.UserProvider = provider
+
+	s.mock.UserProviderMock.
+		EXPECT().
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(false, nil, fmt.Errorf("Invalid credentials"))
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   "test",
+			Successful: false,
+			Time:       s.mock.Clock.Now(),
+		}))
+
+	s.mock.Ctx.Request.SetBodyString(`{
+		"username": "test",
+		"password": "hello",
+		"keepMeLoggedIn": true
+	}`)
+
+	FirstFactorPost(0, false)(s.mock.Ctx)
+
+	// The logs of the provider are correlated with the logs of the request.
+	assert.Equal(s.T(), s.mock.Ctx.RequestID(), provider.operationID)
+	s.mock.Assert401KO(s.T(), "Authentication failed. Check your credentials.")
+}
+
+func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		GetDetails(gomock.Eq("test")).
-		Return(nil, fmt.Errorf("Failed"))
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, nil, fmt.Errorf("Failed"))
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
 
 	s.mock.Ctx.Request.SetBodyString(`{
 		"username": "test",
@@ -128,8 +264,8 @@ func (s *FirstFactorSuite) TestShouldFailIfUserProviderGetDetailsFail() {
 func (s *FirstFactorSuite) TestShouldFailIfAuthenticationMarkFail() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, &authentication.UserDetails{Username: "test"}, nil)
 
 	s.mock.StorageProviderMock.
 		EXPECT().
@@ -150,13 +286,8 @@ func (s *FirstFactorSuite) TestShouldFailIfAuthenticationMarkFail() {
 func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeChecked() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
-
-	s.mock.UserProviderMock.
-		EXPECT().
-		GetDetails(gomock.Eq("test")).
-		Return(&authentication.UserDetails{
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, &authentication.UserDetails{
 			Username: "test",
 			Emails:   []string{"test@example.com"},
 			Groups:   []string{"dev", "admins"},
@@ -190,13 +321,8 @@ func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeChecked() {
 func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeUnchecked() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
-
-	s.mock.UserProviderMock.
-		EXPECT().
-		GetDetails(gomock.Eq("test")).
-		Return(&authentication.UserDetails{
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, &authentication.UserDetails{
 			Username: "test",
 			Emails:   []string{"test@example.com"},
 			Groups:   []string{"dev", "admins"},
@@ -230,13 +356,8 @@ func (s *FirstFactorSuite) TestShouldAuthenticateUserWithRememberMeUnchecked() {
 func (s *FirstFactorSuite) TestShouldSaveUsernameFromAuthenticationBackendInSession() {
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
-
-	s.mock.UserProviderMock.
-		EXPECT().
-		GetDetails(gomock.Eq("test")).
-		Return(&authentication.UserDetails{
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, &authentication.UserDetails{
 			// This is the name in authentication backend, in some setups the binding is
 			// case insensitive but the user ID in session must match the user in LDAP
 			// for the other modules of Authelia to be coherent.
@@ -291,13 +412,8 @@ func (s *FirstFactorRedirectionSuite) SetupTest() {
 
 	s.mock.UserProviderMock.
 		EXPECT().
-		CheckUserPassword(gomock.Eq("test"), gomock.Eq("hello")).
-		Return(true, nil)
-
-	s.mock.UserProviderMock.
-		EXPECT().
-		GetDetails(gomock.Eq("test")).
-		Return(&authentication.UserDetails{
+		CheckUserPasswordAndGetDetails(gomock.Eq("test"), gomock.Eq("hello")).
+		Return(true, &authentication.UserDetails{
 			Username: "test",
 			Emails:   []string{"test@example.com"},
 			Groups:   []string{"dev", "admins"},
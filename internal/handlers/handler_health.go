@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"fmt"
+
 	"github.com/authelia/authelia/internal/middlewares"
 )
 
-// HealthGet can be used by health checks.
+// HealthGet can be used by health checks, it reports the service unavailable when the authentication backend is.
 func HealthGet(ctx *middlewares.AutheliaCtx) {
+	if err := ctx.Providers.UserProvider.Healthcheck(); err != nil {
+		handleAuthenticationBackendUnavailable(ctx, fmt.Errorf("Authentication backend healthcheck failed: %s", err))
+
+		return
+	}
+
 	ctx.ReplyOK()
 }
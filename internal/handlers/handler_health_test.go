@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type HealthSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HealthSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+}
+
+func (s *HealthSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HealthSuite) TestShouldReplyOKWhenAuthenticationBackendIsHealthy() {
+	s.mock.UserProviderMock.EXPECT().
+		Healthcheck().
+		Return(nil)
+
+	HealthGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HealthSuite) TestShouldReplyServiceUnavailableWhenAuthenticationBackendIsUnhealthy() {
+	s.mock.UserProviderMock.EXPECT().
+		Healthcheck().
+		Return(errors.New("unable to connect to the authentication backend ldap://127.0.0.1:389"))
+
+	HealthGet(s.mock.Ctx)
+
+	assert.Equal(s.T(), 503, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "{\"status\":\"KO\",\"message\":\"Authentication backend is unavailable.\"}", string(s.mock.Ctx.Response.Body()))
+	assert.Equal(s.T(), "Authentication backend healthcheck failed: unable to connect to the authentication backend ldap://127.0.0.1:389", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHealthSuite(t *testing.T) {
+	s := new(HealthSuite)
+	suite.Run(t, s)
+}
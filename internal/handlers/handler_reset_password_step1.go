@@ -16,7 +16,7 @@ func identityRetrieverFromStorage(ctx *middlewares.AutheliaCtx) (*session.Identi
 		return nil, err
 	}
 
-	details, err := ctx.Providers.UserProvider.GetDetails(requestBody.Username)
+	details, err := getUserDetails(ctx, requestBody.Username)
 
 	if err != nil {
 		return nil, err
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/utils"
 )
@@ -27,10 +29,12 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	err = ctx.Providers.UserProvider.UpdatePassword(*userSession.PasswordResetUsername, requestBody.Password)
+	err = updateUserPassword(ctx, *userSession.PasswordResetUsername, requestBody.Password)
 
 	if err != nil {
 		switch {
+		case errors.Is(err, authentication.ErrPasswordPolicyViolation):
+			ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
 		case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityCodes):
 			ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
 		case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityErrors):
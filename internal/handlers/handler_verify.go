@@ -132,7 +132,7 @@ func verifyBasicAuth(auth []byte, targetURL url.URL, ctx *middlewares.AutheliaCt
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to parse content of %s header: %s", AuthorizationHeader, err)
 	}
 
-	authenticated, err := ctx.Providers.UserProvider.CheckUserPassword(username, password)
+	authenticated, err := checkUserPassword(ctx, username, password)
 
 	if err != nil {
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to check credentials extracted from %s header: %s", AuthorizationHeader, err)
@@ -144,7 +144,7 @@ func verifyBasicAuth(auth []byte, targetURL url.URL, ctx *middlewares.AutheliaCt
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("User %s is not authenticated", username)
 	}
 
-	details, err := ctx.Providers.UserProvider.GetDetails(username)
+	details, err := getUserDetails(ctx, username)
 
 	if err != nil {
 		return "", "", nil, nil, authentication.NotAuthenticated, fmt.Errorf("Unable to retrieve details of user %s: %s", username, err)
@@ -327,7 +327,7 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 		ctx.Providers.Authorizer.IsURLMatchingRuleWithGroupSubjects(*targetURL) &&
 		(refreshProfileInterval == schema.RefreshIntervalAlways || userSession.RefreshTTL.Before(ctx.Clock.Now())) {
 		ctx.Logger.Debugf("Checking the authentication backend for an updated profile for user %s", userSession.Username)
-		details, err := ctx.Providers.UserProvider.GetDetails(userSession.Username)
+		details, err := getUserDetails(ctx, userSession.Username)
 		// Only update the session if we could get the new details.
 		if err != nil {
 			return err
@@ -109,3 +109,10 @@ func handleAuthenticationUnauthorized(ctx *middlewares.AutheliaCtx, err error, m
 	ctx.SetStatusCode(fasthttp.StatusUnauthorized)
 	ctx.Error(err, message)
 }
+
+// handleAuthenticationBackendUnavailable replies the service is unavailable when the authentication backend is, so
+// the users are not told their credentials are wrong.
+func handleAuthenticationBackendUnavailable(ctx *middlewares.AutheliaCtx, err error) {
+	ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	ctx.Error(err, authenticationBackendUnavailableMessage)
+}
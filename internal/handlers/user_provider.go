@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// userProviderContext returns the context of the calls to the user provider, it carries the ID of the request so the
+// logs of the provider are correlated with the logs of the request.
+func userProviderContext(ctx *middlewares.AutheliaCtx) context.Context {
+	return authentication.ContextWithOperationID(context.Background(), ctx.RequestID())
+}
+
+// checkUserPassword checks the password of the user, with the ID of the request when the provider accepts it.
+func checkUserPassword(ctx *middlewares.AutheliaCtx, username, password string) (bool, error) {
+	if provider, ok := ctx.Providers.UserProvider.(authentication.ContextUserProvider); ok {
+		return provider.CheckUserPasswordWithContext(userProviderContext(ctx), username, password)
+	}
+
+	return ctx.Providers.UserProvider.CheckUserPassword(username, password)
+}
+
+// checkUserPasswordAndGetDetails checks the password of the user and retrieves their details, with the ID of the
+// request when the provider accepts it.
+func checkUserPasswordAndGetDetails(ctx *middlewares.AutheliaCtx, username, password string) (bool, *authentication.UserDetails, error) {
+	if provider, ok := ctx.Providers.UserProvider.(authentication.ContextUserProvider); ok {
+		return provider.CheckUserPasswordAndGetDetailsWithContext(userProviderContext(ctx), username, password)
+	}
+
+	return ctx.Providers.UserProvider.CheckUserPasswordAndGetDetails(username, password)
+}
+
+// getUserDetails retrieves the details of the user, with the ID of the request when the provider accepts it.
+func getUserDetails(ctx *middlewares.AutheliaCtx, username string) (*authentication.UserDetails, error) {
+	if provider, ok := ctx.Providers.UserProvider.(authentication.ContextUserProvider); ok {
+		return provider.GetDetailsWithContext(userProviderContext(ctx), username)
+	}
+
+	return ctx.Providers.UserProvider.GetDetails(username)
+}
+
+// updateUserPassword updates the password of the user, with the ID of the request when the provider accepts it.
+func updateUserPassword(ctx *middlewares.AutheliaCtx, username, newPassword string) error {
+	if provider, ok := ctx.Providers.UserProvider.(authentication.ContextUserProvider); ok {
+		return provider.UpdatePasswordWithContext(userProviderContext(ctx), username, newPassword)
+	}
+
+	return ctx.Providers.UserProvider.UpdatePassword(username, newPassword)
+}
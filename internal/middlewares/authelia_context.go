@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -18,9 +19,10 @@ import (
 // NewRequestLogger create a new request logger for the given request.
 func NewRequestLogger(ctx *AutheliaCtx) *logrus.Entry {
 	return logrus.WithFields(logrus.Fields{
-		"method":    string(ctx.Method()),
-		"path":      string(ctx.Path()),
-		"remote_ip": ctx.RemoteIP().String(),
+		"method":     string(ctx.Method()),
+		"path":       string(ctx.Path()),
+		"remote_ip":  ctx.RemoteIP().String(),
+		"request_id": ctx.RequestID(),
 	})
 }
 
@@ -163,6 +165,12 @@ func (c *AutheliaCtx) SetJSONBody(value interface{}) error {
 	return nil
 }
 
+// RequestID returns the ID of the request logged with every message of the request. It is passed to the providers
+// accepting it so their logs are correlated with the request.
+func (c *AutheliaCtx) RequestID() string {
+	return strconv.FormatUint(c.ID(), 10)
+}
+
 // RemoteIP return the remote IP taking X-Forwarded-For header into account if provided.
 func (c *AutheliaCtx) RemoteIP() net.IP {
 	XForwardedFor := c.Request.Header.Peek("X-Forwarded-For")
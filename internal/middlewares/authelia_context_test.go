@@ -1,6 +1,7 @@
 package middlewares_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -33,3 +34,13 @@ func TestShouldCallNextWithAutheliaCtx(t *testing.T) {
 
 	assert.True(t, nextCalled)
 }
+
+func TestShouldLogRequestID(t *testing.T) {
+	ctx := &fasthttp.RequestCtx{}
+
+	autheliaCtx, err := middlewares.NewAutheliaCtx(ctx, schema.Configuration{}, middlewares.Providers{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, strconv.FormatUint(ctx.ID(), 10), autheliaCtx.RequestID())
+	assert.Equal(t, autheliaCtx.RequestID(), autheliaCtx.Logger.Data["request_id"])
+}
@@ -50,6 +50,22 @@ func (mr *MockUserProviderMockRecorder) CheckUserPassword(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUserPassword", reflect.TypeOf((*MockUserProvider)(nil).CheckUserPassword), arg0, arg1)
 }
 
+// CheckUserPasswordAndGetDetails mocks base method.
+func (m *MockUserProvider) CheckUserPasswordAndGetDetails(arg0, arg1 string) (bool, *authentication.UserDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckUserPasswordAndGetDetails", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(*authentication.UserDetails)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CheckUserPasswordAndGetDetails indicates an expected call of CheckUserPasswordAndGetDetails.
+func (mr *MockUserProviderMockRecorder) CheckUserPasswordAndGetDetails(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUserPasswordAndGetDetails", reflect.TypeOf((*MockUserProvider)(nil).CheckUserPasswordAndGetDetails), arg0, arg1)
+}
+
 // GetDetails mocks base method.
 func (m *MockUserProvider) GetDetails(arg0 string) (*authentication.UserDetails, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +81,64 @@ func (mr *MockUserProviderMockRecorder) GetDetails(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDetails", reflect.TypeOf((*MockUserProvider)(nil).GetDetails), arg0)
 }
 
+// GetDetailsByEmail mocks base method.
+func (m *MockUserProvider) GetDetailsByEmail(arg0 string) (*authentication.UserDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDetailsByEmail", arg0)
+	ret0, _ := ret[0].(*authentication.UserDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDetailsByEmail indicates an expected call of GetDetailsByEmail.
+func (mr *MockUserProviderMockRecorder) GetDetailsByEmail(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDetailsByEmail", reflect.TypeOf((*MockUserProvider)(nil).GetDetailsByEmail), arg0)
+}
+
+// Healthcheck mocks base method.
+func (m *MockUserProvider) Healthcheck() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Healthcheck")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Healthcheck indicates an expected call of Healthcheck.
+func (mr *MockUserProviderMockRecorder) Healthcheck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthcheck", reflect.TypeOf((*MockUserProvider)(nil).Healthcheck))
+}
+
+// ListUsers mocks base method.
+func (m *MockUserProvider) ListUsers() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUserProviderMockRecorder) ListUsers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserProvider)(nil).ListUsers))
+}
+
+// StartupCheck mocks base method.
+func (m *MockUserProvider) StartupCheck() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartupCheck")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartupCheck indicates an expected call of StartupCheck.
+func (mr *MockUserProviderMockRecorder) StartupCheck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartupCheck", reflect.TypeOf((*MockUserProvider)(nil).StartupCheck))
+}
+
 // UpdatePassword mocks base method
 func (m *MockUserProvider) UpdatePassword(arg0, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -78,3 +152,18 @@ func (mr *MockUserProviderMockRecorder) UpdatePassword(arg0, arg1 interface{}) *
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserProvider)(nil).UpdatePassword), arg0, arg1)
 }
+
+// UserExists mocks base method.
+func (m *MockUserProvider) UserExists(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserExists", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserExists indicates an expected call of UserExists.
+func (mr *MockUserProviderMockRecorder) UserExists(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserExists", reflect.TypeOf((*MockUserProvider)(nil).UserExists), arg0)
+}
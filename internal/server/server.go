@@ -4,12 +4,15 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 
 	duoapi "github.com/duosecurity/duo_api_golang"
 	"github.com/fasthttp/router"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/expvarhandler"
 	"github.com/valyala/fasthttp/fasthttpadaptor"
@@ -22,7 +25,8 @@ import (
 	"github.com/authelia/authelia/internal/middlewares"
 )
 
-// StartServer start Authelia server with the given configuration and providers.
+// StartServer start Authelia server with the given configuration and providers. It returns once the server is shut
+// down gracefully on SIGINT or SIGTERM, after the requests in progress are served.
 func StartServer(configuration schema.Configuration, providers middlewares.Providers) {
 	autheliaMiddleware := middlewares.AutheliaMiddleware(configuration, providers)
 	embeddedAssets := "/public_html/"
@@ -122,6 +126,10 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 			middlewares.RequireFirstFactor(handlers.SecondFactorDuoPost(duoAPI))))
 	}
 
+	if configuration.Server.EnableMetrics {
+		r.GET("/metrics", fasthttpadaptor.NewFastHTTPHandler(promhttp.Handler()))
+	}
+
 	// If trace is set, enable pprofhandler and expvarhandler.
 	if configuration.LogLevel == "trace" {
 		r.GET("/debug/pprof/{name?}", pprofhandler.PprofHandler)
@@ -166,11 +174,28 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 		}
 	}
 
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		sig := <-signals
+
+		logging.Logger().Infof("Authelia is shutting down on %s", sig)
+
+		if err := server.Shutdown(); err != nil {
+			logging.Logger().Errorf("Error shutting down the server: %s", err)
+		}
+	}()
+
 	if configuration.TLSCert != "" && configuration.TLSKey != "" {
 		logging.Logger().Infof("Authelia is listening for TLS connections on %s%s", addrPattern, configuration.Server.Path)
-		logging.Logger().Fatal(server.ServeTLS(listener, configuration.TLSCert, configuration.TLSKey))
+		err = server.ServeTLS(listener, configuration.TLSCert, configuration.TLSKey)
 	} else {
 		logging.Logger().Infof("Authelia is listening for non-TLS connections on %s%s", addrPattern, configuration.Server.Path)
-		logging.Logger().Fatal(server.Serve(listener))
+		err = server.Serve(listener)
+	}
+
+	if err != nil {
+		logging.Logger().Fatal(err)
 	}
 }
@@ -1,12 +1,20 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"path"
 	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -18,14 +26,167 @@ func NewTLSConfig(config *schema.TLSConfig, defaultMinVersion uint16, certPool *
 		minVersion = defaultMinVersion
 	}
 
-	return &tls.Config{
+	renegotiation, err := TLSStringToTLSRenegotiationSupport(config.Renegotiation)
+	if err != nil {
+		renegotiation = tls.RenegotiateNever
+	}
+
+	tlsConfig = &tls.Config{
 		ServerName:         config.ServerName,
 		InsecureSkipVerify: config.SkipVerify, //nolint:gosec // Informed choice by user. Off by default.
 		MinVersion:         minVersion,
 		RootCAs:            certPool,
+		Renegotiation:      renegotiation,
+	}
+
+	if config.RequireOCSPStapling {
+		tlsConfig.VerifyConnection = VerifyOCSPStapling
+	}
+
+	if len(config.PinnedCertificates) != 0 || len(config.PinnedPublicKeys) != 0 {
+		tlsConfig.VerifyConnection = ChainVerifyConnection(tlsConfig.VerifyConnection,
+			NewPinningVerification(config.PinnedCertificates, config.PinnedPublicKeys))
+	}
+
+	return tlsConfig
+}
+
+// ChainVerifyConnection returns a verification of the TLS connections running the first verification, if any, then
+// the second one.
+func ChainVerifyConnection(first, second func(state tls.ConnectionState) error) func(state tls.ConnectionState) error {
+	if first == nil {
+		return second
+	}
+
+	return func(state tls.ConnectionState) error {
+		if err := first(state); err != nil {
+			return err
+		}
+
+		return second(state)
+	}
+}
+
+// NewPinningVerification returns a verification of the TLS connections which fails unless one of the certificates
+// presented by the peer matches one of the pins. The pins of the certificates are the hexadecimal SHA-256 fingerprints
+// of the certificates and the pins of the public keys are the base64 SHA-256 digests of their SubjectPublicKeyInfo.
+// Several pins allow rotating the certificate of the peer. The invalid pins are ignored.
+func NewPinningVerification(certificates []string, publicKeys []string) func(state tls.ConnectionState) error {
+	var certificatePins, publicKeyPins [][]byte
+
+	for _, pin := range certificates {
+		if digest, err := ParseCertificatePin(pin); err == nil {
+			certificatePins = append(certificatePins, digest)
+		}
+	}
+
+	for _, pin := range publicKeys {
+		if digest, err := ParsePublicKeyPin(pin); err == nil {
+			publicKeyPins = append(publicKeyPins, digest)
+		}
+	}
+
+	return func(state tls.ConnectionState) error {
+		for _, certificate := range state.PeerCertificates {
+			certificateDigest := sha256.Sum256(certificate.Raw)
+			publicKeyDigest := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+
+			for _, pin := range certificatePins {
+				if bytes.Equal(pin, certificateDigest[:]) {
+					return nil
+				}
+			}
+
+			for _, pin := range publicKeyPins {
+				if bytes.Equal(pin, publicKeyDigest[:]) {
+					return nil
+				}
+			}
+		}
+
+		return errors.New("none of the certificates presented by the peer matches the pinned certificates and public keys")
 	}
 }
 
+// ParseCertificatePin decodes the pin of a certificate, its hexadecimal SHA-256 fingerprint whose bytes may be
+// separated by colons like 2A:7B:...
+func ParseCertificatePin(pin string) ([]byte, error) {
+	digest, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+	if err != nil || len(digest) != sha256.Size {
+		return nil, ErrTLSPinNotSupported
+	}
+
+	return digest, nil
+}
+
+// ParsePublicKeyPin decodes the pin of a public key, the base64 SHA-256 digest of its SubjectPublicKeyInfo optionally
+// prefixed by sha256/.
+func ParsePublicKeyPin(pin string) ([]byte, error) {
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, TLSPublicKeyPinPrefix))
+	if err != nil || len(digest) != sha256.Size {
+		return nil, ErrTLSPinNotSupported
+	}
+
+	return digest, nil
+}
+
+// VerifyOCSPStapling verifies the peer stapled a valid OCSP response reporting its certificate as good during the
+// handshake. The response must be signed by the issuer of the certificate, which is taken from the verified chain or
+// from the certificates presented by the peer when the chain is not verified.
+func VerifyOCSPStapling(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("the peer presented no certificate")
+	}
+
+	if len(state.OCSPResponse) == 0 {
+		return errors.New("the peer didn't staple an OCSP response")
+	}
+
+	var issuer *x509.Certificate
+
+	switch {
+	case len(state.VerifiedChains) != 0 && len(state.VerifiedChains[0]) > 1:
+		issuer = state.VerifiedChains[0][1]
+	case len(state.PeerCertificates) > 1:
+		issuer = state.PeerCertificates[1]
+	default:
+		return errors.New("the issuer of the certificate of the peer is unknown, the stapled OCSP response can't be verified")
+	}
+
+	response, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		return fmt.Errorf("the stapled OCSP response is invalid: %w", err)
+	}
+
+	switch response.Status {
+	case ocsp.Good:
+		if !response.NextUpdate.IsZero() && time.Now().After(response.NextUpdate) {
+			return fmt.Errorf("the stapled OCSP response expired at %s", response.NextUpdate.UTC().Format(time.RFC3339))
+		}
+
+		return nil
+	case ocsp.Revoked:
+		return fmt.Errorf("the certificate of the peer was revoked at %s", response.RevokedAt.UTC().Format(time.RFC3339))
+	default:
+		return errors.New("the stapled OCSP response reports an unknown status for the certificate of the peer")
+	}
+}
+
+// NewTLSCertificates loads the client certificate and its private key from the PEM files of a schema.TLSConfig. The
+// certificates are nil when no certificate is configured.
+func NewTLSCertificates(config *schema.TLSConfig) ([]tls.Certificate, error) {
+	if config == nil || (config.Certificate == "" && config.Key == "") {
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(config.Certificate, config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the certificate %s with the key %s: %w", config.Certificate, config.Key, err)
+	}
+
+	return []tls.Certificate{certificate}, nil
+}
+
 //nolint:gocyclo // TODO: Remove in 4.28. Should be able to remove the nolint during the removal of deprecated config.
 // NewX509CertPool generates a x509.CertPool from the system PKI and the directory specified.
 func NewX509CertPool(directory string, config *schema.Configuration) (certPool *x509.CertPool, errors []error, nonFatalErrors []error) {
@@ -89,3 +250,18 @@ func TLSStringToTLSConfigVersion(input string) (version uint16, err error) {
 
 	return 0, ErrTLSVersionNotSupported
 }
+
+// TLSStringToTLSRenegotiationSupport returns the tls.RenegotiationSupport of the textual representation of the
+// renegotiation support, which is never when empty.
+func TLSStringToTLSRenegotiationSupport(input string) (renegotiation tls.RenegotiationSupport, err error) {
+	switch strings.ToLower(input) {
+	case "", TLSRenegotiationNever:
+		return tls.RenegotiateNever, nil
+	case TLSRenegotiationOnce:
+		return tls.RenegotiateOnceAsClient, nil
+	case TLSRenegotiationFreely:
+		return tls.RenegotiateFreelyAsClient, nil
+	}
+
+	return tls.RenegotiateNever, ErrTLSRenegotiationNotSupported
+}
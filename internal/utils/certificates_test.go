@@ -1,11 +1,22 @@
 package utils
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -75,6 +86,189 @@ func TestShouldReturnZeroAndErrorOnInvalidTLSVersions(t *testing.T) {
 	assert.EqualError(t, err, "supplied TLS version isn't supported")
 }
 
+func TestShouldReturnCorrectTLSRenegotiationSupports(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected tls.RenegotiationSupport
+	}{
+		{"", tls.RenegotiateNever},
+		{TLSRenegotiationNever, tls.RenegotiateNever},
+		{TLSRenegotiationOnce, tls.RenegotiateOnceAsClient},
+		{"Freely", tls.RenegotiateFreelyAsClient},
+	}
+
+	for _, tc := range testCases {
+		renegotiation, err := TLSStringToTLSRenegotiationSupport(tc.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, renegotiation)
+	}
+
+	_, err := TLSStringToTLSRenegotiationSupport("always")
+	assert.EqualError(t, err, "supplied TLS renegotiation support isn't supported")
+}
+
+func TestShouldSetupRenegotiationAndOCSPStapling(t *testing.T) {
+	tlsConfig := NewTLSConfig(&schema.TLSConfig{}, tls.VersionTLS12, nil)
+
+	assert.Equal(t, tls.RenegotiateNever, tlsConfig.Renegotiation)
+	assert.Nil(t, tlsConfig.VerifyConnection)
+
+	tlsConfig = NewTLSConfig(&schema.TLSConfig{Renegotiation: TLSRenegotiationOnce, RequireOCSPStapling: true}, tls.VersionTLS12, nil)
+
+	assert.Equal(t, tls.RenegotiateOnceAsClient, tlsConfig.Renegotiation)
+	assert.NotNil(t, tlsConfig.VerifyConnection)
+}
+
+func newOCSPTestCertificates(t *testing.T) (issuer, leaf *x509.Certificate, key *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issuerTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Authelia Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, issuerTemplate, issuerTemplate, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	issuer, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	der, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ldap.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, issuer, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return issuer, leaf, key
+}
+
+func TestShouldVerifyOCSPStapling(t *testing.T) {
+	issuer, leaf, key := newOCSPTestCertificates(t)
+
+	newResponse := func(status int, nextUpdate time.Time) []byte {
+		response, err := ocsp.CreateResponse(issuer, issuer, ocsp.Response{
+			Status:       status,
+			SerialNumber: leaf.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Hour),
+			NextUpdate:   nextUpdate,
+			RevokedAt:    time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}, key)
+		require.NoError(t, err)
+
+		return response
+	}
+
+	verifiedChains := [][]*x509.Certificate{{leaf, issuer}}
+
+	assert.NoError(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   verifiedChains,
+		OCSPResponse:     newResponse(ocsp.Good, time.Now().Add(time.Hour)),
+	}))
+
+	assert.EqualError(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   verifiedChains,
+		OCSPResponse:     newResponse(ocsp.Revoked, time.Now().Add(time.Hour)),
+	}), "the certificate of the peer was revoked at 2021-01-01T00:00:00Z")
+
+	assert.EqualError(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   verifiedChains,
+		OCSPResponse:     newResponse(ocsp.Unknown, time.Now().Add(time.Hour)),
+	}), "the stapled OCSP response reports an unknown status for the certificate of the peer")
+
+	assert.Error(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf, issuer},
+		OCSPResponse:     newResponse(ocsp.Good, time.Now().Add(-time.Minute)),
+	}))
+
+	assert.EqualError(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   verifiedChains,
+	}), "the peer didn't staple an OCSP response")
+
+	assert.EqualError(t, VerifyOCSPStapling(tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		OCSPResponse:     newResponse(ocsp.Good, time.Now().Add(time.Hour)),
+	}), "the issuer of the certificate of the peer is unknown, the stapled OCSP response can't be verified")
+
+	assert.EqualError(t, VerifyOCSPStapling(tls.ConnectionState{}), "the peer presented no certificate")
+}
+
+func TestShouldVerifyPinnedCertificatesAndPublicKeys(t *testing.T) {
+	issuer, leaf, _ := newOCSPTestCertificates(t)
+
+	certificateDigest := sha256.Sum256(leaf.Raw)
+	publicKeyDigest := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+	otherDigest := sha256.Sum256([]byte("other"))
+
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, issuer}}
+
+	// The fingerprints are accepted with or without colons.
+	var colonFingerprint string
+
+	for i, b := range certificateDigest {
+		if i != 0 {
+			colonFingerprint += ":"
+		}
+
+		colonFingerprint += hex.EncodeToString([]byte{b})
+	}
+
+	testCases := []struct {
+		name         string
+		certificates []string
+		publicKeys   []string
+		valid        bool
+	}{
+		{"Certificate", []string{hex.EncodeToString(certificateDigest[:])}, nil, true},
+		{"CertificateWithColons", []string{colonFingerprint}, nil, true},
+		{"PublicKeyOfIssuer", nil, []string{"sha256/" + base64.StdEncoding.EncodeToString(publicKeyDigest[:])}, true},
+		{"RotatedPins", []string{hex.EncodeToString(otherDigest[:]), hex.EncodeToString(certificateDigest[:])}, nil, true},
+		{"NoMatch", []string{hex.EncodeToString(otherDigest[:])}, []string{base64.StdEncoding.EncodeToString(otherDigest[:])}, false},
+		{"InvalidPinsIgnored", []string{"2a:7b"}, []string{"abc"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewPinningVerification(tc.certificates, tc.publicKeys)(state)
+
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "none of the certificates presented by the peer matches the pinned certificates and public keys")
+			}
+		})
+	}
+}
+
+func TestShouldChainPinningAfterOCSPStapling(t *testing.T) {
+	tlsConfig := NewTLSConfig(&schema.TLSConfig{
+		RequireOCSPStapling: true,
+		PinnedPublicKeys:    []string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}, tls.VersionTLS12, nil)
+
+	assert.EqualError(t, tlsConfig.VerifyConnection(tls.ConnectionState{}), "the peer presented no certificate")
+
+	tlsConfig = NewTLSConfig(&schema.TLSConfig{
+		PinnedPublicKeys: []string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}, tls.VersionTLS12, nil)
+
+	assert.EqualError(t, tlsConfig.VerifyConnection(tls.ConnectionState{}), "none of the certificates presented by the peer matches the pinned certificates and public keys")
+}
+
 func TestShouldReturnErrWhenX509DirectoryNotExist(t *testing.T) {
 	pool, errs, nonFatalErrs := NewX509CertPool("/tmp/asdfzyxabc123/not/a/real/dir", nil)
 	assert.NotNil(t, pool)
@@ -132,3 +326,21 @@ func TestShouldReadCertsFromDirectoryButNotKeys(t *testing.T) {
 	assert.Len(t, nonFatalErrs, 0)
 	assert.EqualError(t, errs[0], "could not import certificate key.pem")
 }
+
+func TestShouldLoadTLSCertificates(t *testing.T) {
+	certificates, err := NewTLSCertificates(&schema.TLSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, certificates)
+
+	certificates, err = NewTLSCertificates(&schema.TLSConfig{
+		Certificate: "../suites/common/ssl/cert.pem",
+		Key:         "../suites/common/ssl/key.pem",
+	})
+	require.NoError(t, err)
+	assert.Len(t, certificates, 1)
+
+	_, err = NewTLSCertificates(&schema.TLSConfig{
+		Certificate: "../suites/common/ssl/cert.pem",
+	})
+	assert.EqualError(t, err, "could not load the certificate ../suites/common/ssl/cert.pem with the key : open : no such file or directory")
+}
@@ -47,3 +47,23 @@ const TLS11 = "1.1"
 
 // TLS10 is the textual representation of TLS 1.0.
 const TLS10 = "1.0"
+
+// ErrTLSRenegotiationNotSupported returned when an unknown TLS renegotiation support supplied.
+var ErrTLSRenegotiationNotSupported = errors.New("supplied TLS renegotiation support isn't supported")
+
+// ErrTLSPinNotSupported returned when a pin of a certificate or of a public key isn't a SHA-256 digest.
+var ErrTLSPinNotSupported = errors.New("supplied pin isn't a SHA-256 digest")
+
+// TLSPublicKeyPinPrefix is the optional prefix of the pins of the public keys, as used by the pin-sha256 directive
+// of HTTP Public Key Pinning.
+const TLSPublicKeyPinPrefix = "sha256/"
+
+// TLSRenegotiationNever is the textual representation of the renegotiation support rejecting any renegotiation.
+const TLSRenegotiationNever = "never"
+
+// TLSRenegotiationOnce is the textual representation of the renegotiation support allowing a single renegotiation
+// per connection.
+const TLSRenegotiationOnce = "once"
+
+// TLSRenegotiationFreely is the textual representation of the renegotiation support allowing repeated renegotiations.
+const TLSRenegotiationFreely = "freely"
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
 )
 
 // IsStringAlphaNumeric returns false if any rune in the string is not alpha-numeric.
@@ -92,6 +94,31 @@ func StringSlicesDelta(before, after []string) (added, removed []string) {
 	return added, removed
 }
 
+// TrimDN trims the whitespaces and the stray commas around a distinguished name, for instance the trailing comma left
+// when copying a DN. The trailing commas and whitespaces escaped with a backslash are part of the DN and kept.
+func TrimDN(dn string) string {
+	dn = strings.TrimLeft(dn, ", \t")
+
+	for len(dn) != 0 && strings.ContainsRune(", \t", rune(dn[len(dn)-1])) && !strings.HasSuffix(dn[:len(dn)-1], "\\") {
+		dn = dn[:len(dn)-1]
+	}
+
+	return dn
+}
+
+// NormalizeGroupName normalizes the name of a group according to the LDAP group name normalization, so the groups
+// returned by the LDAP server and the groups of the access control rules are compared in the same form.
+func NormalizeGroupName(name string, normalization string) string {
+	switch normalization {
+	case schema.LDAPGroupNameNormalizationTrim:
+		return strings.TrimSpace(name)
+	case schema.LDAPGroupNameNormalizationLowercase:
+		return strings.ToLower(strings.TrimSpace(name))
+	default:
+		return name
+	}
+}
+
 // RandomString generate a random string of n characters.
 func RandomString(n int, characters []rune) (randomString string) {
 	rand.Seed(time.Now().UnixNano())
@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
 )
 
 func TestShouldSplitIntoEvenStringsOfFour(t *testing.T) {
@@ -82,3 +84,17 @@ func TestShouldNotFindStringInSliceContains(t *testing.T) {
 	s := IsStringInSliceContains(a, b)
 	assert.False(t, s)
 }
+
+func TestShouldTrimDN(t *testing.T) {
+	assert.Equal(t, "dc=example,dc=com", TrimDN(" dc=example,dc=com, "))
+	assert.Equal(t, "ou=users", TrimDN(",ou=users,,"))
+	assert.Equal(t, "cn=trailing\\,", TrimDN("cn=trailing\\,"))
+	assert.Equal(t, "cn=trailing\\ ", TrimDN("cn=trailing\\ , "))
+	assert.Equal(t, "", TrimDN(" , "))
+}
+
+func TestShouldNormalizeGroupName(t *testing.T) {
+	assert.Equal(t, " Admins ", NormalizeGroupName(" Admins ", schema.LDAPGroupNameNormalizationNone))
+	assert.Equal(t, "Admins", NormalizeGroupName(" Admins ", schema.LDAPGroupNameNormalizationTrim))
+	assert.Equal(t, "admins", NormalizeGroupName(" Admins ", schema.LDAPGroupNameNormalizationLowercase))
+}